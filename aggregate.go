@@ -0,0 +1,250 @@
+package xk6_mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	stageOut   = "$out"
+	stageMerge = "$merge"
+)
+
+var errNoWriteStage = errors.New("pipeline must end with a $out or $merge stage")
+
+// AggregateWriteResult describes where a $out or $merge pipeline wrote its
+// output.
+type AggregateWriteResult struct {
+	Stage      string `js:"stage"`
+	Database   string `js:"database"`
+	Collection string `js:"collection"`
+}
+
+// aggregator is implemented by both *mongo.Collection and *mongo.Database.
+type aggregator interface {
+	Aggregate(ctx context.Context, pipeline any, opts ...*options.AggregateOptions) (*mongo.Cursor, error)
+}
+
+// Aggregate runs pipeline on a collection. Options: allowDiskUse, batchSize,
+// maxTime, hint, collation, comment, let, bypassDocumentValidation and
+// readConcern. A pipeline ending in $out or $merge returns an empty result.
+func (c *Client) Aggregate(database string, collection string, pipeline any, opts map[string]any) ([]bson.M, error) {
+	if pipeline == nil {
+		return nil, errPipelineNil
+	}
+
+	parsed, err := parseAggregateOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	col, err := c.aggregateCollection(database, collection, parsed)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	return c.runAggregate(col, pipeline, parsed)
+}
+
+// AggregateDatabase runs a database-level pipeline, such as one starting
+// with $documents, $currentOp or $listLocalSessions. It takes the same
+// options as Aggregate.
+func (c *Client) AggregateDatabase(database string, pipeline any, opts map[string]any) ([]bson.M, error) {
+	if database == "" {
+		return nil, errDatabaseEmpty
+	}
+	if pipeline == nil {
+		return nil, errPipelineNil
+	}
+
+	parsed, err := parseAggregateOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	dbOpts := options.Database()
+	if parsed.readConcern != nil {
+		dbOpts.SetReadConcern(parsed.readConcern)
+	}
+
+	return c.runAggregate(c.client.Database(database, dbOpts), pipeline, parsed)
+}
+
+// AggregateWrite runs a pipeline ending in $out or $merge and reports the
+// collection the results were written to.
+func (c *Client) AggregateWrite(database string, collection string, pipeline any, opts map[string]any) (*AggregateWriteResult, error) {
+	if pipeline == nil {
+		return nil, errPipelineNil
+	}
+
+	target, err := aggregateWriteTarget(pipeline, database)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.Aggregate(database, collection, pipeline, opts); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+func (c *Client) aggregateCollection(database, collection string, parsed *commandOptions) (*mongo.Collection, error) {
+	colOpts := options.Collection()
+	if parsed.readConcern != nil {
+		colOpts.SetReadConcern(parsed.readConcern)
+	}
+	return c.getCollection(database, collection, colOpts)
+}
+
+// runAggregate runs pipeline on source and decodes the results. Pipelines
+// ending in a write stage produce no documents, so their cursor is only
+// closed.
+func (c *Client) runAggregate(source aggregator, pipeline any, parsed *commandOptions) ([]bson.M, error) {
+	// $out replaces its target collection, so running it again gives the
+	// same result; what $merge does twice depends on its whenMatched.
+	kind := opRead
+	stage, _, ok := lastPipelineStage(pipeline)
+	writes := ok && (stage == stageOut || stage == stageMerge)
+	if writes {
+		kind = opIdempotentWrite
+		if stage == stageMerge {
+			kind = opWrite
+		}
+	}
+
+	results := []bson.M{}
+	err := c.do("aggregate", kind, func(ctx context.Context) error {
+		cur, err := source.Aggregate(ctx, pipeline, parsed.aggregate())
+		if err != nil {
+			log.Printf(errAggregating, err)
+			return err
+		}
+		defer cur.Close(ctx)
+
+		if writes {
+			return nil
+		}
+		if err = cur.All(ctx, &results); err != nil {
+			log.Printf(errDecodingDocuments, err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// aggregateWriteTarget resolves the collection written by the trailing $out
+// or $merge stage of pipeline. Targets without a database default to
+// database.
+func aggregateWriteTarget(pipeline any, database string) (*AggregateWriteResult, error) {
+	stage, spec, ok := lastPipelineStage(pipeline)
+	if !ok || (stage != stageOut && stage != stageMerge) {
+		return nil, errNoWriteStage
+	}
+
+	if stage == stageMerge {
+		if m, isMap := stageDocument(spec); isMap {
+			spec = m["into"]
+		}
+	}
+
+	target := &AggregateWriteResult{Stage: stage, Database: database}
+	switch v := spec.(type) {
+	case string:
+		target.Collection = v
+	default:
+		m, isMap := stageDocument(v)
+		if !isMap {
+			return nil, fmt.Errorf("unsupported %s target %T", stage, spec)
+		}
+		if db, ok := m["db"].(string); ok && db != "" {
+			target.Database = db
+		}
+		target.Collection, _ = m["coll"].(string)
+	}
+	if target.Collection == "" {
+		return nil, fmt.Errorf("%s stage does not name a collection", stage)
+	}
+	return target, nil
+}
+
+// lastPipelineStage returns the operator and argument of the final stage of
+// pipeline, accepting the shapes produced by JS scripts and Go callers.
+func lastPipelineStage(pipeline any) (string, any, bool) {
+	var last any
+	switch p := pipeline.(type) {
+	case []any:
+		if len(p) == 0 {
+			return "", nil, false
+		}
+		last = p[len(p)-1]
+	case bson.A:
+		if len(p) == 0 {
+			return "", nil, false
+		}
+		last = p[len(p)-1]
+	case []bson.M:
+		if len(p) == 0 {
+			return "", nil, false
+		}
+		last = p[len(p)-1]
+	case []map[string]any:
+		if len(p) == 0 {
+			return "", nil, false
+		}
+		last = p[len(p)-1]
+	case []bson.D:
+		if len(p) == 0 {
+			return "", nil, false
+		}
+		last = p[len(p)-1]
+	case mongo.Pipeline:
+		if len(p) == 0 {
+			return "", nil, false
+		}
+		last = p[len(p)-1]
+	default:
+		return "", nil, false
+	}
+
+	switch stage := last.(type) {
+	case bson.D:
+		if len(stage) == 1 {
+			return stage[0].Key, stage[0].Value, true
+		}
+	default:
+		if m, ok := stageDocument(stage); ok && len(m) == 1 {
+			for key, value := range m {
+				return key, value, true
+			}
+		}
+	}
+	return "", nil, false
+}
+
+// stageDocument returns value as a map when it is a document.
+func stageDocument(value any) (map[string]any, bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		return v, true
+	case bson.M:
+		return v, true
+	case bson.D:
+		m := make(map[string]any, len(v))
+		for _, e := range v {
+			m[e.Key] = e.Value
+		}
+		return m, true
+	}
+	return nil, false
+}
@@ -0,0 +1,498 @@
+package xk6_mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	changeStreamEventChange = "change"
+	changeStreamEventError  = "error"
+)
+
+var (
+	errChangeStreamClosed   = errors.New("change stream is closed")
+	errChangeStreamCallback = errors.New("change stream is consumed by an event callback")
+)
+
+// ChangeStream is a handle to an open change stream. Events can be pulled
+// with Next/TryNext or pushed to a callback registered with On.
+type ChangeStream struct {
+	stream *mongo.ChangeStream
+	client *Client
+
+	mu       sync.Mutex
+	closed   bool
+	handlers map[string]sobek.Callable
+	cancel   context.CancelFunc
+	token    bson.Raw
+}
+
+// changeStreamSource is implemented by mongo.Collection, mongo.Database and
+// mongo.Client, the three scopes a change stream can be opened on.
+type changeStreamSource interface {
+	Watch(ctx context.Context, pipeline any, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error)
+}
+
+// OpenChangeStream opens a change stream on a collection without blocking the
+// VU. Change streams require a MongoDB replica set or sharded cluster.
+func (c *Client) OpenChangeStream(database string, collection string, pipeline any, opts map[string]any) (*ChangeStream, error) {
+	csOpts, err := changeStreamOptionsFromMap(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	col, err := c.getCollection(database, collection)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	return c.openChangeStream(col, pipeline, csOpts)
+}
+
+// OpenDatabaseChangeStream opens a change stream on every collection of a
+// database.
+func (c *Client) OpenDatabaseChangeStream(database string, pipeline any, opts map[string]any) (*ChangeStream, error) {
+	if database == "" {
+		return nil, errDatabaseEmpty
+	}
+
+	csOpts, err := changeStreamOptionsFromMap(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.openChangeStream(c.client.Database(database), pipeline, csOpts)
+}
+
+// OpenClusterChangeStream opens a change stream on every non-system
+// collection of the deployment.
+func (c *Client) OpenClusterChangeStream(pipeline any, opts map[string]any) (*ChangeStream, error) {
+	csOpts, err := changeStreamOptionsFromMap(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.openChangeStream(c.client, pipeline, csOpts)
+}
+
+// WatchDatabase collects change events from every collection of a database
+// for the specified duration.
+func (c *Client) WatchDatabase(database string, pipeline any, durationMs int64) ([]bson.M, error) {
+	if database == "" {
+		return nil, errDatabaseEmpty
+	}
+
+	return c.collectChangeEvents(c.client.Database(database), pipeline, durationMs)
+}
+
+// WatchCluster collects change events from the whole deployment for the
+// specified duration.
+func (c *Client) WatchCluster(pipeline any, durationMs int64) ([]bson.M, error) {
+	return c.collectChangeEvents(c.client, pipeline, durationMs)
+}
+
+func (c *Client) openChangeStream(source changeStreamSource, pipeline any, opts *options.ChangeStreamOptions) (*ChangeStream, error) {
+	if pipeline == nil {
+		pipeline = []bson.M{}
+	}
+
+	ctx, cancel := c.getContext()
+	defer cancel()
+
+	cs, err := source.Watch(ctx, pipeline, opts)
+	if err != nil {
+		log.Printf(errOpeningChangeStream, err)
+		return nil, err
+	}
+
+	return &ChangeStream{stream: cs, client: c}, nil
+}
+
+// collectChangeEvents blocks for durationMs and returns every event seen.
+func (c *Client) collectChangeEvents(source changeStreamSource, pipeline any, durationMs int64) ([]bson.M, error) {
+	if pipeline == nil {
+		pipeline = []bson.M{}
+	}
+
+	watchDuration := time.Duration(durationMs) * time.Millisecond
+	if watchDuration <= 0 {
+		watchDuration = 5 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), watchDuration)
+	defer cancel()
+
+	cs, err := source.Watch(ctx, pipeline)
+	if err != nil {
+		log.Printf(errWatchingChanges, err)
+		return nil, err
+	}
+	defer cs.Close(ctx)
+
+	var results []bson.M
+	for cs.Next(ctx) {
+		var event bson.M
+		if err := cs.Decode(&event); err != nil {
+			log.Printf(errDecodingDocuments, err)
+			return results, err
+		}
+		c.recordChangeEvent(event, time.Now())
+		results = append(results, event)
+	}
+
+	return results, nil
+}
+
+// TryNext returns the next available event, or null if the server had none
+// ready within maxAwaitTime.
+func (s *ChangeStream) TryNext() (bson.M, error) {
+	if err := s.checkPullable(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.client.getContext()
+	defer cancel()
+
+	ok := s.stream.TryNext(ctx)
+	s.recordToken()
+	if !ok {
+		return nil, s.stream.Err()
+	}
+	return s.decode()
+}
+
+// Next waits for the next event for up to the client's operation timeout and
+// returns null if none arrived in that window.
+func (s *ChangeStream) Next() (bson.M, error) {
+	deadline := time.Now().Add(s.client.defaultTimeout)
+	for time.Now().Before(deadline) {
+		event, err := s.TryNext()
+		if err != nil || event != nil {
+			return event, err
+		}
+	}
+	return nil, nil
+}
+
+// ResumeToken returns the token of the most recently returned event, which
+// can be passed as resumeAfter or startAfter to continue the stream later.
+func (s *ChangeStream) ResumeToken() (bson.M, error) {
+	s.mu.Lock()
+	raw := s.token
+	s.mu.Unlock()
+
+	if raw == nil {
+		return nil, nil
+	}
+	var token bson.M
+	if err := bson.Unmarshal(raw, &token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// On registers a handler for "change" or "error" events. Registering a
+// "change" handler starts delivering events on the VU event loop until Close
+// is called; Next and TryNext are unavailable from then on.
+func (s *ChangeStream) On(event string, handler sobek.Callable) error {
+	if handler == nil {
+		return errors.New("change stream handler cannot be nil")
+	}
+	if event != changeStreamEventChange && event != changeStreamEventError {
+		return fmt.Errorf("unsupported change stream event %q", event)
+	}
+	if s.client.vu == nil {
+		return errNoVU
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errChangeStreamClosed
+	}
+	if s.handlers == nil {
+		s.handlers = make(map[string]sobek.Callable)
+	}
+	s.handlers[event] = handler
+
+	if event == changeStreamEventChange && s.cancel == nil {
+		ctx, cancel := context.WithCancel(s.client.vu.Context())
+		s.cancel = cancel
+		go s.pump(ctx, s.client.vu.RegisterCallback())
+	}
+	return nil
+}
+
+// Close stops event delivery and releases the server-side cursor.
+func (s *ChangeStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	// In callback mode the pump goroutine owns the stream and closes it.
+	if s.cancel != nil {
+		s.cancel()
+		return nil
+	}
+
+	ctx, cancel := s.client.getContext()
+	defer cancel()
+	return s.stream.Close(ctx)
+}
+
+func (s *ChangeStream) checkPullable() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errChangeStreamClosed
+	}
+	if s.cancel != nil {
+		return errChangeStreamCallback
+	}
+	return nil
+}
+
+// recordToken snapshots the stream's resume token so ResumeToken can be read
+// while the pump goroutine is advancing the stream.
+func (s *ChangeStream) recordToken() {
+	token := s.stream.ResumeToken()
+	s.mu.Lock()
+	s.token = token
+	s.mu.Unlock()
+}
+
+func (s *ChangeStream) decode() (bson.M, error) {
+	receivedAt := time.Now()
+	var event bson.M
+	if err := s.stream.Decode(&event); err != nil {
+		log.Printf(errDecodingDocuments, err)
+		return nil, err
+	}
+	s.client.recordChangeEvent(event, receivedAt)
+	return event, nil
+}
+
+// pump reads events in the background and hands each one to the event loop.
+// Only one callback is queued at a time, so a slow handler applies
+// backpressure to the stream instead of buffering events in memory.
+func (s *ChangeStream) pump(ctx context.Context, enqueue func(func() error)) {
+	defer func() {
+		closeCtx, cancel := s.client.getContext()
+		defer cancel()
+		_ = s.stream.Close(closeCtx)
+	}()
+
+	for s.stream.Next(ctx) {
+		s.recordToken()
+		event, err := s.decode()
+		next := make(chan func(func() error), 1)
+		enqueue(func() error {
+			next <- s.client.vu.RegisterCallback()
+			if err != nil {
+				return s.dispatchError(err)
+			}
+			return s.dispatch(changeStreamEventChange, event)
+		})
+
+		select {
+		case enqueue = <-next:
+		case <-s.client.vu.Context().Done():
+			return
+		}
+	}
+
+	streamErr := s.stream.Err()
+	if ctx.Err() != nil {
+		streamErr = nil
+	}
+	enqueue(func() error {
+		if streamErr != nil {
+			log.Printf(errWatchingChanges, streamErr)
+			return s.dispatchError(streamErr)
+		}
+		return nil
+	})
+}
+
+func (s *ChangeStream) dispatch(event string, payload any) error {
+	s.mu.Lock()
+	handler := s.handlers[event]
+	s.mu.Unlock()
+
+	if handler == nil {
+		return nil
+	}
+	_, err := handler(sobek.Undefined(), s.client.vu.Runtime().ToValue(payload))
+	return err
+}
+
+// dispatchError reports err to the "error" handler, or fails the iteration if
+// none was registered.
+func (s *ChangeStream) dispatchError(err error) error {
+	s.mu.Lock()
+	_, ok := s.handlers[changeStreamEventError]
+	s.mu.Unlock()
+
+	if !ok {
+		return err
+	}
+	return s.dispatch(changeStreamEventError, err.Error())
+}
+
+// recordChangeEvent emits the end-to-end lag between the server writing an
+// event and the VU receiving it, plus a counter by operation type and
+// namespace.
+func (c *Client) recordChangeEvent(event bson.M, receivedAt time.Time) {
+	if c.metrics == nil {
+		return
+	}
+
+	tags := map[string]string{
+		"operation_type": fmt.Sprint(event["operationType"]),
+		"namespace":      changeEventNamespace(event),
+	}
+	c.emit(c.metrics.changeStreamEvents, 1, tags)
+
+	if at, ok := changeEventTime(event); ok {
+		c.emit(c.metrics.changeStreamLag, metrics.D(receivedAt.Sub(at)), tags)
+	}
+}
+
+// changeEventTime prefers the millisecond-precision wallTime (MongoDB 6.0+)
+// and falls back to the second-precision clusterTime.
+func changeEventTime(event bson.M) (time.Time, bool) {
+	if wallTime, ok := event["wallTime"].(primitive.DateTime); ok {
+		return wallTime.Time(), true
+	}
+	if clusterTime, ok := event["clusterTime"].(primitive.Timestamp); ok {
+		return time.Unix(int64(clusterTime.T), 0), true
+	}
+	return time.Time{}, false
+}
+
+// changeEventNamespace renders the event's ns field as "db.collection", or
+// just "db" for database-level events such as dropDatabase.
+func changeEventNamespace(event bson.M) string {
+	ns, ok := event["ns"].(bson.M)
+	if !ok {
+		return ""
+	}
+	db, _ := ns["db"].(string)
+	if coll, ok := ns["coll"].(string); ok && coll != "" {
+		return db + "." + coll
+	}
+	return db
+}
+
+// changeStreamOptionsFromMap builds driver options from the JS options object.
+func changeStreamOptionsFromMap(raw map[string]any) (*options.ChangeStreamOptions, error) {
+	opts := options.ChangeStream()
+
+	if token, ok := lookupOption(raw, "resumeAfter"); ok && token != nil {
+		opts.SetResumeAfter(token)
+	}
+	if token, ok := lookupOption(raw, "startAfter"); ok && token != nil {
+		opts.SetStartAfter(token)
+	}
+	if value, ok := lookupOption(raw, "startAtOperationTime"); ok && value != nil {
+		ts, err := toTimestamp(value)
+		if err != nil {
+			return nil, fmt.Errorf("option startAtOperationTime: %w", err)
+		}
+		opts.SetStartAtOperationTime(ts)
+	}
+
+	fullDocument, ok, err := stringOption(raw, "fullDocument")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		fd, err := parseFullDocument(fullDocument, options.Default, options.UpdateLookup, options.WhenAvailable, options.Required)
+		if err != nil {
+			return nil, fmt.Errorf("option fullDocument: %w", err)
+		}
+		opts.SetFullDocument(fd)
+	}
+
+	beforeChange, ok, err := stringOption(raw, "fullDocumentBeforeChange")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		fd, err := parseFullDocument(beforeChange, options.Off, options.WhenAvailable, options.Required)
+		if err != nil {
+			return nil, fmt.Errorf("option fullDocumentBeforeChange: %w", err)
+		}
+		opts.SetFullDocumentBeforeChange(fd)
+	}
+
+	batchSize, ok, err := batchSizeOption(raw)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		opts.SetBatchSize(batchSize)
+	}
+
+	maxAwaitTime, ok, err := durationOption(raw, "maxAwaitTime")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		opts.SetMaxAwaitTime(maxAwaitTime)
+	}
+
+	return opts, nil
+}
+
+func parseFullDocument(value string, allowed ...options.FullDocument) (options.FullDocument, error) {
+	for _, fd := range allowed {
+		if string(fd) == value {
+			return fd, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported value %q", value)
+}
+
+// toTimestamp accepts a BSON timestamp, a {t, i} object, a JS Date or a
+// number of seconds since the epoch.
+func toTimestamp(value any) (*primitive.Timestamp, error) {
+	switch v := value.(type) {
+	case primitive.Timestamp:
+		return &v, nil
+	case *primitive.Timestamp:
+		return v, nil
+	case time.Time:
+		return &primitive.Timestamp{T: uint32(v.Unix())}, nil
+	case map[string]any:
+		t, okT := toInt64(v["t"])
+		i, okI := toInt64(v["i"])
+		if !okT || !okI {
+			return nil, errors.New("timestamp object requires numeric t and i fields")
+		}
+		return &primitive.Timestamp{T: uint32(t), I: uint32(i)}, nil
+	}
+	if seconds, ok := toInt64(value); ok {
+		return &primitive.Timestamp{T: uint32(seconds)}, nil
+	}
+	return nil, fmt.Errorf("unsupported timestamp type %T", value)
+}
@@ -0,0 +1,222 @@
+package xk6_mongo
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestChangeStreamOptionsFromMap(t *testing.T) {
+	t.Run("nil options", func(t *testing.T) {
+		opts, err := changeStreamOptionsFromMap(nil)
+		if err != nil {
+			t.Fatalf("changeStreamOptionsFromMap failed: %v", err)
+		}
+		if opts.FullDocument != nil || opts.BatchSize != nil {
+			t.Error("Expected no options to be set")
+		}
+	})
+
+	t.Run("all options", func(t *testing.T) {
+		token := map[string]any{"_data": "8263"}
+		opts, err := changeStreamOptionsFromMap(map[string]any{
+			"resumeAfter":                 token,
+			"fullDocument":                "updateLookup",
+			"full_document_before_change": "whenAvailable",
+			"batchSize":                   int64(50),
+			"maxAwaitTime":                float64(250),
+			"startAtOperationTime":        map[string]any{"t": int64(1700000000), "i": int64(3)},
+		})
+		if err != nil {
+			t.Fatalf("changeStreamOptionsFromMap failed: %v", err)
+		}
+		if *opts.FullDocument != options.UpdateLookup {
+			t.Errorf("Expected fullDocument updateLookup, got %v", *opts.FullDocument)
+		}
+		if *opts.FullDocumentBeforeChange != options.WhenAvailable {
+			t.Errorf("Expected fullDocumentBeforeChange whenAvailable, got %v", *opts.FullDocumentBeforeChange)
+		}
+		if *opts.BatchSize != 50 {
+			t.Errorf("Expected batchSize 50, got %d", *opts.BatchSize)
+		}
+		if *opts.MaxAwaitTime != 250*time.Millisecond {
+			t.Errorf("Expected maxAwaitTime 250ms, got %v", *opts.MaxAwaitTime)
+		}
+		if *opts.StartAtOperationTime != (primitive.Timestamp{T: 1700000000, I: 3}) {
+			t.Errorf("Unexpected startAtOperationTime %v", *opts.StartAtOperationTime)
+		}
+		if opts.ResumeAfter == nil {
+			t.Error("Expected resumeAfter to be set")
+		}
+	})
+
+	invalid := map[string]map[string]any{
+		"unknown fullDocument":       {"fullDocument": "always"},
+		"off is not a fullDocument":  {"fullDocument": "off"},
+		"updateLookup before change": {"fullDocumentBeforeChange": "updateLookup"},
+		"zero batchSize":             {"batchSize": int64(0)},
+		"fractional batchSize":       {"batchSize": 1.5},
+		"negative maxAwaitTime":      {"maxAwaitTime": int64(-1)},
+		"string timestamp":           {"startAtOperationTime": "yesterday"},
+	}
+	for name, raw := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := changeStreamOptionsFromMap(raw); err == nil {
+				t.Error("Expected error for invalid option")
+			}
+		})
+	}
+}
+
+func TestToTimestamp(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name  string
+		input any
+		want  primitive.Timestamp
+	}{
+		{"seconds", int64(1700000000), primitive.Timestamp{T: 1700000000}},
+		{"date", now, primitive.Timestamp{T: 1700000000}},
+		{"object", map[string]any{"t": 1700000000, "i": 7}, primitive.Timestamp{T: 1700000000, I: 7}},
+		{"timestamp", primitive.Timestamp{T: 5, I: 1}, primitive.Timestamp{T: 5, I: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toTimestamp(tt.input)
+			if err != nil {
+				t.Fatalf("toTimestamp failed: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("toTimestamp(%v) = %v, want %v", tt.input, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupOption(t *testing.T) {
+	raw := map[string]any{"batch_size": 10, "maxAwaitTime": 5}
+	for _, name := range []string{"batchSize", "batch_size", "BatchSize"} {
+		if _, ok := lookupOption(raw, name); !ok {
+			t.Errorf("Expected %s to resolve", name)
+		}
+	}
+	if _, ok := lookupOption(raw, "max_await_time"); !ok {
+		t.Error("Expected max_await_time to resolve")
+	}
+	if _, ok := lookupOption(nil, "batchSize"); ok {
+		t.Error("Expected lookup on nil map to fail")
+	}
+}
+
+func TestOpenChangeStreamValidation(t *testing.T) {
+	client := &Client{}
+
+	t.Run("empty database", func(t *testing.T) {
+		_, err := client.OpenChangeStream("", "col", nil, nil)
+		if err == nil {
+			t.Error("Expected error for empty database")
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := client.OpenChangeStream("db", "col", []bson.M{}, map[string]any{"fullDocument": "always"})
+		if err == nil {
+			t.Error("Expected error for invalid options")
+		}
+	})
+}
+
+func TestDatabaseAndClusterChangeStreamValidation(t *testing.T) {
+	client := &Client{}
+
+	t.Run("empty database for OpenDatabaseChangeStream", func(t *testing.T) {
+		_, err := client.OpenDatabaseChangeStream("", nil, nil)
+		if err != errDatabaseEmpty {
+			t.Errorf("Expected errDatabaseEmpty, got %v", err)
+		}
+	})
+
+	t.Run("empty database for WatchDatabase", func(t *testing.T) {
+		_, err := client.WatchDatabase("", nil, 100)
+		if err != errDatabaseEmpty {
+			t.Errorf("Expected errDatabaseEmpty, got %v", err)
+		}
+	})
+
+	t.Run("invalid options for OpenClusterChangeStream", func(t *testing.T) {
+		_, err := client.OpenClusterChangeStream(nil, map[string]any{"batchSize": "ten"})
+		if err == nil {
+			t.Error("Expected error for invalid options")
+		}
+	})
+}
+
+func TestChangeEventTimeAndNamespace(t *testing.T) {
+	wall := time.UnixMilli(1700000000123)
+	event := bson.M{
+		"wallTime":    primitive.NewDateTimeFromTime(wall),
+		"clusterTime": primitive.Timestamp{T: 1700000000},
+		"ns":          bson.M{"db": "shop", "coll": "orders"},
+	}
+
+	at, ok := changeEventTime(event)
+	if !ok || !at.Equal(wall) {
+		t.Errorf("Expected wallTime %v, got %v", wall, at)
+	}
+
+	delete(event, "wallTime")
+	at, ok = changeEventTime(event)
+	if !ok || at.Unix() != 1700000000 {
+		t.Errorf("Expected clusterTime fallback, got %v", at)
+	}
+
+	if _, ok := changeEventTime(bson.M{}); ok {
+		t.Error("Expected no event time for empty event")
+	}
+
+	if ns := changeEventNamespace(event); ns != "shop.orders" {
+		t.Errorf("Expected namespace shop.orders, got %q", ns)
+	}
+	if ns := changeEventNamespace(bson.M{"ns": bson.M{"db": "shop"}}); ns != "shop" {
+		t.Errorf("Expected namespace shop, got %q", ns)
+	}
+}
+
+func TestRecordChangeEventMetrics(t *testing.T) {
+	client, samples := newMetricsTestClient(t)
+
+	receivedAt := time.UnixMilli(1700000000500)
+	client.recordChangeEvent(bson.M{
+		"operationType": "insert",
+		"wallTime":      primitive.NewDateTimeFromTime(time.UnixMilli(1700000000100)),
+		"ns":            bson.M{"db": "shop", "coll": "orders"},
+	}, receivedAt)
+
+	got := collectSamples(samples)
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 samples, got %d", len(got))
+	}
+	for _, sample := range got {
+		if op, _ := sample.Tags.Get("operation_type"); op != "insert" {
+			t.Errorf("Expected operation_type insert, got %q", op)
+		}
+		if ns, _ := sample.Tags.Get("namespace"); ns != "shop.orders" {
+			t.Errorf("Expected namespace shop.orders, got %q", ns)
+		}
+		switch sample.Metric.Name {
+		case "mongo_changestream_events":
+			if sample.Value != 1 {
+				t.Errorf("Expected event count 1, got %v", sample.Value)
+			}
+		case "mongo_changestream_lag":
+			if sample.Value != 400 {
+				t.Errorf("Expected lag 400ms, got %v", sample.Value)
+			}
+		default:
+			t.Errorf("Unexpected metric %s", sample.Metric.Name)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package xk6_mongo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/grafana/sobek"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const errRunningCommand = "Error while running command: %v"
+
+var errCommandEmpty = errors.New("command cannot be empty")
+
+// RunCommand runs a database command and returns the server's reply. The
+// command's field order is preserved, so the command name must come first,
+// e.g. {collStats: "orders", scale: 1024}.
+func (c *Client) RunCommand(database string, command sobek.Value) (bson.M, error) {
+	cmd, err := orderedCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	return c.runCommand(database, cmd)
+}
+
+// runCommand runs an ordered command document against database.
+func (c *Client) runCommand(database string, command bson.D) (bson.M, error) {
+	if database == "" {
+		return nil, errDatabaseEmpty
+	}
+	if len(command) == 0 {
+		return nil, errCommandEmpty
+	}
+
+	ctx, cancel := c.getContext()
+	defer cancel()
+
+	var reply bson.M
+	if err := c.client.Database(database).RunCommand(ctx, command).Decode(&reply); err != nil {
+		log.Printf(errRunningCommand, err)
+		return nil, err
+	}
+	return reply, nil
+}
+
+// orderedCommand converts a JS object into a command document, keeping the
+// order in which its properties were defined, down through nested objects
+// and arrays. Exporting the object to a Go map would lose that order, and the
+// server requires the command name to be the first field and reads sort and
+// index key specs in order.
+func orderedCommand(value sobek.Value) (bson.D, error) {
+	if value == nil || sobek.IsUndefined(value) || sobek.IsNull(value) {
+		return nil, errCommandEmpty
+	}
+	obj, ok := value.(*sobek.Object)
+	if !ok {
+		return nil, fmt.Errorf("command must be an object, got %T", value.Export())
+	}
+
+	keys := obj.Keys()
+	if len(keys) == 0 {
+		return nil, errCommandEmpty
+	}
+	cmd := make(bson.D, 0, len(keys))
+	for _, key := range keys {
+		cmd = append(cmd, bson.E{Key: key, Value: orderedValue(obj.Get(key))})
+	}
+	return cmd, nil
+}
+
+// orderedValue exports value, turning plain JS objects into bson.D and arrays
+// into bson.A so that nested field order survives. Other values, such as
+// dates and values created in Go, are exported as is.
+func orderedValue(value sobek.Value) any {
+	if value == nil {
+		return nil
+	}
+	obj, ok := value.(*sobek.Object)
+	if !ok {
+		return value.Export()
+	}
+	switch exported := obj.Export().(type) {
+	case map[string]any:
+		keys := obj.Keys()
+		doc := make(bson.D, 0, len(keys))
+		for _, key := range keys {
+			doc = append(doc, bson.E{Key: key, Value: orderedValue(obj.Get(key))})
+		}
+		return doc
+	case []any:
+		arr := make(bson.A, len(exported))
+		for i := range exported {
+			arr[i] = orderedValue(obj.Get(strconv.Itoa(i)))
+		}
+		return arr
+	default:
+		return exported
+	}
+}
@@ -25,7 +25,7 @@ func TestCRUDOperations(t *testing.T) {
 	col := "crudtestcol"
 	filter := bson.M{"_id": bson.M{"$eq": "crud-1"}}
 
-	if err := client.Insert(db, col, bson.M{"_id": "crud-1", "name": "init"}); err != nil {
+	if err := client.Insert(db, col, bson.M{"_id": "crud-1", "name": "init"}, nil); err != nil {
 		t.Fatalf("insert: %v", err)
 	}
 
@@ -38,7 +38,7 @@ func TestCRUDOperations(t *testing.T) {
 	}
 
 	update := bson.M{"name": "updated"}
-	if err := client.UpdateOne(db, col, filter, update); err != nil {
+	if err := client.UpdateOne(db, col, filter, update, nil); err != nil {
 		t.Fatalf("update: %v", err)
 	}
 
@@ -50,11 +50,11 @@ func TestCRUDOperations(t *testing.T) {
 		t.Fatalf("unexpected name after update %v", doc["name"])
 	}
 
-	if err := client.DeleteOne(db, col, filter); err != nil {
+	if err := client.DeleteOne(db, col, filter, nil); err != nil {
 		t.Fatalf("delete: %v", err)
 	}
 
-	count, err := client.CountDocuments(db, col, filter)
+	count, err := client.CountDocuments(db, col, filter, nil)
 	if err != nil {
 		t.Fatalf("count after delete: %v", err)
 	}
@@ -0,0 +1,208 @@
+package xk6_mongo
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	errListingOperations = "Error while listing current operations: %v"
+	errKillingOperation  = "Error while killing operation: %v"
+)
+
+var errOpIDNil = errors.New("operation id cannot be nil")
+
+// CurrentOp lists the in-progress operations matching filter, using the
+// $currentOp aggregation stage on the admin database. Options: allUsers
+// (default true), idleConnections, idleSessions, idleCursors and localOps.
+func (c *Client) CurrentOp(filter any, opts map[string]any) ([]bson.M, error) {
+	pipeline, err := currentOpPipeline(filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ops, err := c.AggregateDatabase("admin", pipeline, nil)
+	if err != nil {
+		log.Printf(errListingOperations, err)
+		return nil, err
+	}
+	return ops, nil
+}
+
+// KillOp terminates an operation by the opid reported by CurrentOp. On
+// sharded clusters the opid is a string such as "shard01:12345".
+func (c *Client) KillOp(opid any) error {
+	if opid == nil {
+		return errOpIDNil
+	}
+	if n, ok := toInt64(opid); ok {
+		opid = n
+	}
+
+	if _, err := c.runCommand("admin", bson.D{{Key: "killOp", Value: 1}, {Key: "op", Value: opid}}); err != nil {
+		log.Printf(errKillingOperation, err)
+		return err
+	}
+	log.Printf("Operation killed: %v", opid)
+	return nil
+}
+
+func currentOpPipeline(filter any, opts map[string]any) (bson.A, error) {
+	flags := []string{"allUsers", "idleConnections", "idleSessions", "idleCursors", "localOps"}
+	if err := checkAllowedOptions(opts, flags...); err != nil {
+		return nil, err
+	}
+
+	stage := bson.D{}
+	for _, flag := range flags {
+		value, ok, err := boolOption(opts, flag)
+		if err != nil {
+			return nil, err
+		}
+		if !ok && flag == "allUsers" {
+			value, ok = true, true
+		}
+		if ok {
+			stage = append(stage, bson.E{Key: flag, Value: value})
+		}
+	}
+
+	pipeline := bson.A{bson.D{{Key: "$currentOp", Value: stage}}}
+	if filter != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: filter}})
+	}
+	return pipeline, nil
+}
+
+// activeOpKey identifies the series of mongo_active_operations.
+type activeOpKey struct {
+	op             string
+	namespace      string
+	waitingForLock bool
+}
+
+// CurrentOpSampler periodically counts the active operations by type,
+// namespace and lock wait and emits them as mongo_active_operations.
+type CurrentOpSampler struct {
+	client *Client
+	filter any
+
+	mu       sync.Mutex
+	previous map[activeOpKey]int64
+	stopped  bool
+
+	loop *sampleLoop
+}
+
+// StartCurrentOpSampler starts counting active operations in the
+// background. Options: interval in ms (default 5000) and filter, applied to
+// the $currentOp output. Like the server status sampler, it runs until the
+// end of the test when started from setup(), and until the VU that started
+// it is done otherwise.
+func (c *Client) StartCurrentOpSampler(opts map[string]any) (*CurrentOpSampler, error) {
+	if err := checkAllowedOptions(opts, "interval", "filter"); err != nil {
+		return nil, err
+	}
+	interval, err := samplerInterval(opts)
+	if err != nil {
+		return nil, err
+	}
+	filter, _ := lookupOption(opts, "filter")
+
+	s := &CurrentOpSampler{client: c, filter: filter, previous: map[activeOpKey]int64{}}
+	s.loop = newSampleLoop(c)
+	s.loop.start(interval, func() { _ = s.Sample() }, s.release)
+	return s, nil
+}
+
+// Sample counts the active operations immediately.
+func (s *CurrentOpSampler) Sample() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return errSamplerStopped
+	}
+
+	var filter any = bson.M{"active": true}
+	if s.filter != nil {
+		filter = bson.M{"$and": bson.A{filter, s.filter}}
+	}
+	ops, err := s.client.CurrentOp(filter, nil)
+	if err != nil {
+		return err
+	}
+	s.record(countActiveOps(ops))
+	return nil
+}
+
+// Stop stops sampling.
+func (s *CurrentOpSampler) Stop() error {
+	s.loop.stop()
+	return nil
+}
+
+// release marks the sampler stopped once the loop is over.
+func (s *CurrentOpSampler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+}
+
+// record emits the counts, and zero for the series that were active in the
+// previous sample but are not anymore, so gauges do not keep stale values.
+func (s *CurrentOpSampler) record(counts map[activeOpKey]int64) {
+	c := s.client
+	if c.metrics == nil {
+		return
+	}
+	for key := range s.previous {
+		if _, ok := counts[key]; !ok {
+			s.loop.emit(c.metrics.activeOperations, 0, key.tags())
+		}
+	}
+	for key, n := range counts {
+		s.loop.emit(c.metrics.activeOperations, float64(n), key.tags())
+	}
+	s.previous = counts
+}
+
+func (k activeOpKey) tags() map[string]string {
+	return map[string]string{
+		"op":               k.op,
+		"namespace":        k.namespace,
+		"waiting_for_lock": strconv.FormatBool(k.waitingForLock),
+	}
+}
+
+// countActiveOps groups operations by type, namespace and lock wait,
+// leaving out the $currentOp aggregation that listed them.
+func countActiveOps(ops []bson.M) map[activeOpKey]int64 {
+	counts := map[activeOpKey]int64{}
+	for _, op := range ops {
+		if isCurrentOpCommand(op) {
+			continue
+		}
+		key := activeOpKey{}
+		key.op, _ = op["op"].(string)
+		key.namespace, _ = op["ns"].(string)
+		key.waitingForLock, _ = op["waitingForLock"].(bool)
+		counts[key]++
+	}
+	return counts
+}
+
+func isCurrentOpCommand(op bson.M) bool {
+	command, _ := stageDocument(op["command"])
+	pipeline, _ := documentArray(command["pipeline"])
+	if len(pipeline) == 0 {
+		return false
+	}
+	first, _ := stageDocument(pipeline[0])
+	_, ok := first["$currentOp"]
+	return ok
+}
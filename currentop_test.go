@@ -0,0 +1,75 @@
+package xk6_mongo
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCurrentOpPipeline(t *testing.T) {
+	pipeline, err := currentOpPipeline(bson.M{"secs_running": bson.M{"$gt": 5}}, map[string]any{"idleSessions": false})
+	if err != nil {
+		t.Fatalf("currentOpPipeline failed: %v", err)
+	}
+	want := bson.A{
+		bson.D{{Key: "$currentOp", Value: bson.D{{Key: "allUsers", Value: true}, {Key: "idleSessions", Value: false}}}},
+		bson.D{{Key: "$match", Value: bson.M{"secs_running": bson.M{"$gt": 5}}}},
+	}
+	if !reflect.DeepEqual(pipeline, want) {
+		t.Errorf("currentOpPipeline = %v, want %v", pipeline, want)
+	}
+
+	if _, err := currentOpPipeline(nil, map[string]any{"allUsers": "yes"}); err == nil {
+		t.Error("Expected error for a non-boolean flag")
+	}
+	if _, err := currentOpPipeline(nil, map[string]any{"truncateOps": true}); err == nil {
+		t.Error("Expected error for an unknown option")
+	}
+}
+
+func TestKillOpValidation(t *testing.T) {
+	if err := (&Client{}).KillOp(nil); err != errOpIDNil {
+		t.Errorf("Expected errOpIDNil, got %v", err)
+	}
+}
+
+func TestCountActiveOps(t *testing.T) {
+	counts := countActiveOps([]bson.M{
+		{"op": "query", "ns": "shop.orders"},
+		{"op": "query", "ns": "shop.orders"},
+		{"op": "update", "ns": "shop.orders", "waitingForLock": true},
+		{"op": "command", "ns": "admin.$cmd", "command": bson.M{"aggregate": 1,
+			"pipeline": bson.A{bson.M{"$currentOp": bson.M{}}, bson.M{"$match": bson.M{}}}}},
+	})
+
+	want := map[activeOpKey]int64{
+		{op: "query", namespace: "shop.orders"}:                        2,
+		{op: "update", namespace: "shop.orders", waitingForLock: true}: 1,
+	}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("countActiveOps = %v, want %v", counts, want)
+	}
+}
+
+func TestRecordActiveOpsResetsStaleSeries(t *testing.T) {
+	client, samples := newMetricsTestClient(t)
+	sampler := &CurrentOpSampler{client: client, previous: map[activeOpKey]int64{}, loop: newSampleLoop(client)}
+	defer sampler.loop.cancel()
+
+	sampler.record(map[activeOpKey]int64{{op: "query", namespace: "shop.orders"}: 3})
+	collectSamples(samples)
+
+	sampler.record(map[activeOpKey]int64{{op: "insert", namespace: "shop.orders"}: 1})
+	got := map[string]float64{}
+	for _, sample := range collectSamples(samples) {
+		op, _ := sample.Tags.Get("op")
+		got[op] = sample.Value
+		if waiting, _ := sample.Tags.Get("waiting_for_lock"); waiting != "false" {
+			t.Errorf("Expected waiting_for_lock false, got %q", waiting)
+		}
+	}
+	if !reflect.DeepEqual(got, map[string]float64{"query": 0, "insert": 1}) {
+		t.Errorf("Unexpected samples %v", got)
+	}
+}
@@ -0,0 +1,462 @@
+package xk6_mongo
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.k6.io/k6/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	errDumpingCollection   = "Error while dumping %s.%s: %v"
+	errRestoringCollection = "Error while restoring %s.%s: %v"
+
+	// maxDumpDocumentSize bounds the length prefix of a document read from a
+	// dump, like the server's limit on documents with their overhead.
+	maxDumpDocumentSize = 16*1024*1024 + 16*1024
+
+	namespaceExistsCode = 48
+)
+
+var errDumpIsView = errors.New("views cannot be dumped, dump the collections they read instead")
+
+// DumpResult reports the outcome of a DumpCollection call.
+type DumpResult struct {
+	Documents      int64   `js:"documents"`
+	Bytes          int64   `js:"bytes"`
+	Indexes        int     `js:"indexes"`
+	DurationMillis float64 `js:"durationMillis"`
+}
+
+// RestoreResult reports the outcome of a RestoreCollection call.
+type RestoreResult struct {
+	// Read is the number of documents read from the dump.
+	Read int64 `js:"read"`
+	// Inserted is the number of documents the server accepted; documents
+	// rejected as duplicates or with write errors are counted apart.
+	Inserted       int64   `js:"inserted"`
+	Duplicates     int64   `js:"duplicates"`
+	WriteErrors    int64   `js:"writeErrors"`
+	Batches        int64   `js:"batches"`
+	Indexes        int     `js:"indexes"`
+	DurationMillis float64 `js:"durationMillis"`
+}
+
+// collectionMetadata is the content of a mongodump metadata file.
+type collectionMetadata struct {
+	Options        bson.D   `bson:"options"`
+	Indexes        []bson.D `bson:"indexes"`
+	UUID           string   `bson:"uuid,omitempty"`
+	CollectionName string   `bson:"collectionName"`
+	Type           string   `bson:"type"`
+}
+
+// DumpCollection writes the documents of a collection to
+// dir/database/collection.bson and its options and index definitions to
+// dir/database/collection.metadata.json, the layout of mongodump, so that
+// the files can be read by RestoreCollection or mongorestore. Options:
+// filter, to dump part of the collection.
+func (c *Client) DumpCollection(database string, collection string, dir string, opts map[string]any) (*DumpResult, error) {
+	if dir == "" {
+		return nil, errPathEmpty
+	}
+	parsed, err := parseCommandOptions(opts, "filter")
+	if err != nil {
+		return nil, err
+	}
+	filter := any(bson.D{})
+	if value, ok := lookupOption(opts, "filter"); ok && value != nil {
+		filter = value
+	}
+	col, err := c.getCollection(database, collection)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	start := time.Now()
+	meta, err := c.collectionMetadata(col)
+	if err != nil {
+		log.Printf(errDumpingCollection, database, collection, err)
+		return nil, err
+	}
+
+	bsonPath, metadataPath := dumpPaths(dir, database, collection)
+	if err := os.MkdirAll(filepath.Dir(bsonPath), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(bsonPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result := &DumpResult{Indexes: len(meta.Indexes)}
+	w := bufio.NewWriter(f)
+	err = c.scan("dumpCollection", col, filter, parsed.find(), func(doc bson.Raw) error {
+		result.Documents++
+		result.Bytes += int64(len(doc))
+		_, err := w.Write(doc)
+		return err
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = writeMetadata(metadataPath, meta)
+	}
+	if err != nil {
+		log.Printf(errDumpingCollection, database, collection, err)
+		return nil, err
+	}
+
+	result.DurationMillis = metrics.D(time.Since(start))
+	log.Printf("Dumped %d documents from %s.%s to %s", result.Documents, database, collection, bsonPath)
+	return result, nil
+}
+
+// collectionMetadata reads the options and the index definitions of a
+// collection.
+func (c *Client) collectionMetadata(col *mongo.Collection) (*collectionMetadata, error) {
+	meta := &collectionMetadata{CollectionName: col.Name(), Options: bson.D{}, Indexes: []bson.D{}}
+	err := c.do("dumpCollection", opRead, func(ctx context.Context) error {
+		specs, err := col.Database().ListCollectionSpecifications(ctx, bson.D{{Key: "name", Value: col.Name()}})
+		if err != nil {
+			return err
+		}
+		if len(specs) == 0 {
+			return fmt.Errorf("collection %s.%s does not exist", col.Database().Name(), col.Name())
+		}
+		if specs[0].Type == "view" {
+			return errDumpIsView
+		}
+		meta.Type = specs[0].Type
+		if specs[0].UUID != nil {
+			meta.UUID = hex.EncodeToString(specs[0].UUID.Data)
+		}
+		if len(specs[0].Options) > 0 {
+			if err := bson.Unmarshal(specs[0].Options, &meta.Options); err != nil {
+				return err
+			}
+		}
+
+		cursor, err := col.Indexes().List(ctx)
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, &meta.Indexes)
+	})
+	return meta, err
+}
+
+// RestoreCollection inserts the documents of a dump written by
+// DumpCollection or mongodump, in batches with parallel workers, then
+// creates its indexes. Options: from (the "database.collection" namespace
+// of the dump in dir, default the target), drop (drop the collection
+// first), noIndexRestore, noOptionsRestore (do not create the collection
+// with the options of the dump, such as capped or validator), batchSize
+// (default 1000), workers (default 4) and the insertMany options ordered
+// (default false), bypassDocumentValidation and ignoreDuplicates. The
+// first failing batch stops the workers.
+func (c *Client) RestoreCollection(database string, collection string, dir string, opts map[string]any) (*RestoreResult, error) {
+	if dir == "" {
+		return nil, errPathEmpty
+	}
+	parsed, err := parseCommandOptions(opts, "from", "drop", "noIndexRestore", "noOptionsRestore", optBatchSize,
+		"workers", optBypassDocumentValidation, optIgnoreDuplicates, optOrdered)
+	if err != nil {
+		return nil, err
+	}
+	from, ok, err := stringOption(opts, "from")
+	if err != nil {
+		return nil, err
+	}
+	fromDatabase, fromCollection := database, collection
+	if ok {
+		var found bool
+		if fromDatabase, fromCollection, found = strings.Cut(from, "."); !found || fromDatabase == "" || fromCollection == "" {
+			return nil, fmt.Errorf("option from must be a database.collection namespace, got %q", from)
+		}
+	}
+	drop, _, err := boolOption(opts, "drop")
+	if err != nil {
+		return nil, err
+	}
+	noIndexRestore, _, err := boolOption(opts, "noIndexRestore")
+	if err != nil {
+		return nil, err
+	}
+	noOptionsRestore, _, err := boolOption(opts, "noOptionsRestore")
+	if err != nil {
+		return nil, err
+	}
+	batchSize := defaultSeedBatchSize
+	if parsed.batchSize != nil {
+		batchSize = int(*parsed.batchSize)
+	}
+	workers, ok, err := intOption(opts, "workers")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		workers = defaultSeedWorkers
+	}
+	if workers < 1 {
+		return nil, fmt.Errorf("option workers must be at least 1, got %d", workers)
+	}
+	if parsed.ordered == nil {
+		unordered := false
+		parsed.ordered = &unordered
+	}
+
+	col, err := c.getCollection(database, collection)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	bsonPath, metadataPath := dumpPaths(dir, fromDatabase, fromCollection)
+	meta, err := readMetadata(metadataPath)
+	if err != nil {
+		log.Printf(errRestoringCollection, database, collection, err)
+		return nil, err
+	}
+	f, err := os.Open(bsonPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	start := time.Now()
+	if drop {
+		if err := c.do("restoreCollection", opIdempotentWrite, col.Drop); err != nil {
+			log.Printf(errRestoringCollection, database, collection, err)
+			return nil, err
+		}
+		c.forgetWrites(database, collection)
+	}
+	if !noOptionsRestore && len(meta.Options) > 0 {
+		if err := c.createCollection(col, meta.Options); err != nil {
+			log.Printf(errRestoringCollection, database, collection, err)
+			return nil, err
+		}
+	}
+
+	result, err := c.restoreDocuments(col, bufio.NewReader(f), batchSize, int(workers), parsed)
+	if err != nil {
+		log.Printf(errRestoringCollection, database, collection, err)
+		return nil, err
+	}
+	c.recordWrite("restoreCollection", database, collection, false, int(result.Inserted))
+
+	if specs := meta.indexSpecs(); !noIndexRestore && len(specs) > 0 {
+		err := c.do("restoreCollection", opIdempotentWrite, func(ctx context.Context) error {
+			return col.Database().RunCommand(ctx, bson.D{
+				{Key: "createIndexes", Value: col.Name()},
+				{Key: "indexes", Value: specs},
+			}).Err()
+		})
+		if err != nil {
+			log.Printf(errRestoringCollection, database, collection, err)
+			return nil, err
+		}
+		result.Indexes = len(specs)
+	}
+
+	result.DurationMillis = metrics.D(time.Since(start))
+	log.Printf("Restored %d documents and %d indexes from %s into %s.%s", result.Inserted, result.Indexes,
+		bsonPath, database, collection)
+	return result, nil
+}
+
+// createCollection creates a collection with the options of a dump. A
+// collection that already exists is kept as it is, like mongorestore does.
+func (c *Client) createCollection(col *mongo.Collection, collectionOptions bson.D) error {
+	command := append(bson.D{{Key: "create", Value: col.Name()}}, collectionOptions...)
+	err := c.do("restoreCollection", opIdempotentWrite, func(ctx context.Context) error {
+		return col.Database().RunCommand(ctx, command).Err()
+	})
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == namespaceExistsCode {
+		return nil
+	}
+	return err
+}
+
+// restoreDocuments reads the documents of a dump into batches, which the
+// workers insert.
+func (c *Client) restoreDocuments(col *mongo.Collection, r io.Reader, batchSize, workers int, parsed *commandOptions) (*RestoreResult, error) {
+	parent := context.Background()
+	if c.vu != nil && c.vu.Context() != nil {
+		parent = c.vu.Context()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	progress := &batchProgress{}
+	batches := make(chan []any, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for docs := range batches {
+				if ctx.Err() != nil {
+					continue
+				}
+				result, err := c.insertMany("restoreCollection", col, docs, parsed)
+				if err != nil {
+					progress.fail(err)
+					cancel()
+					continue
+				}
+				progress.inserted.Add(result.InsertedCount)
+				progress.duplicates.Add(result.DuplicateCount)
+				progress.writeErrors.Add(int64(len(result.WriteErrors)))
+				progress.batches.Add(1)
+			}
+		}()
+	}
+
+	var read int64
+	err := func() error {
+		defer close(batches)
+		docs := make([]any, 0, batchSize)
+		for {
+			doc, err := readBSONDocument(r)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("document %d: %w", read+1, err)
+			}
+			read++
+			if docs = append(docs, doc); len(docs) < batchSize {
+				continue
+			}
+			select {
+			case batches <- docs:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			docs = make([]any, 0, batchSize)
+		}
+		if len(docs) > 0 {
+			batches <- docs
+		}
+		return nil
+	}()
+	wg.Wait()
+
+	if progress.err != nil {
+		err = progress.err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("restore stopped after %d documents: %w", progress.inserted.Load(), err)
+	}
+	return &RestoreResult{
+		Read:        read,
+		Inserted:    progress.inserted.Load(),
+		Duplicates:  progress.duplicates.Load(),
+		WriteErrors: progress.writeErrors.Load(),
+		Batches:     progress.batches.Load(),
+	}, nil
+}
+
+// dumpPaths returns the paths of the data and metadata files of a
+// collection in a dump directory.
+func dumpPaths(dir, database, collection string) (string, string) {
+	base := filepath.Join(dir, database, collection)
+	return base + ".bson", base + ".metadata.json"
+}
+
+// readBSONDocument reads the next document of a stream of BSON documents,
+// as written by mongodump.
+func readBSONDocument(r io.Reader) (bson.Raw, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("truncated document length: %w", err)
+		}
+		return nil, err
+	}
+	length := int32(binary.LittleEndian.Uint32(header[:]))
+	if length < 5 || length > maxDumpDocumentSize {
+		return nil, fmt.Errorf("invalid document length %d", length)
+	}
+	doc := make([]byte, length)
+	copy(doc, header[:])
+	if _, err := io.ReadFull(r, doc[4:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("truncated document: %w", err)
+	}
+	if err := bson.Raw(doc).Validate(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// writeMetadata writes a metadata file in canonical Extended JSON, like
+// mongodump.
+func writeMetadata(path string, meta *collectionMetadata) error {
+	data, err := bson.MarshalExtJSON(meta, true, false)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// readMetadata reads a metadata file. Dumps without one are restored
+// without options and indexes, like mongorestore does.
+func readMetadata(path string) (*collectionMetadata, error) {
+	meta := &collectionMetadata{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return meta, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := bson.UnmarshalExtJSON(data, false, meta); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return meta, nil
+}
+
+// indexSpecs returns the index definitions to pass to createIndexes,
+// without the _id index, which the server creates, and without the ns field
+// that servers before 4.4 wrote and newer ones reject.
+func (m *collectionMetadata) indexSpecs() []bson.D {
+	specs := make([]bson.D, 0, len(m.Indexes))
+	for _, index := range m.Indexes {
+		spec := make(bson.D, 0, len(index))
+		for _, e := range index {
+			if e.Key == "name" && e.Value == "_id_" {
+				spec = nil
+				break
+			}
+			if e.Key != "ns" {
+				spec = append(spec, e)
+			}
+		}
+		if spec != nil {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
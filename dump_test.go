@@ -0,0 +1,200 @@
+package xk6_mongo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func mustMarshal(t *testing.T, doc bson.D) bson.Raw {
+	t.Helper()
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	return raw
+}
+
+func TestReadBSONDocument(t *testing.T) {
+	docs := []bson.Raw{
+		mustMarshal(t, bson.D{{Key: "_id", Value: 1}}),
+		mustMarshal(t, bson.D{{Key: "_id", Value: 2}, {Key: "tags", Value: bson.A{"a", "b"}}}),
+		mustMarshal(t, bson.D{}),
+	}
+	var stream []byte
+	for _, doc := range docs {
+		stream = append(stream, doc...)
+	}
+
+	r := bytes.NewReader(stream)
+	for i, want := range docs {
+		got, err := readBSONDocument(r)
+		if err != nil {
+			t.Fatalf("document %d: %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("document %d: expected %v, got %v", i, want, got)
+		}
+	}
+	if _, err := readBSONDocument(r); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+
+	tests := map[string][]byte{
+		"truncated length":   stream[:2],
+		"truncated document": docs[1][:len(docs[1])-3],
+		"length too small":   {4, 0, 0, 0},
+		"length too large":   {0, 0, 0, 2},
+		"invalid document":   {6, 0, 0, 0, 0x7f, 0},
+	}
+	for name, data := range tests {
+		if _, err := readBSONDocument(bytes.NewReader(data)); err == nil || errors.Is(err, io.EOF) {
+			t.Errorf("%s: expected an error, got %v", name, err)
+		}
+	}
+}
+
+func TestCollectionMetadataFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.metadata.json")
+	meta := &collectionMetadata{
+		Options: bson.D{{Key: "capped", Value: true}, {Key: "size", Value: int64(4096)}},
+		Indexes: []bson.D{
+			{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "_id", Value: int32(1)}}}, {Key: "name", Value: "_id_"}},
+			{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "b", Value: int32(1)}, {Key: "a", Value: int32(-1)}}},
+				{Key: "name", Value: "b_1_a_-1"}, {Key: "ns", Value: "shop.orders"}, {Key: "unique", Value: true}},
+		},
+		UUID:           "0123456789abcdef0123456789abcdef",
+		CollectionName: "orders",
+		Type:           "collection",
+	}
+	if err := writeMetadata(path, meta); err != nil {
+		t.Fatalf("writeMetadata failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), `{"options":{"capped":true,"size":{"$numberLong":"4096"}},"indexes":[{"v":{"$numberInt":"2"}`) {
+		t.Errorf("Expected canonical Extended JSON in mongodump order, got %s", data)
+	}
+
+	read, err := readMetadata(path)
+	if err != nil {
+		t.Fatalf("readMetadata failed: %v", err)
+	}
+	if !reflect.DeepEqual(read, meta) {
+		t.Errorf("Expected %v, got %v", meta, read)
+	}
+
+	want := []bson.D{{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "b", Value: int32(1)}, {Key: "a", Value: int32(-1)}}},
+		{Key: "name", Value: "b_1_a_-1"}, {Key: "unique", Value: true}}}
+	if specs := read.indexSpecs(); !reflect.DeepEqual(specs, want) {
+		t.Errorf("Expected the secondary index without ns, got %v", specs)
+	}
+
+	missing, err := readMetadata(filepath.Join(t.TempDir(), "missing.metadata.json"))
+	if err != nil || len(missing.Options) != 0 || len(missing.indexSpecs()) != 0 {
+		t.Errorf("Expected empty metadata for a missing file, got %v (%v)", missing, err)
+	}
+	_ = os.WriteFile(path, []byte("{"), 0o644)
+	if _, err := readMetadata(path); err == nil {
+		t.Error("Expected an error for an invalid metadata file")
+	}
+}
+
+func TestDumpRestoreValidation(t *testing.T) {
+	client := &Client{}
+	if _, err := client.DumpCollection("db", "col", "", nil); err != errPathEmpty {
+		t.Errorf("Expected errPathEmpty, got %v", err)
+	}
+	if _, err := client.DumpCollection("db", "col", "dump", map[string]any{"query": bson.M{}}); err == nil {
+		t.Error("Expected an error for an unknown option")
+	}
+	tests := map[string]map[string]any{
+		"unknown option": {"nsFrom": "db.col"},
+		"bad from":       {"from": "col"},
+		"empty from":     {"from": "db."},
+		"no workers":     {"workers": int64(0)},
+		"bad drop":       {"drop": "yes"},
+	}
+	for name, opts := range tests {
+		if _, err := client.RestoreCollection("db", "col", "dump", opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDumpRestoreCollection(t *testing.T) {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("MONGODB_URI not set")
+	}
+
+	client := new(Mongo).NewClient(uri)
+	if client == nil {
+		t.Fatal("Failed to create client")
+	}
+	defer func() {
+		_ = client.Disconnect()
+	}()
+
+	db := "dumptest"
+	_ = client.DropDatabase(db)
+	defer func() {
+		_ = client.DropDatabase(db)
+	}()
+
+	if err := client.CreateCappedCollection(db, "events", 1<<20, 0); err != nil {
+		t.Fatalf("CreateCappedCollection failed: %v", err)
+	}
+	if _, err := client.CreateIndex(db, "events", bson.D{{Key: "kind", Value: 1}, {Key: "at", Value: -1}}, nil); err != nil {
+		t.Fatalf("CreateIndex failed: %v", err)
+	}
+	docs := make([]any, 250)
+	for i := range docs {
+		docs[i] = bson.D{{Key: "_id", Value: i}, {Key: "kind", Value: i % 3}}
+	}
+	if _, err := client.InsertMany(db, "events", docs, nil); err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	dumped, err := client.DumpCollection(db, "events", dir, nil)
+	if err != nil || dumped.Documents != 250 || dumped.Indexes != 2 {
+		t.Fatalf("DumpCollection dumped %v: %v", dumped, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, db, "events.metadata.json")); err != nil {
+		t.Errorf("Expected a metadata file: %v", err)
+	}
+
+	restored, err := client.RestoreCollection(db, "copy", dir, map[string]any{"from": db + ".events", "batchSize": int64(40), "workers": int64(3)})
+	if err != nil || restored.Inserted != 250 || restored.Batches != 7 || restored.Indexes != 1 {
+		t.Fatalf("RestoreCollection restored %v: %v", restored, err)
+	}
+	col, _ := client.getCollection(db, "copy")
+	if meta, err := client.collectionMetadata(col); err != nil || meta.Options.Map()["capped"] != true {
+		t.Errorf("Expected a capped copy, got %v (%v)", meta, err)
+	}
+	indexes, _ := client.ListIndexes(db, "copy")
+	if len(indexes) != 2 {
+		t.Errorf("Expected 2 indexes, got %v", indexes)
+	}
+
+	again, err := client.RestoreCollection(db, "copy", dir, map[string]any{"from": db + ".events", "drop": true})
+	if err != nil || again.Inserted != 250 {
+		t.Fatalf("RestoreCollection with drop restored %v: %v", again, err)
+	}
+	count, _ := client.CountDocuments(db, "copy", bson.M{}, nil)
+	if count != 250 {
+		t.Errorf("Expected 250 documents, got %d", count)
+	}
+
+	duplicates, err := client.RestoreCollection(db, "copy", dir, map[string]any{"from": db + ".events", "ignoreDuplicates": true})
+	if err != nil || duplicates.Read != 250 || duplicates.Inserted != 0 || duplicates.Duplicates != 250 {
+		t.Errorf("Expected a restore over the same documents to only find duplicates, got %v: %v", duplicates, err)
+	}
+}
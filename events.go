@@ -0,0 +1,43 @@
+package xk6_mongo
+
+import (
+	"context"
+
+	k6modules "go.k6.io/k6/js/modules"
+)
+
+// k6 event types, from go.k6.io/k6/internal/event. Extensions receive the
+// events through vu.Events() but cannot import the package that names
+// them.
+const (
+	k6EventTestEnd = 3
+	k6EventExit    = 6
+)
+
+// testContext is canceled when the test ends. Background work started from
+// setup() outlives the VU that runs setup(), whose context ends with it, and
+// stops with testContext instead.
+var testContext, endTest = context.WithCancel(context.Background())
+
+// watchTestEnd subscribes to the end of the test, after teardown(), and to
+// the exit of k6, which also happens when the test is aborted, to stop the
+// background work and disable the fail points left enabled. Otherwise
+// mongod keeps failing commands for the next run.
+func watchTestEnd(vu k6modules.VU) {
+	events := vu.Events().Global
+	if events == nil {
+		return
+	}
+	id, ch := events.Subscribe(k6EventTestEnd, k6EventExit)
+	go func() {
+		for ev := range ch {
+			endTest()
+			disableTrackedFailPoints()
+			ev.Done()
+			if ev.Type == k6EventExit {
+				events.Unsubscribe(id)
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,21 @@
+import xk6_mongo from 'k6/x/mongo';
+
+const client = xk6_mongo.newClient('mongodb://localhost:27017');
+
+export default () => {
+  const pipeline = [
+    { $match: { correlationId: "test--mongodb" } },
+    { $group: { _id: "$locale", count: { $sum: 1 } } },
+    { $merge: { into: { db: "reports", coll: "locale_counts" }, whenMatched: "replace" } }
+  ];
+
+  const target = client.aggregateWrite("testdb", "testcollection", pipeline, {
+    allowDiskUse: true,
+    maxTime: 5000,
+    comment: "locale report",
+  });
+  console.log(`Aggregation wrote to ${target.database}.${target.collection} via ${target.stage}`);
+
+  const sessions = client.aggregateDatabase("admin", [{ $listLocalSessions: {} }], { readConcern: "local" });
+  console.log(`Local sessions: ${sessions.length}`);
+}
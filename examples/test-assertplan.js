@@ -0,0 +1,33 @@
+import xk6_mongo from 'k6/x/mongo';
+
+const client = xk6_mongo.newClient('mongodb://localhost:27017');
+
+export const options = {
+  thresholds: {
+    checks: ['rate==1'],
+    'mongo_plan_violations{rule:forbidStages}': ['count==0'],
+  },
+};
+
+export function setup() {
+  client.createIndex("testdb", "orders", { user: 1 }, { name: "by_user" });
+  for (let i = 0; i < 100; i++) {
+    client.insert("testdb", "orders", { user: `u${i % 10}`, total: i });
+  }
+}
+
+export default () => {
+  const result = client.assertPlan("testdb", "orders", { find: { filter: { user: "u1" } } }, {
+    name: "orders by user uses by_user",
+    forbidStages: ["COLLSCAN"],
+    expectIndex: "by_user",
+    maxRatio: 1.5,
+  });
+  if (!result.passed) {
+    console.warn(`Plan regression: ${result.violations.join("; ")}`);
+  }
+}
+
+export function teardown() {
+  client.dropCollection("testdb", "orders");
+}
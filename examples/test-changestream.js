@@ -0,0 +1,34 @@
+import xk6_mongo from 'k6/x/mongo';
+
+// Change streams require a MongoDB replica set or sharded cluster
+const client = xk6_mongo.newClient('mongodb://localhost:27017/?replicaSet=rs0');
+
+export default () => {
+  // Pull events explicitly while writing from the same VU
+  const stream = client.openChangeStream("testdb", "testcollection", [
+    { $match: { operationType: "insert" } }
+  ], { fullDocument: "updateLookup", maxAwaitTime: 500 });
+
+  client.insert("testdb", "testcollection", { name: "pulled" });
+  const event = stream.next();
+  console.log(`Pulled ${event.operationType}: ${JSON.stringify(event.fullDocument)}`);
+
+  // Keep the token to resume after a restart
+  const token = stream.resumeToken();
+  stream.close();
+
+  // Push mode: events are delivered on the event loop until close()
+  const resumed = client.openChangeStream("testdb", "testcollection", [], { resumeAfter: token });
+  let received = 0;
+  resumed.on("change", (change) => {
+    received++;
+    console.log(`Callback got ${change.operationType}`);
+    if (received === 2) {
+      resumed.close();
+    }
+  });
+  resumed.on("error", (err) => console.error(`Change stream failed: ${err}`));
+
+  client.insert("testdb", "testcollection", { name: "pushed-1" });
+  client.insert("testdb", "testcollection", { name: "pushed-2" });
+}
@@ -6,3 +6,11 @@ export default () => {
   let count = client.countDocuments("testdb", "testcollection", {correlationId: `test--mongodb`});
   console.log(`Number of documents with correlationId 'test--mongodb': ${count}`);
 }
+
+export function teardown() {
+  // Cheap whole-collection count from metadata, as used by dashboards
+  const estimated = client.estimatedDocumentCount("testdb", "testcollection", { maxTime: 1000 });
+  // Exact count that stops after 1000 matches
+  const capped = client.countDocuments("testdb", "testcollection", { correlationId: `test--mongodb` }, { limit: 1000 });
+  console.log(`Estimated total: ${estimated}, capped exact count: ${capped}`);
+}
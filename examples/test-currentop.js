@@ -0,0 +1,44 @@
+import xk6_mongo from 'k6/x/mongo';
+import { sleep } from 'k6';
+
+const client = xk6_mongo.newClient('mongodb://localhost:27017');
+
+export const options = {
+  scenarios: {
+    load: {
+      executor: 'constant-vus',
+      vus: 5,
+      duration: '30s',
+    },
+    chaos: {
+      executor: 'shared-iterations',
+      vus: 1,
+      iterations: 1,
+      exec: 'chaos',
+    },
+  },
+};
+
+export default () => {
+  client.insert("testdb", "orders", { user: `u${__ITER % 100}`, total: __ITER });
+  client.find("testdb", "orders", { total: { $gt: __ITER } }, null, 10);
+};
+
+export function chaos() {
+  const sampler = client.startCurrentOpSampler({ interval: 1000, filter: { ns: { $regex: "^testdb\\." } } });
+
+  sleep(10);
+  // Kill every operation on testdb that has been running for more than a second.
+  const slow = client.currentOp({ ns: { $regex: "^testdb\\." }, secs_running: { $gte: 1 } }, null);
+  for (const op of slow) {
+    console.log(`killing ${op.opid}: ${op.op} on ${op.ns}`);
+    client.killOp(op.opid);
+  }
+
+  sleep(20);
+  sampler.stop();
+}
+
+export function teardown() {
+  client.dropCollection("testdb", "orders");
+}
@@ -0,0 +1,37 @@
+import xk6_mongo from 'k6/x/mongo';
+
+const client = xk6_mongo.newClient('mongodb://localhost:27017');
+
+const accounts = xk6_mongo.generator({
+  _id: { $gen: "sequence" },
+  owner: { $gen: "name" },
+  balance: { $gen: "float", min: 0, max: 10000, precision: 2 },
+}, { seed: 7 });
+
+export const options = {
+  setupTimeout: '5m',
+  vus: 10,
+  duration: '30s',
+};
+
+export function setup() {
+  // Build the dataset once, keep a snapshot of it, and restore the snapshot
+  // so that every run starts from the same state.
+  client.dropCollection("testdb", "accounts");
+  client.seed("testdb", "accounts", { generator: accounts, count: 100000 });
+  client.createIndex("testdb", "accounts", { owner: 1 });
+  const dumped = client.dumpCollection("testdb", "accounts", "snapshots");
+  console.log(`dumped ${dumped.documents} accounts (${dumped.bytes} bytes, ${dumped.indexes} indexes)`);
+
+  const r = client.restoreCollection("testdb", "accounts", "snapshots", { drop: true, batchSize: 1000, workers: 8 });
+  console.log(`restored ${r.inserted} accounts and ${r.indexes} indexes in ${r.durationMillis.toFixed(0)}ms`);
+}
+
+export default () => {
+  const id = Math.floor(Math.random() * 100000) + 1;
+  client.updateOne("testdb", "accounts", { _id: id }, { $inc: { balance: -1 } });
+};
+
+export function teardown() {
+  client.dropCollection("testdb", "accounts");
+}
@@ -0,0 +1,34 @@
+import xk6_mongo from 'k6/x/mongo';
+
+const client = xk6_mongo.newClient('mongodb://localhost:27017');
+
+export function setup() {
+  client.createIndex("testdb", "orders", { user: 1, createdAt: -1 }, { name: "by_user" });
+  for (let i = 0; i < 100; i++) {
+    client.insert("testdb", "orders", { user: `u${i % 10}`, total: i, createdAt: new Date() });
+  }
+}
+
+export default () => {
+  const find = client.explain("testdb", "orders", {
+    find: { filter: { user: "u1" }, sort: { createdAt: -1 }, limit: 5 },
+  }, "executionStats");
+  console.log(`find: ${JSON.stringify(find.summary)}`);
+
+  const agg = client.explain("testdb", "orders", {
+    aggregate: [{ $match: { total: { $gt: 50 } } }, { $group: { _id: "$user", sum: { $sum: "$total" } } }],
+  });
+  console.log(`aggregate: ${JSON.stringify(agg.summary)}`);
+
+  const update = client.explain("testdb", "orders", {
+    update: { filter: { user: "u2" }, update: { flagged: true }, multi: true },
+  }, "queryPlanner");
+  console.log(`update: ${update.summary.stages.join(" <- ")}`);
+
+  const stats = client.runCommand("testdb", { collStats: "orders", scale: 1024 });
+  console.log(`orders size: ${stats.size} KB`);
+}
+
+export function teardown() {
+  client.dropCollection("testdb", "orders");
+}
@@ -0,0 +1,53 @@
+import xk6_mongo from 'k6/x/mongo';
+import { sleep } from 'k6';
+
+// Requires a replica set.
+const client = xk6_mongo.newClient('mongodb://localhost:27017/?replicaSet=rs0');
+
+export const options = {
+  scenarios: {
+    writes: {
+      executor: 'constant-arrival-rate',
+      rate: 50,
+      timeUnit: '1s',
+      duration: '60s',
+      preAllocatedVUs: 20,
+    },
+    failover: {
+      executor: 'shared-iterations',
+      vus: 1,
+      iterations: 1,
+      exec: 'failover',
+    },
+  },
+  thresholds: {
+    mongo_failover_unavailable_ms: ['max<12000'],
+  },
+};
+
+export default () => {
+  try {
+    client.insert("testdb", "failover", { at: new Date() });
+  } catch (e) {
+    // Failed writes are what the monitor measures.
+  }
+};
+
+export function failover() {
+  const monitor = client.startFailoverMonitor({ interval: 250 });
+
+  sleep(10);
+  client.stepDownPrimary(30, { secondaryCatchUpPeriodSecs: 10 });
+  sleep(40);
+
+  monitor.stop();
+  const report = monitor.report();
+  for (const event of report.events) {
+    console.log(`+${event.offsetMillis.toFixed(0)}ms ${event.type} ${event.host} ${event.detail}`);
+  }
+  console.log(`election took ${report.electionMillis}ms, writes failed for ${report.unavailableMillis}ms (${report.failedWrites} failed writes)`);
+}
+
+export function teardown() {
+  client.dropCollection("testdb", "failover");
+}
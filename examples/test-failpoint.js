@@ -0,0 +1,61 @@
+import xk6_mongo from 'k6/x/mongo';
+import { check } from 'k6';
+
+// Requires a replica set started with --setParameter enableTestCommands=1.
+const client = xk6_mongo.newClient('mongodb://localhost:27017/?replicaSet=rs0');
+
+export const options = {
+  vus: 1,
+  iterations: 1,
+};
+
+export default () => {
+  // Retryable error: retryWrites hides it from the script.
+  client.withFailPoint("failCommand", { times: 1 }, {
+    failCommands: ["insert"],
+    errorCode: 91,
+    errorLabels: ["RetryableWriteError"],
+  }, () => {
+    client.insert("testdb", "failpoints", { case: "errorCode" });
+  });
+
+  // Network error: the driver reconnects and retries the read.
+  client.withFailPoint("failCommand", { times: 1 }, {
+    failCommands: ["find"],
+    closeConnection: true,
+  }, () => {
+    const docs = client.find("testdb", "failpoints", {}, null, 10);
+    check(docs, { "find survived a closed connection": (d) => d.length > 0 });
+  });
+
+  // Slow server: every insert blocks for 200ms for the next 5 inserts.
+  client.withFailPoint("failCommand", { times: 5 }, {
+    failCommands: ["insert"],
+    blockConnection: true,
+    blockTimeMS: 200,
+  }, () => {
+    for (let i = 0; i < 5; i++) {
+      client.insert("testdb", "failpoints", { case: "blockConnection", i });
+    }
+  });
+
+  // Non-retryable error: surfaces to the script.
+  client.failPoint("failCommand", { times: 1 }, {
+    failCommands: ["insert"],
+    errorCode: 2,
+  });
+  let failed = false;
+  try {
+    client.insert("testdb", "failpoints", { case: "nonRetryable" });
+  } catch (e) {
+    failed = true;
+  }
+  check(failed, { "non-retryable error surfaced": (f) => f });
+};
+
+export function teardown() {
+  // Fail points are also turned off when the test ends; turning them off
+  // here keeps them from affecting the cleanup below.
+  client.disableFailPoints();
+  client.dropCollection("testdb", "failpoints");
+}
@@ -0,0 +1,24 @@
+import xk6_mongo from 'k6/x/mongo';
+
+const client = xk6_mongo.newClient('mongodb://localhost:27017');
+
+export function setup() {
+  for (let i = 0; i < 10; i++) {
+    client.insert("testdb", "jobs", { status: "pending", priority: i % 3 });
+  }
+}
+
+export default () => {
+  // Claim the highest-priority pending job
+  const claimed = client.findOneAndUpdate("testdb", "jobs",
+    { status: "pending" },
+    { $set: { status: "running" } },
+    { sort: { priority: -1 }, returnDocument: "after" });
+  console.log(`Claimed: ${JSON.stringify(claimed)}`);
+
+  // Pop the finished job; null means the queue is empty
+  const popped = client.findOneAndDelete("testdb", "jobs", { status: "running" }, { sort: { _id: 1 } });
+  if (popped === null) {
+    console.log("Queue drained");
+  }
+}
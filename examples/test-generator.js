@@ -0,0 +1,38 @@
+import xk6_mongo from 'k6/x/mongo';
+
+const client = xk6_mongo.newClient('mongodb://localhost:27017');
+
+const orders = xk6_mongo.generator({
+  _id: { $gen: "uuid", version: 7 },
+  orderNo: { $gen: "sequence", name: "orderNo", prefix: "ORD-" },
+  customer: {
+    name: { $gen: "name" },
+    email: { $gen: "email" },
+    address: { $gen: "address" },
+  },
+  status: { $gen: "enum", values: ["new", "paid", "shipped", "cancelled"], weights: [2, 5, 3, 1] },
+  total: { $gen: "float", min: 5, max: 500, distribution: "exponential", mean: 60, precision: 2 },
+  productRank: { $gen: "int", min: 1, max: 10000, distribution: "zipf" },
+  items: {
+    $gen: "array", min: 1, max: 5,
+    of: { sku: { $gen: "string", length: 8 }, qty: { $gen: "int", min: 1, max: 3 } },
+  },
+  note: { $gen: "lorem", bytes: 256 },
+  createdAt: { $gen: "date", from: "2024-01-01T00:00:00Z" },
+  source: "k6",
+});
+
+export const options = {
+  vus: 5,
+  duration: '30s',
+};
+
+export default () => {
+  client.insertMany("testdb", "orders", orders.batch(500));
+  client.insert("testdb", "orders", orders);
+  client.bulkWrite("testdb", "orders", orders.insertModels(100));
+};
+
+export function teardown() {
+  client.dropCollection("testdb", "orders");
+}
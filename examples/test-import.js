@@ -0,0 +1,39 @@
+import xk6_mongo from 'k6/x/mongo';
+
+const client = xk6_mongo.newClient('mongodb://localhost:27017');
+
+export const options = {
+  vus: 10,
+  duration: '30s',
+};
+
+export function setup() {
+  client.dropCollection("testdb", "products");
+  client.insertMany("testdb", "products", [
+    { sku: "A-1", name: "Lamp", price: 19.99, stock: { warehouse: "north", qty: 12 }, addedAt: new Date("2024-01-02T00:00:00Z") },
+    { sku: "B-2", name: "Desk", price: 149.5, stock: { warehouse: "south", qty: 3 }, addedAt: new Date("2024-02-03T00:00:00Z") },
+  ]);
+
+  // Write the collection as a fixture in both formats, then load it back.
+  const exported = client.exportFile("testdb", "products", "products.jsonl", { sort: { sku: 1 } });
+  client.exportFile("testdb", "products", "products.csv", {
+    fields: ["sku", "name", "price", "stock.warehouse", "stock.qty", "addedAt"],
+  });
+  console.log(`exported ${exported.exported} products`);
+
+  client.dropCollection("testdb", "products");
+  const fromJSON = client.importFile("testdb", "products", "products.jsonl", { batchSize: 500 });
+  const fromCSV = client.importFile("testdb", "products_csv", "products.csv", {
+    fieldTypes: { sku: "string", price: "double", "stock.qty": "int", addedAt: "date" },
+  });
+  console.log(`imported ${fromJSON.inserted} products from jsonl and ${fromCSV.inserted} from csv`);
+}
+
+export default () => {
+  client.find("testdb", "products", { "stock.warehouse": "north" });
+};
+
+export function teardown() {
+  client.dropCollection("testdb", "products");
+  client.dropCollection("testdb", "products_csv");
+}
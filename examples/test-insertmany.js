@@ -10,9 +10,11 @@ export default () => {
     docobjs.push(getRecord());
   }
 
-  let error = client.insertMany("testdb", "testcollection", docobjs);
-  if (error) 
-    console.log(error.message);
+  const result = client.insertMany("testdb", "testcollection", docobjs, { ordered: false, ignoreDuplicates: true });
+  for (const e of result.writeErrors) {
+    console.log(`document ${e.index} rejected (${e.code}): ${e.message}`);
+  }
+  console.log(`inserted ${result.insertedCount}, duplicates ${result.duplicateCount}`);
 }
 
 function getRecord() {
@@ -0,0 +1,28 @@
+import xk6_mongo from 'k6/x/mongo';
+
+const client = xk6_mongo.newClient('mongodb://localhost:27017');
+
+export const options = {
+  vus: 5,
+  duration: '30s',
+};
+
+export function setup() {
+  client.setProfilingLevel("testdb", 1, { slowms: 20, sampleRate: 1.0 });
+  return { startedAt: new Date().toISOString() };
+}
+
+export default () => {
+  client.insert("testdb", "orders", { user: `u${__ITER % 100}`, total: __ITER });
+  client.find("testdb", "orders", { user: `u${__ITER % 100}` }, null, 10);
+};
+
+export function teardown(data) {
+  const groups = client.harvestProfile("testdb", data.startedAt);
+  for (const g of groups.slice(0, 5)) {
+    console.log(`${g.op} ${g.namespace} ${g.shape}: count=${g.count} p50=${g.p50Millis}ms p95=${g.p95Millis}ms plan=${g.planSummary} docsExamined=${g.docsExamined}`);
+  }
+
+  client.setProfilingLevel("testdb", 0, null);
+  client.dropCollection("testdb", "orders");
+}
@@ -0,0 +1,19 @@
+import xk6_mongo from 'k6/x/mongo';
+
+const client = xk6_mongo.newClient('mongodb://localhost:27017');
+
+export default () => {
+  // Replace the whole profile document, creating it if missing
+  const result = client.replaceOne("testdb", "profiles",
+    { _id: `user-${__VU}` },
+    { name: `User ${__VU}`, plan: "pro", updatedAt: new Date() },
+    { upsert: true });
+  console.log(`matched=${result.matchedCount} modified=${result.modifiedCount} upserted=${result.upsertedId}`);
+
+  // Replace several documents in one round trip
+  const many = client.replaceMany("testdb", "profiles", [
+    { filter: { _id: "user-a" }, replacement: { name: "A", plan: "free" } },
+    { filter: { _id: "user-b" }, replacement: { name: "B", plan: "free" } },
+  ], { upsert: true, ordered: false });
+  console.log(`replaceMany upserted ${many.upsertedCount} documents`);
+}
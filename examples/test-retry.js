@@ -0,0 +1,48 @@
+import xk6_mongo from 'k6/x/mongo';
+
+// Requires a replica set started with --setParameter enableTestCommands=1.
+const client = xk6_mongo.newClient('mongodb://localhost:27017/?replicaSet=rs0');
+client.setRetryPolicy({ maxAttempts: 4, initialBackoff: 50, maxBackoff: 1000, jitter: "equal" });
+
+export const options = {
+  vus: 1,
+  iterations: 1,
+  thresholds: {
+    'mongo_retry_giveups': ['count<2'],
+  },
+};
+
+export default () => {
+  // Three failures: the driver retries once, the policy retries the rest.
+  client.withFailPoint("failCommand", { times: 3 }, {
+    failCommands: ["find"],
+    errorCode: 91,
+  }, () => {
+    client.find("testdb", "retry", {}, null, 10);
+  });
+
+  // Idempotent write: the document has an _id, so it is retried.
+  client.withFailPoint("failCommand", { times: 3 }, {
+    failCommands: ["insert"],
+    closeConnection: true,
+  }, () => {
+    client.insert("testdb", "retry", { _id: `doc-${__ITER}`, value: 1 });
+  });
+
+  // Non-idempotent write after a network error: the outcome is unknown, so
+  // the policy gives up instead of risking a duplicate.
+  client.failPoint("failCommand", { times: 2 }, {
+    failCommands: ["update"],
+    closeConnection: true,
+  });
+  try {
+    client.updateOne("testdb", "retry", { _id: `doc-${__ITER}` }, { $inc: { value: 1 } });
+  } catch (e) {
+    console.log(`gave up: ${e}`);
+  }
+};
+
+export function teardown() {
+  client.disableFailPoints();
+  client.dropCollection("testdb", "retry");
+}
@@ -0,0 +1,39 @@
+import xk6_mongo from 'k6/x/mongo';
+
+const client = xk6_mongo.newClient('mongodb://localhost:27017');
+
+const users = xk6_mongo.generator({
+  _id: { $gen: "sequence" },
+  name: { $gen: "name" },
+  email: { $gen: "email" },
+  age: { $gen: "int", min: 18, max: 90, distribution: "normal", mean: 40, stddev: 12 },
+  plan: { $gen: "enum", values: ["free", "pro", "enterprise"], weights: [80, 18, 2] },
+  signedUpAt: { $gen: "date", from: "2020-01-01T00:00:00Z" },
+}, { seed: 1 });
+
+export const options = {
+  setupTimeout: '10m',
+  vus: 10,
+  duration: '30s',
+};
+
+export function setup() {
+  client.dropCollection("testdb", "users");
+  const r = client.seed("testdb", "users", {
+    generator: users,
+    count: 1000000,
+    batchSize: 1000,
+    workers: 8,
+    ordered: false,
+  });
+  console.log(`seeded ${r.inserted} users in ${(r.durationMillis / 1000).toFixed(1)}s (${r.docsPerSecond.toFixed(0)} docs/s)`);
+}
+
+export default () => {
+  const id = Math.floor(Math.random() * 1000000) + 1;
+  client.findOne("testdb", "users", { _id: id });
+};
+
+export function teardown() {
+  client.dropCollection("testdb", "users");
+}
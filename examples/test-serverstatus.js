@@ -0,0 +1,24 @@
+import xk6_mongo from 'k6/x/mongo';
+
+const client = xk6_mongo.newClient('mongodb://localhost:27017');
+
+export const options = {
+  vus: 10,
+  duration: '1m',
+  thresholds: {
+    'mongo_server_globallock_queue{type:writers}': ['value<10'],
+  },
+};
+
+export function setup() {
+  // A sampler started from setup() keeps sampling until the test ends.
+  client.startServerStatusSampler({ interval: 2000 });
+}
+
+export default () => {
+  client.insert("testdb", "load", { at: new Date(), payload: "x".repeat(256) });
+};
+
+export function teardown() {
+  client.dropCollection("testdb", "load");
+}
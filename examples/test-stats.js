@@ -0,0 +1,33 @@
+import xk6_mongo from 'k6/x/mongo';
+
+const client = xk6_mongo.newClient('mongodb://localhost:27017');
+
+export const options = {
+  vus: 5,
+  iterations: 200,
+};
+
+export function setup() {
+  client.createIndex("testdb", "orders", { user: 1 }, { name: "by_user" });
+  return { stats: client.statsSnapshot("testdb", ["orders"]) };
+}
+
+export default () => {
+  client.insert("testdb", "orders", { user: `u${__ITER % 10}`, total: __ITER });
+  client.find("testdb", "orders", { user: "u1" }, null, 5);
+};
+
+export function handleSummary(data) {
+  const diff = client.diffStats(data.setup_data.stats, client.statsSnapshot("testdb", ["orders"]));
+  const orders = diff.collections.orders;
+  const report = [
+    `documents created: ${orders.count}`,
+    `storage growth: ${orders.storageSize} bytes`,
+    `indexes used: ${orders.usedIndexes.join(", ")}`,
+    `indexes unused: ${orders.unusedIndexes.join(", ")}`,
+  ].join("\n");
+
+  // teardown() runs before handleSummary(), so clean up here instead.
+  client.dropCollection("testdb", "orders");
+  return { stdout: report + "\n" };
+}
@@ -0,0 +1,24 @@
+import xk6_mongo from 'k6/x/mongo';
+
+const client = xk6_mongo.newClient('mongodb://localhost:27017');
+
+export function setup() {
+  client.dropCollection("testdb", "queue");
+  client.createCappedCollection("testdb", "queue", 1024 * 1024, 1000);
+  // A tailable cursor on an empty capped collection is closed immediately,
+  // so seed one document before consumers start tailing.
+  client.insert("testdb", "queue", { kind: "bootstrap", sentAt: new Date() });
+}
+
+export default () => {
+  const cursor = client.openTailableCursor("testdb", "queue", { kind: "job" }, {
+    maxAwaitTime: 500,
+    latencyField: "sentAt"
+  });
+
+  client.insert("testdb", "queue", { kind: "job", sentAt: new Date() });
+
+  const doc = cursor.next();
+  console.log(`Consumed job ${JSON.stringify(doc)}`);
+  cursor.close();
+}
@@ -0,0 +1,23 @@
+import xk6_mongo from 'k6/x/mongo';
+import { sleep } from 'k6';
+
+// Polling heartbeats report an RTT sample on every check.
+const client = xk6_mongo.newClient('mongodb://localhost:27017/?replicaSet=rs0&serverMonitoringMode=poll&heartbeatFrequencyMS=1000');
+
+export const options = {
+  vus: 1,
+  duration: '30s',
+  thresholds: {
+    'mongo_heartbeat_rtt': ['p(95)<50'],
+    'mongo_heartbeat_failures': ['count<1'],
+  },
+};
+
+export default () => {
+  const topology = client.topology();
+  console.log(`${topology.kind} ${topology.setName} (${topology.changes} changes)`);
+  for (const server of topology.servers) {
+    console.log(`  ${server.host} ${server.role} rtt=${server.rttMillis.toFixed(1)}ms electionId=${server.electionId}`);
+  }
+  sleep(5);
+};
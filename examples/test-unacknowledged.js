@@ -0,0 +1,33 @@
+import xk6_mongo from 'k6/x/mongo';
+
+const client = xk6_mongo.newClient('mongodb://localhost:27017');
+
+export const options = {
+  vus: 10,
+  duration: '30s',
+  thresholds: {
+    'mongo_documents_sent{acknowledged:false}': ['count>0'],
+  },
+};
+
+export function setup() {
+  client.dropCollection("testdb", "events");
+}
+
+export default () => {
+  const docs = [];
+  for (let i = 0; i < 100; i++) {
+    docs.push({ vu: __VU, iter: __ITER, seq: i, ts: new Date() });
+  }
+  // Fire and forget: returns once the batch is sent.
+  client.insertMany("testdb", "events", docs, { unacknowledged: true });
+
+  // Acknowledged write for comparison.
+  client.insert("testdb", "events", { vu: __VU, iter: __ITER, checkpoint: true });
+};
+
+export function teardown() {
+  const r = client.reconcileWrites("testdb", "events", { wait: 5000 });
+  console.log(`sent=${r.sent} (unacknowledged=${r.unacknowledged}) landed=${r.landed} missing=${r.missing} lossRate=${r.lossRate}`);
+  client.dropCollection("testdb", "events");
+}
@@ -0,0 +1,24 @@
+import xk6_mongo from 'k6/x/mongo';
+
+// Change streams require a MongoDB replica set or sharded cluster
+const client = xk6_mongo.newClient('mongodb://localhost:27017/?replicaSet=rs0');
+
+export default () => {
+  // Follow every collection in the database, e.g. an event-sourcing fan-out
+  const stream = client.openDatabaseChangeStream("testdb", [
+    { $match: { operationType: { $in: ["insert", "update"] } } }
+  ], { batchSize: 500, maxAwaitTime: 200 });
+
+  client.insert("testdb", "orders", { status: "new" });
+  client.insert("testdb", "payments", { amount: 10 });
+
+  let event;
+  while ((event = stream.tryNext()) !== null) {
+    console.log(`${event.ns.coll}: ${event.operationType}`);
+  }
+  stream.close();
+
+  // Blocking variant over the whole deployment
+  const events = client.watchCluster([], 2000);
+  console.log(`Received ${events.length} deployment-wide events`);
+}
@@ -0,0 +1,314 @@
+package xk6_mongo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/grafana/sobek"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	errExplaining = "Error while explaining: %v"
+
+	defaultExplainVerbosity = "executionStats"
+)
+
+var errExplainQuery = errors.New("explain query must have exactly one of find, aggregate, update, delete, count or distinct")
+
+// ExplainSummary is the part of an explain output that load tests usually
+// assert on, normalized across commands and server versions.
+type ExplainSummary struct {
+	// Stages lists the winning plan's stages from the root down, e.g.
+	// ["FETCH", "IXSCAN"].
+	Stages []string `js:"stages"`
+	// PipelineStages lists the aggregation stages run after the query layer.
+	PipelineStages      []string `js:"pipelineStages"`
+	IndexesUsed         []string `js:"indexesUsed"`
+	KeysExamined        int64    `js:"keysExamined"`
+	DocsExamined        int64    `js:"docsExamined"`
+	NReturned           int64    `js:"nReturned"`
+	ExecutionTimeMillis int64    `js:"executionTimeMillis"`
+}
+
+// ExplainResult holds the raw explain output and its summary.
+type ExplainResult struct {
+	Raw     bson.M         `js:"raw"`
+	Summary ExplainSummary `js:"summary"`
+}
+
+// Explain explains one command on a collection. query holds a single key
+// naming the command, whose value describes it:
+//
+//	{find: {filter, sort, projection, limit, skip, hint, collation}}
+//	{aggregate: pipeline} or {aggregate: {pipeline, allowDiskUse, hint, collation}}
+//	{update: {filter, update, multi, upsert, arrayFilters, hint, collation}}
+//	{delete: {filter, limit, hint, collation}}
+//	{count: {filter, limit, skip, hint, collation}}
+//	{distinct: {key, filter, collation}}
+//
+// verbosity is "queryPlanner", "executionStats" (the default) or
+// "allPlansExecution". Field order is kept, so compound sorts and hints are
+// explained as written.
+func (c *Client) Explain(database string, collection string, query sobek.Value, verbosity string) (*ExplainResult, error) {
+	if err := validateDatabaseAndCollection(database, collection); err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+	if verbosity == "" {
+		verbosity = defaultExplainVerbosity
+	}
+	switch verbosity {
+	case "queryPlanner", "executionStats", "allPlansExecution":
+	default:
+		return nil, fmt.Errorf("unknown explain verbosity %q", verbosity)
+	}
+
+	ordered, err := orderedCommand(query)
+	if err != nil {
+		return nil, err
+	}
+	cmd, err := explainableCommand(collection, ordered)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := c.runCommand(database, bson.D{
+		{Key: "explain", Value: cmd},
+		{Key: "verbosity", Value: verbosity},
+	})
+	if err != nil {
+		log.Printf(errExplaining, err)
+		return nil, err
+	}
+	return &ExplainResult{Raw: raw, Summary: summarizeExplain(raw)}, nil
+}
+
+// explainableCommand builds the command document to explain from query.
+func explainableCommand(collection string, query bson.D) (bson.D, error) {
+	if len(query) != 1 {
+		return nil, errExplainQuery
+	}
+	name, spec := query[0].Key, query[0].Value
+
+	if name == "aggregate" {
+		if _, ok := stageDocument(spec); !ok {
+			spec = map[string]any{"pipeline": spec}
+		}
+	}
+	body, ok := stageDocument(spec)
+	if !ok {
+		return nil, fmt.Errorf("explain %s must be an object, got %T", name, spec)
+	}
+
+	switch name {
+	case "find":
+		return explainFields(bson.D{{Key: "find", Value: collection}}, body,
+			"filter", "sort", "projection", "limit", "skip", "hint", "collation")
+	case "aggregate":
+		if body["pipeline"] == nil {
+			return nil, errPipelineNil
+		}
+		cmd, err := explainFields(bson.D{{Key: "aggregate", Value: collection}}, body,
+			"pipeline", "allowDiskUse", "hint", "collation", "let")
+		if err != nil {
+			return nil, err
+		}
+		return append(cmd, bson.E{Key: "cursor", Value: bson.D{}}), nil
+	case "update":
+		return explainUpdate(collection, body)
+	case "delete":
+		return explainDelete(collection, body)
+	case "count":
+		// The count command calls its filter "query".
+		cmd, err := explainFields(bson.D{{Key: "count", Value: collection}}, body,
+			"filter", "limit", "skip", "hint", "collation")
+		return renameField(cmd, "filter", "query"), err
+	case "distinct":
+		if key, _ := body["key"].(string); key == "" {
+			return nil, errors.New("explain distinct requires a key")
+		}
+		cmd, err := explainFields(bson.D{{Key: "distinct", Value: collection}}, body,
+			"key", "filter", "collation")
+		return renameField(cmd, "filter", "query"), err
+	}
+	return nil, errExplainQuery
+}
+
+func explainUpdate(collection string, body map[string]any) (bson.D, error) {
+	if body["filter"] == nil {
+		return nil, errFilterNil
+	}
+	stmt, err := explainFields(bson.D{}, body,
+		"filter", "update", "multi", "upsert", "arrayFilters", "hint", "collation")
+	if err != nil {
+		return nil, err
+	}
+
+	update, err := prepareUpdateDocument(body["update"])
+	if err != nil {
+		return nil, err
+	}
+	for i := range stmt {
+		switch stmt[i].Key {
+		case "filter":
+			stmt[i].Key = "q"
+		case "update":
+			stmt[i] = bson.E{Key: "u", Value: update}
+		}
+	}
+	return bson.D{
+		{Key: "update", Value: collection},
+		{Key: "updates", Value: bson.A{stmt}},
+	}, nil
+}
+
+func explainDelete(collection string, body map[string]any) (bson.D, error) {
+	if body["filter"] == nil {
+		return nil, errFilterNil
+	}
+	stmt, err := explainFields(bson.D{}, body, "filter", "limit", "hint", "collation")
+	if err != nil {
+		return nil, err
+	}
+
+	stmt = renameField(stmt, "filter", "q")
+	if _, ok := body["limit"]; !ok {
+		// A delete statement must say whether it removes one or all matches.
+		stmt = append(stmt, bson.E{Key: "limit", Value: 0})
+	}
+	return bson.D{
+		{Key: "delete", Value: collection},
+		{Key: "deletes", Value: bson.A{stmt}},
+	}, nil
+}
+
+// explainFields appends the fields of body to cmd in the order given by
+// allowed, failing on any field that is not allowed.
+func explainFields(cmd bson.D, body map[string]any, allowed ...string) (bson.D, error) {
+	for key := range body {
+		found := false
+		for _, name := range allowed {
+			if key == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unsupported explain field %q", key)
+		}
+	}
+	for _, name := range allowed {
+		if value, ok := body[name]; ok && value != nil {
+			cmd = append(cmd, bson.E{Key: name, Value: value})
+		}
+	}
+	return cmd, nil
+}
+
+func renameField(doc bson.D, from, to string) bson.D {
+	for i := range doc {
+		if doc[i].Key == from {
+			doc[i].Key = to
+		}
+	}
+	return doc
+}
+
+// summarizeExplain extracts the winning plan and execution statistics from an
+// explain output. Aggregations that were not pushed down into the query layer
+// report them under their leading $cursor stage.
+func summarizeExplain(raw bson.M) ExplainSummary {
+	summary := ExplainSummary{Stages: []string{}, PipelineStages: []string{}, IndexesUsed: []string{}}
+
+	planner, _ := stageDocument(raw["queryPlanner"])
+	stats, _ := stageDocument(raw["executionStats"])
+
+	stages, _ := documentArray(raw["stages"])
+	for _, item := range stages {
+		stage, ok := stageDocument(item)
+		if !ok {
+			continue
+		}
+		for name, spec := range stage {
+			// Entries also carry per-stage statistics such as nReturned.
+			if !strings.HasPrefix(name, "$") {
+				continue
+			}
+			if name != "$cursor" {
+				summary.PipelineStages = append(summary.PipelineStages, name)
+				continue
+			}
+			if cursor, ok := stageDocument(spec); ok {
+				planner, _ = stageDocument(cursor["queryPlanner"])
+				stats, _ = stageDocument(cursor["executionStats"])
+			}
+		}
+	}
+
+	if planner != nil {
+		summary.walkPlan(planner["winningPlan"])
+	}
+	if stats != nil {
+		summary.KeysExamined, _ = toInt64(stats["totalKeysExamined"])
+		summary.DocsExamined, _ = toInt64(stats["totalDocsExamined"])
+		summary.NReturned, _ = toInt64(stats["nReturned"])
+		summary.ExecutionTimeMillis, _ = toInt64(stats["executionTimeMillis"])
+	}
+	return summary
+}
+
+// walkPlan records the stages and indexes of a plan tree. Slot-based plans
+// nest the classic tree under queryPlan and sharded plans list one winning
+// plan per shard.
+func (s *ExplainSummary) walkPlan(value any) {
+	plan, ok := stageDocument(value)
+	if !ok {
+		return
+	}
+	if inner, ok := plan["queryPlan"]; ok {
+		s.walkPlan(inner)
+		return
+	}
+
+	if stage, ok := plan["stage"].(string); ok {
+		s.Stages = append(s.Stages, stage)
+	}
+	if index, ok := plan["indexName"].(string); ok {
+		s.addIndex(index)
+	}
+
+	s.walkPlan(plan["inputStage"])
+	children, _ := documentArray(plan["inputStages"])
+	for _, child := range children {
+		s.walkPlan(child)
+	}
+	shards, _ := documentArray(plan["shards"])
+	for _, item := range shards {
+		if shard, ok := stageDocument(item); ok {
+			s.walkPlan(shard["winningPlan"])
+		}
+	}
+}
+
+func (s *ExplainSummary) addIndex(name string) {
+	for _, existing := range s.IndexesUsed {
+		if existing == name {
+			return
+		}
+	}
+	s.IndexesUsed = append(s.IndexesUsed, name)
+}
+
+// documentArray returns value as a slice when it is an array.
+func documentArray(value any) ([]any, bool) {
+	switch v := value.(type) {
+	case []any:
+		return v, true
+	case bson.A:
+		return v, true
+	}
+	return nil, false
+}
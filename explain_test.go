@@ -0,0 +1,200 @@
+package xk6_mongo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestOrderedCommand(t *testing.T) {
+	rt := sobek.New()
+	value, err := rt.RunString(`({collStats: "orders", scale: 1024, comment: "k6"})`)
+	if err != nil {
+		t.Fatalf("RunString failed: %v", err)
+	}
+
+	cmd, err := orderedCommand(value)
+	if err != nil {
+		t.Fatalf("orderedCommand failed: %v", err)
+	}
+	keys := make([]string, 0, len(cmd))
+	for _, e := range cmd {
+		keys = append(keys, e.Key)
+	}
+	if !reflect.DeepEqual(keys, []string{"collStats", "scale", "comment"}) {
+		t.Errorf("Expected field order to be preserved, got %v", keys)
+	}
+
+	value, err = rt.RunString(`({createIndexes: "orders", indexes: [{key: {z: 1, a: -1}, name: "z_a"}]})`)
+	if err != nil {
+		t.Fatalf("RunString failed: %v", err)
+	}
+	cmd, err = orderedCommand(value)
+	if err != nil {
+		t.Fatalf("orderedCommand failed: %v", err)
+	}
+	want := bson.D{{Key: "createIndexes", Value: "orders"}, {Key: "indexes", Value: bson.A{
+		bson.D{{Key: "key", Value: bson.D{{Key: "z", Value: int64(1)}, {Key: "a", Value: int64(-1)}}}, {Key: "name", Value: "z_a"}}}}}
+	if !reflect.DeepEqual(cmd, want) {
+		t.Errorf("Expected nested field order to be preserved, got %v", cmd)
+	}
+
+	for name, src := range map[string]string{"empty object": "({})", "string": `"ping"`, "undefined": "undefined"} {
+		t.Run(name, func(t *testing.T) {
+			value, err := rt.RunString(src)
+			if err != nil {
+				t.Fatalf("RunString failed: %v", err)
+			}
+			if _, err := orderedCommand(value); err == nil {
+				t.Error("Expected error for invalid command")
+			}
+		})
+	}
+}
+
+func TestExplainValidation(t *testing.T) {
+	client := &Client{}
+	query := sobek.New().ToValue(map[string]any{"find": map[string]any{}})
+
+	t.Run("empty collection", func(t *testing.T) {
+		_, err := client.Explain("db", "", query, "")
+		if err == nil {
+			t.Error("Expected error for empty collection")
+		}
+	})
+
+	t.Run("unknown verbosity", func(t *testing.T) {
+		_, err := client.Explain("db", "col", query, "verbose")
+		if err == nil {
+			t.Error("Expected error for unknown verbosity")
+		}
+	})
+
+	invalid := map[string]bson.D{
+		"no command":         {},
+		"two commands":       {{Key: "find", Value: bson.D{}}, {Key: "count", Value: bson.D{}}},
+		"unknown command":    {{Key: "insert", Value: bson.D{}}},
+		"unknown find field": {{Key: "find", Value: bson.D{{Key: "filter", Value: bson.D{}}, {Key: "batchSize", Value: 10}}}},
+		"update no filter":   {{Key: "update", Value: bson.D{{Key: "update", Value: bson.D{{Key: "a", Value: 1}}}}}},
+		"delete no filter":   {{Key: "delete", Value: bson.D{}}},
+		"distinct no key":    {{Key: "distinct", Value: bson.D{{Key: "filter", Value: bson.D{}}}}},
+		"nil pipeline":       {{Key: "aggregate", Value: nil}},
+	}
+	for name, query := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := explainableCommand("col", query); err == nil {
+				t.Error("Expected error for invalid explain query")
+			}
+		})
+	}
+}
+
+func TestExplainableCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		query bson.D
+		want  bson.D
+	}{
+		{
+			"find",
+			bson.D{{Key: "find", Value: bson.D{{Key: "limit", Value: 5}, {Key: "filter", Value: bson.D{{Key: "user", Value: "u1"}}},
+				{Key: "sort", Value: bson.D{{Key: "z", Value: 1}, {Key: "a", Value: -1}}}}}},
+			bson.D{{Key: "find", Value: "col"}, {Key: "filter", Value: bson.D{{Key: "user", Value: "u1"}}},
+				{Key: "sort", Value: bson.D{{Key: "z", Value: 1}, {Key: "a", Value: -1}}}, {Key: "limit", Value: 5}},
+		},
+		{
+			"aggregate pipeline",
+			bson.D{{Key: "aggregate", Value: bson.A{bson.D{{Key: "$match", Value: bson.D{}}}}}},
+			bson.D{{Key: "aggregate", Value: "col"}, {Key: "pipeline", Value: bson.A{bson.D{{Key: "$match", Value: bson.D{}}}}},
+				{Key: "cursor", Value: bson.D{}}},
+		},
+		{
+			"update wraps plain document",
+			bson.D{{Key: "update", Value: bson.D{{Key: "filter", Value: bson.D{{Key: "_id", Value: 1}}},
+				{Key: "update", Value: bson.D{{Key: "a", Value: 1}}}, {Key: "multi", Value: true}}}},
+			bson.D{{Key: "update", Value: "col"}, {Key: "updates", Value: bson.A{bson.D{
+				{Key: "q", Value: bson.D{{Key: "_id", Value: 1}}}, {Key: "u", Value: bson.D{{Key: "$set", Value: bson.D{{Key: "a", Value: 1}}}}},
+				{Key: "multi", Value: true}}}}},
+		},
+		{
+			"delete defaults to all matches",
+			bson.D{{Key: "delete", Value: bson.D{{Key: "filter", Value: bson.D{{Key: "a", Value: 1}}}}}},
+			bson.D{{Key: "delete", Value: "col"}, {Key: "deletes", Value: bson.A{bson.D{
+				{Key: "q", Value: bson.D{{Key: "a", Value: 1}}}, {Key: "limit", Value: 0}}}}},
+		},
+		{
+			"count renames filter",
+			bson.D{{Key: "count", Value: bson.D{{Key: "filter", Value: bson.D{{Key: "a", Value: 1}}}}}},
+			bson.D{{Key: "count", Value: "col"}, {Key: "query", Value: bson.D{{Key: "a", Value: 1}}}},
+		},
+		{
+			"distinct",
+			bson.D{{Key: "distinct", Value: bson.D{{Key: "key", Value: "status"}}}},
+			bson.D{{Key: "distinct", Value: "col"}, {Key: "key", Value: "status"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := explainableCommand("col", tt.query)
+			if err != nil {
+				t.Fatalf("explainableCommand failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("explainableCommand = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeExplain(t *testing.T) {
+	ixscan := bson.M{"stage": "FETCH", "inputStage": bson.M{"stage": "IXSCAN", "indexName": "by_user"}}
+	stats := bson.M{"totalKeysExamined": int32(10), "totalDocsExamined": int32(10),
+		"nReturned": int32(10), "executionTimeMillis": int32(3)}
+
+	tests := []struct {
+		name string
+		raw  bson.M
+		want ExplainSummary
+	}{
+		{
+			"classic find",
+			bson.M{"queryPlanner": bson.M{"winningPlan": ixscan}, "executionStats": stats},
+			ExplainSummary{Stages: []string{"FETCH", "IXSCAN"}, PipelineStages: []string{},
+				IndexesUsed: []string{"by_user"}, KeysExamined: 10, DocsExamined: 10, NReturned: 10, ExecutionTimeMillis: 3},
+		},
+		{
+			"slot-based plan",
+			bson.M{"queryPlanner": bson.M{"winningPlan": bson.M{"queryPlan": bson.M{"stage": "COLLSCAN"}}},
+				"executionStats": bson.M{"totalDocsExamined": int64(500), "nReturned": int64(2)}},
+			ExplainSummary{Stages: []string{"COLLSCAN"}, PipelineStages: []string{}, IndexesUsed: []string{},
+				DocsExamined: 500, NReturned: 2},
+		},
+		{
+			"aggregate with $cursor",
+			bson.M{"stages": bson.A{
+				bson.M{"$cursor": bson.M{"queryPlanner": bson.M{"winningPlan": ixscan}, "executionStats": stats}},
+				bson.M{"$group": bson.M{"_id": "$user"}, "nReturned": int64(2)},
+			}},
+			ExplainSummary{Stages: []string{"FETCH", "IXSCAN"}, PipelineStages: []string{"$group"},
+				IndexesUsed: []string{"by_user"}, KeysExamined: 10, DocsExamined: 10, NReturned: 10, ExecutionTimeMillis: 3},
+		},
+		{
+			"sharded",
+			bson.M{"queryPlanner": bson.M{"winningPlan": bson.M{"stage": "SHARD_MERGE", "shards": bson.A{
+				bson.M{"shardName": "a", "winningPlan": ixscan},
+				bson.M{"shardName": "b", "winningPlan": ixscan},
+			}}}},
+			ExplainSummary{Stages: []string{"SHARD_MERGE", "FETCH", "IXSCAN", "FETCH", "IXSCAN"},
+				PipelineStages: []string{}, IndexesUsed: []string{"by_user"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarizeExplain(tt.raw); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("summarizeExplain = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,519 @@
+package xk6_mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.k6.io/k6/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+const (
+	errSteppingDown   = "Error while stepping down the primary: %v"
+	errFreezingMember = "Error while freezing member %s: %v"
+	errPollingMember  = "Error while polling member %s: %v"
+)
+
+const defaultFailoverPoll = 500 * time.Millisecond
+
+// Failover timeline event types.
+const (
+	eventPrimaryObserved    = "primary_observed"
+	eventStepDownRequested  = "stepdown_requested"
+	eventMemberFrozen       = "member_frozen"
+	eventPrimarySteppedDown = "primary_stepped_down"
+	eventPrimaryElected     = "primary_elected"
+	eventWritesFailing      = "writes_failing"
+	eventWritesRecovered    = "writes_recovered"
+)
+
+var (
+	errHostEmpty       = errors.New("host cannot be empty")
+	errSecondsNegative = errors.New("seconds cannot be negative")
+)
+
+// notPrimaryErrorCodes are the server error codes returned while a replica
+// set has no writable primary or a member is shutting down.
+var notPrimaryErrorCodes = []int{
+	91,    // ShutdownInProgress
+	189,   // PrimarySteppedDown
+	10107, // NotWritablePrimary
+	11600, // InterruptedAtShutdown
+	11602, // InterruptedDueToReplStateChange
+	13435, // NotPrimaryNoSecondaryOk
+	13436, // NotPrimaryOrSecondary
+}
+
+// isUnavailableError reports whether err means the deployment could not
+// accept the operation: a not-primary rejection, a network error or no
+// server to select. Other failures, including operations that merely timed
+// out, say nothing about the primary.
+func isUnavailableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if mongo.IsNetworkError(err) || errors.As(err, &topology.ServerSelectionError{}) {
+		return true
+	}
+	var se mongo.ServerError
+	if errors.As(err, &se) {
+		for _, code := range notPrimaryErrorCodes {
+			if se.HasErrorCode(code) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// StepDownPrimary asks the primary to step down and not seek re-election for
+// seconds. Options: secondaryCatchUpPeriodSecs and force.
+func (c *Client) StepDownPrimary(seconds int64, opts map[string]any) error {
+	if seconds < 0 {
+		return errSecondsNegative
+	}
+	if err := checkAllowedOptions(opts, "secondaryCatchUpPeriodSecs", "force"); err != nil {
+		return err
+	}
+
+	cmd := bson.D{{Key: "replSetStepDown", Value: seconds}}
+	catchUp, ok, err := intOption(opts, "secondaryCatchUpPeriodSecs")
+	if err != nil {
+		return err
+	}
+	if ok {
+		cmd = append(cmd, bson.E{Key: "secondaryCatchUpPeriodSecs", Value: catchUp})
+	}
+	force, ok, err := boolOption(opts, "force")
+	if err != nil {
+		return err
+	}
+	if ok {
+		cmd = append(cmd, bson.E{Key: "force", Value: force})
+	}
+
+	notifyFailoverMonitors(c.deploymentKey(), eventStepDownRequested, "", fmt.Sprintf("%ds", seconds))
+	// Servers before 4.2 close every connection when stepping down, so the
+	// reply may never arrive even though the step down succeeded.
+	if _, err := c.runCommand("admin", cmd); err != nil && !mongo.IsNetworkError(err) {
+		log.Printf(errSteppingDown, err)
+		return err
+	}
+	log.Printf("Primary stepped down for %ds", seconds)
+	return nil
+}
+
+// Freeze prevents the member at host from seeking election for seconds; 0
+// unfreezes it. Freezing every secondary but one decides which member wins
+// the next election.
+func (c *Client) Freeze(host string, seconds int64) error {
+	if host == "" {
+		return errHostEmpty
+	}
+	if seconds < 0 {
+		return errSecondsNegative
+	}
+
+	ctx, cancel := c.getContext()
+	defer cancel()
+
+	conn, err := c.connectToHost(ctx, host)
+	if err != nil {
+		log.Printf(errFreezingMember, host, err)
+		return err
+	}
+	defer conn.Disconnect(ctx)
+
+	err = conn.Database("admin").RunCommand(ctx, bson.D{{Key: "replSetFreeze", Value: seconds}}).Err()
+	if err != nil {
+		log.Printf(errFreezingMember, host, err)
+		return err
+	}
+	notifyFailoverMonitors(c.deploymentKey(), eventMemberFrozen, host, fmt.Sprintf("%ds", seconds))
+	log.Printf("Member %s frozen for %ds", host, seconds)
+	return nil
+}
+
+// failoverMonitors lists the running monitors by deployment, so that writes
+// made by every VU's client are attributed to them.
+var failoverMonitors = struct {
+	sync.RWMutex
+	byDeployment map[string]map[*FailoverMonitor]struct{}
+}{byDeployment: map[string]map[*FailoverMonitor]struct{}{}}
+
+func notifyFailoverMonitors(key, kind, host, detail string) {
+	for _, m := range activeFailoverMonitors(key) {
+		m.addEvent(time.Now(), kind, host, detail)
+	}
+}
+
+func activeFailoverMonitors(key string) []*FailoverMonitor {
+	failoverMonitors.RLock()
+	defer failoverMonitors.RUnlock()
+
+	monitors := make([]*FailoverMonitor, 0, len(failoverMonitors.byDeployment[key]))
+	for m := range failoverMonitors.byDeployment[key] {
+		monitors = append(monitors, m)
+	}
+	return monitors
+}
+
+// observeWrite reports the outcome of a write started at start to the
+// failover monitors and emits mongo_failover_unavailable_ms once when it ends
+// a window of failed writes. Monitors started at different times may see
+// windows of different lengths; the longest is emitted.
+func (c *Client) observeWrite(start time.Time, err error) {
+	monitors := activeFailoverMonitors(c.deploymentKey())
+	if len(monitors) == 0 {
+		return
+	}
+	end := time.Now()
+	var longest time.Duration
+	closed := false
+	for _, m := range monitors {
+		if window, ok := m.observeWrite(start, end, err); ok {
+			closed = true
+			longest = max(longest, window)
+		}
+	}
+	if closed && c.metrics != nil {
+		c.emit(c.metrics.failoverUnavailable, metrics.D(longest), nil)
+	}
+}
+
+// FailoverEvent is an entry of the failover timeline.
+type FailoverEvent struct {
+	Time string `js:"time"`
+	// OffsetMillis is the time elapsed since the monitor started.
+	OffsetMillis float64 `js:"offsetMillis"`
+	Type         string  `js:"type"`
+	Host         string  `js:"host"`
+	Detail       string  `js:"detail"`
+}
+
+// FailoverReport summarizes what a FailoverMonitor observed.
+type FailoverReport struct {
+	Events        []FailoverEvent `js:"events"`
+	OldPrimary    string          `js:"oldPrimary"`
+	NewPrimary    string          `js:"newPrimary"`
+	SteppedDownAt string          `js:"steppedDownAt"`
+	ElectedAt     string          `js:"electedAt"`
+	// ElectionMillis is the time without a primary, from the step down to
+	// the election.
+	ElectionMillis float64 `js:"electionMillis"`
+	// UnavailableMillis is the total time writes failed, and
+	// UnavailableWindows the length of each window of failed writes.
+	UnavailableMillis  float64   `js:"unavailableMillis"`
+	UnavailableWindows []float64 `js:"unavailableWindows"`
+	FailedWrites       int64     `js:"failedWrites"`
+}
+
+// memberState is what one poll learned about a member.
+type memberState struct {
+	writablePrimary bool
+	electionID      string
+}
+
+// FailoverMonitor polls every member of a replica set for the current
+// primary and watches the writes made by the Client methods of every VU, to
+// build a timeline of a failover.
+type FailoverMonitor struct {
+	client *Client
+	key    string
+	hosts  []string
+
+	mu           sync.Mutex
+	conns        map[string]*mongo.Client
+	started      time.Time
+	polled       bool
+	primary      string
+	electionID   string
+	events       []FailoverEvent
+	oldPrimary   string
+	newPrimary   string
+	steppedDown  time.Time
+	elected      time.Time
+	failingSince time.Time
+	windows      []time.Duration
+	failedWrites int64
+	stopped      bool
+	releaseErr   error
+
+	loop *sampleLoop
+}
+
+// StartFailoverMonitor starts watching for failovers. Options: interval, how
+// often each member is polled in ms (default 500), and hosts, the members to
+// poll (defaults to every member reported by the deployment). Start it
+// before stepping down the primary, from the scenario that drives the
+// failover.
+func (c *Client) StartFailoverMonitor(opts map[string]any) (*FailoverMonitor, error) {
+	if err := checkAllowedOptions(opts, "interval", "hosts"); err != nil {
+		return nil, err
+	}
+	interval, ok, err := durationOption(opts, "interval")
+	if err != nil {
+		return nil, err
+	}
+	if !ok || interval <= 0 {
+		interval = defaultFailoverPoll
+	}
+	hosts, _, err := stringsOption(opts, "hosts")
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		ctx, cancel := c.getContext()
+		hosts, err = c.knownHosts(ctx)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m := newFailoverMonitor(c, hosts)
+	failoverMonitors.Lock()
+	if failoverMonitors.byDeployment[m.key] == nil {
+		failoverMonitors.byDeployment[m.key] = map[*FailoverMonitor]struct{}{}
+	}
+	failoverMonitors.byDeployment[m.key][m] = struct{}{}
+	failoverMonitors.Unlock()
+
+	m.loop = newSampleLoop(c)
+	m.loop.start(interval, func() { _ = m.Sample() }, m.release)
+	return m, nil
+}
+
+func newFailoverMonitor(c *Client, hosts []string) *FailoverMonitor {
+	return &FailoverMonitor{
+		client:  c,
+		key:     c.deploymentKey(),
+		hosts:   hosts,
+		conns:   map[string]*mongo.Client{},
+		started: time.Now(),
+	}
+}
+
+// Sample polls every member immediately. Members that do not answer are
+// treated as not primary.
+func (m *FailoverMonitor) Sample() error {
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return errSamplerStopped
+	}
+	m.mu.Unlock()
+
+	states := map[string]memberState{}
+	var errs []error
+	for _, host := range m.hosts {
+		state, err := m.poll(host)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", host, err))
+			continue
+		}
+		states[host] = state
+	}
+
+	m.mu.Lock()
+	m.recordPrimary(time.Now(), states)
+	m.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+func (m *FailoverMonitor) poll(host string) (memberState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.client.defaultTimeout)
+	defer cancel()
+
+	m.mu.Lock()
+	conn, ok := m.conns[host]
+	m.mu.Unlock()
+	if !ok {
+		var err error
+		if conn, err = m.client.connectToHost(ctx, host); err != nil {
+			return memberState{}, err
+		}
+		m.mu.Lock()
+		m.conns[host] = conn
+		m.mu.Unlock()
+	}
+
+	var hello struct {
+		IsWritablePrimary bool               `bson:"isWritablePrimary"`
+		ElectionID        primitive.ObjectID `bson:"electionId"`
+	}
+	if err := conn.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		log.Printf(errPollingMember, host, err)
+		return memberState{}, err
+	}
+	state := memberState{writablePrimary: hello.IsWritablePrimary}
+	if !hello.ElectionID.IsZero() {
+		state.electionID = hello.ElectionID.Hex()
+	}
+	return state, nil
+}
+
+// recordPrimary updates the timeline from the member states of one poll.
+func (m *FailoverMonitor) recordPrimary(now time.Time, states map[string]memberState) {
+	// During an election two members may briefly both claim to be primary;
+	// the one with the newer election id wins.
+	var primary, electionID string
+	for host, state := range states {
+		if state.writablePrimary && (primary == "" || state.electionID > electionID) {
+			primary, electionID = host, state.electionID
+		}
+	}
+
+	if !m.polled {
+		m.polled = true
+		if primary != "" {
+			m.addEventLocked(now, eventPrimaryObserved, primary, electionID)
+		}
+		m.primary, m.electionID = primary, electionID
+		return
+	}
+
+	if m.primary != "" && (primary != m.primary || electionID != m.electionID) {
+		m.oldPrimary = m.primary
+		m.steppedDown = now
+		m.elected = time.Time{}
+		m.addEventLocked(now, eventPrimarySteppedDown, m.primary, "")
+		m.primary, m.electionID = "", ""
+	}
+	if primary != "" && m.primary == "" {
+		m.newPrimary = primary
+		m.elected = now
+		m.primary, m.electionID = primary, electionID
+		detail := electionID
+		if !m.steppedDown.IsZero() {
+			detail = fmt.Sprintf("%s after %s without a primary", electionID, now.Sub(m.steppedDown).Round(time.Millisecond))
+		}
+		m.addEventLocked(now, eventPrimaryElected, primary, detail)
+	}
+}
+
+// observeWrite records the outcome of a write. Windows of failed writes open
+// with the first write that fails because the deployment is unavailable and
+// close with the first write issued after that which succeeds; the window is
+// returned when it closes.
+func (m *FailoverMonitor) observeWrite(start, end time.Time, err error) (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopped {
+		return 0, false
+	}
+	if isUnavailableError(err) {
+		m.failedWrites++
+		if m.failingSince.IsZero() {
+			m.failingSince = start
+			m.addEventLocked(start, eventWritesFailing, "", err.Error())
+		}
+		return 0, false
+	}
+	if err != nil || m.failingSince.IsZero() || start.Before(m.failingSince) {
+		return 0, false
+	}
+
+	window := end.Sub(m.failingSince)
+	m.windows = append(m.windows, window)
+	m.failingSince = time.Time{}
+	m.addEventLocked(end, eventWritesRecovered, "", fmt.Sprintf("writes failed for %s", window.Round(time.Millisecond)))
+	return window, true
+}
+
+func (m *FailoverMonitor) addEvent(t time.Time, kind, host, detail string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.addEventLocked(t, kind, host, detail)
+}
+
+func (m *FailoverMonitor) addEventLocked(t time.Time, kind, host, detail string) {
+	m.events = append(m.events, FailoverEvent{
+		Time:         t.UTC().Format(time.RFC3339Nano),
+		OffsetMillis: metrics.D(t.Sub(m.started)),
+		Type:         kind,
+		Host:         host,
+		Detail:       detail,
+	})
+}
+
+// Report returns the timeline observed so far. A window of failed writes
+// that is still open counts up to now.
+func (m *FailoverMonitor) Report() *FailoverReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := &FailoverReport{
+		Events:             append([]FailoverEvent{}, m.events...),
+		OldPrimary:         m.oldPrimary,
+		NewPrimary:         m.newPrimary,
+		UnavailableWindows: []float64{},
+		FailedWrites:       m.failedWrites,
+	}
+	if !m.steppedDown.IsZero() {
+		r.SteppedDownAt = m.steppedDown.UTC().Format(time.RFC3339Nano)
+		if !m.elected.IsZero() {
+			r.ElectedAt = m.elected.UTC().Format(time.RFC3339Nano)
+			r.ElectionMillis = metrics.D(m.elected.Sub(m.steppedDown))
+		}
+	}
+	windows := m.windows
+	if !m.failingSince.IsZero() {
+		windows = append(append([]time.Duration{}, windows...), time.Since(m.failingSince))
+	}
+	for _, window := range windows {
+		r.UnavailableWindows = append(r.UnavailableWindows, metrics.D(window))
+		r.UnavailableMillis += metrics.D(window)
+	}
+	return r
+}
+
+// Stop stops polling, closes the per-member connections and stops watching
+// writes. The report remains available.
+func (m *FailoverMonitor) Stop() error {
+	if m.loop != nil {
+		m.loop.stop()
+	} else {
+		m.release()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.releaseErr
+}
+
+// release stops watching writes and closes the per-member connections once
+// the loop is over.
+func (m *FailoverMonitor) release() {
+	failoverMonitors.Lock()
+	delete(failoverMonitors.byDeployment[m.key], m)
+	failoverMonitors.Unlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopped {
+		return
+	}
+	m.stopped = true
+
+	ctx, cancel := m.client.getContext()
+	defer cancel()
+
+	var errs []error
+	for host, conn := range m.conns {
+		if err := conn.Disconnect(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", host, err))
+		}
+	}
+	m.conns = nil
+	m.releaseErr = errors.Join(errs...)
+}
@@ -0,0 +1,175 @@
+package xk6_mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+func TestIsUnavailableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"not writable primary", mongo.CommandError{Code: 10107}, true},
+		{"stepped down", mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 189}}}, true},
+		{"network", mongo.CommandError{Labels: []string{"NetworkError"}}, true},
+		{"server selection", topology.ServerSelectionError{Wrapped: topology.ErrServerSelectionTimeout}, true},
+		{"shutdown", mongo.CommandError{Code: 91}, true},
+		{"timeout", context.DeadlineExceeded, false},
+		{"exceeded time limit", mongo.CommandError{Code: 262, Labels: []string{"RetryableWriteError"}}, false},
+		{"duplicate key", mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := isUnavailableError(tt.err); got != tt.want {
+			t.Errorf("%s: isUnavailableError = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFailoverValidation(t *testing.T) {
+	c := &Client{}
+	if err := c.StepDownPrimary(-1, nil); err != errSecondsNegative {
+		t.Errorf("Expected errSecondsNegative, got %v", err)
+	}
+	if err := c.StepDownPrimary(10, map[string]any{"wait": true}); err == nil {
+		t.Error("Expected error for an unknown option")
+	}
+	if err := c.Freeze("", 10); err != errHostEmpty {
+		t.Errorf("Expected errHostEmpty, got %v", err)
+	}
+	if err := c.Freeze("rs1:27017", -1); err != errSecondsNegative {
+		t.Errorf("Expected errSecondsNegative, got %v", err)
+	}
+}
+
+func TestFailoverMonitorElection(t *testing.T) {
+	m := newFailoverMonitor(&Client{}, []string{"a", "b", "c"})
+	t0 := m.started
+
+	m.recordPrimary(t0, map[string]memberState{
+		"a": {writablePrimary: true, electionID: "7fffffff0000000000000001"},
+		"b": {}, "c": {},
+	})
+	m.recordPrimary(t0.Add(time.Second), map[string]memberState{"b": {}, "c": {}})
+	// The old primary still claims to be primary with a stale election id.
+	m.recordPrimary(t0.Add(3*time.Second), map[string]memberState{
+		"a": {writablePrimary: true, electionID: "7fffffff0000000000000001"},
+		"b": {writablePrimary: true, electionID: "7fffffff0000000000000002"},
+	})
+
+	r := m.Report()
+	wantTypes := []string{eventPrimaryObserved, eventPrimarySteppedDown, eventPrimaryElected}
+	if len(r.Events) != len(wantTypes) {
+		t.Fatalf("Expected %d events, got %+v", len(wantTypes), r.Events)
+	}
+	for i, kind := range wantTypes {
+		if r.Events[i].Type != kind {
+			t.Errorf("Event %d = %s, want %s", i, r.Events[i].Type, kind)
+		}
+	}
+	if r.OldPrimary != "a" || r.NewPrimary != "b" {
+		t.Errorf("Expected a -> b, got %s -> %s", r.OldPrimary, r.NewPrimary)
+	}
+	if r.ElectionMillis != 2000 {
+		t.Errorf("ElectionMillis = %v, want 2000", r.ElectionMillis)
+	}
+	if r.Events[2].OffsetMillis != 3000 {
+		t.Errorf("OffsetMillis = %v, want 3000", r.Events[2].OffsetMillis)
+	}
+}
+
+func TestFailoverMonitorReelection(t *testing.T) {
+	m := newFailoverMonitor(&Client{}, []string{"a"})
+	t0 := m.started
+
+	m.recordPrimary(t0, map[string]memberState{"a": {writablePrimary: true, electionID: "01"}})
+	// Stepped down and re-elected between two polls.
+	m.recordPrimary(t0.Add(time.Second), map[string]memberState{"a": {writablePrimary: true, electionID: "02"}})
+
+	r := m.Report()
+	if len(r.Events) != 3 || r.Events[1].Type != eventPrimarySteppedDown || r.Events[2].Type != eventPrimaryElected {
+		t.Fatalf("Expected a step down and an election, got %+v", r.Events)
+	}
+	if r.OldPrimary != "a" || r.NewPrimary != "a" {
+		t.Errorf("Expected a -> a, got %s -> %s", r.OldPrimary, r.NewPrimary)
+	}
+}
+
+func TestFailoverMonitorWriteWindows(t *testing.T) {
+	m := newFailoverMonitor(&Client{}, nil)
+	t0 := m.started
+	unavailable := mongo.CommandError{Code: 10107}
+
+	at := func(ms int) time.Time { return t0.Add(time.Duration(ms) * time.Millisecond) }
+
+	if _, ok := m.observeWrite(at(0), at(5), nil); ok {
+		t.Error("A successful write without failures must not close a window")
+	}
+	m.observeWrite(at(100), at(110), unavailable)
+	m.observeWrite(at(200), at(210), unavailable)
+	m.observeWrite(at(300), at(310), mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}})
+	// Issued before the window opened: it does not prove writes recovered.
+	if _, ok := m.observeWrite(at(50), at(400), nil); ok {
+		t.Error("A write issued before the failures must not close the window")
+	}
+	window, ok := m.observeWrite(at(500), at(600), nil)
+	if !ok || window != 500*time.Millisecond {
+		t.Errorf("Expected a 500ms window, got %v (%v)", window, ok)
+	}
+
+	r := m.Report()
+	if r.FailedWrites != 2 {
+		t.Errorf("FailedWrites = %d, want 2", r.FailedWrites)
+	}
+	if r.UnavailableMillis != 500 || len(r.UnavailableWindows) != 1 {
+		t.Errorf("Expected one 500ms window, got %v %v", r.UnavailableMillis, r.UnavailableWindows)
+	}
+	if len(r.Events) != 2 || r.Events[0].Type != eventWritesFailing || r.Events[1].Type != eventWritesRecovered {
+		t.Errorf("Unexpected events %+v", r.Events)
+	}
+}
+
+func TestObserveWriteEmitsUnavailable(t *testing.T) {
+	client, samples := newMetricsTestClient(t)
+	client.clientOptions = options.Client().SetHosts([]string{"failover-test:27017"})
+
+	// Two monitors watch the same writes, e.g. one per scenario.
+	m, other := newFailoverMonitor(client, nil), newFailoverMonitor(client, nil)
+	failoverMonitors.Lock()
+	failoverMonitors.byDeployment[m.key] = map[*FailoverMonitor]struct{}{m: {}, other: {}}
+	failoverMonitors.Unlock()
+	defer func() { _ = other.Stop() }()
+
+	client.observeWrite(time.Now().Add(-time.Second), mongo.CommandError{Code: 91})
+	client.observeWrite(time.Now(), nil)
+
+	var found int
+	for _, s := range collectSamples(samples) {
+		if s.Metric.Name == "mongo_failover_unavailable_ms" {
+			found++
+			if s.Value < 1000 {
+				t.Errorf("Expected at least 1000ms, got %v", s.Value)
+			}
+		}
+	}
+	if found != 1 {
+		t.Errorf("Expected one mongo_failover_unavailable_ms sample, got %d", found)
+	}
+	if r := other.Report(); len(r.UnavailableWindows) != 1 {
+		t.Errorf("Expected every monitor to record the window, got %v", r.UnavailableWindows)
+	}
+
+	_ = m.Stop()
+	if monitors := activeFailoverMonitors(m.key); len(monitors) != 1 || monitors[0] != other {
+		t.Errorf("Expected only the stopped monitor to be unregistered, got %d", len(monitors))
+	}
+}
@@ -0,0 +1,215 @@
+package xk6_mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/grafana/sobek"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	errConfiguringFailPoint = "Error while configuring fail point: %v"
+
+	failPointOff = "off"
+)
+
+var (
+	errFailPointName     = errors.New("fail point name cannot be empty")
+	errFailPointCallback = errors.New("withFailPoint requires a callback")
+)
+
+// enabledFailPoints tracks the fail points enabled through the extension,
+// keyed by deployment, with the options of a client connected to it. Fail
+// points are server state rather than client state, so they are disabled
+// when the test ends, whichever VU enabled them.
+var enabledFailPoints = struct {
+	sync.Mutex
+	byDeployment map[string]*deploymentFailPoints
+}{byDeployment: map[string]*deploymentFailPoints{}}
+
+type deploymentFailPoints struct {
+	names         map[string]struct{}
+	clientOptions *options.ClientOptions
+}
+
+// disableTrackedFailPoints disables the tracked fail points of every
+// deployment, with a new connection since the clients of the VUs may be
+// disconnected.
+func disableTrackedFailPoints() {
+	enabledFailPoints.Lock()
+	var targets []*options.ClientOptions
+	for _, tracked := range enabledFailPoints.byDeployment {
+		if len(tracked.names) > 0 && tracked.clientOptions != nil {
+			targets = append(targets, tracked.clientOptions)
+		}
+	}
+	enabledFailPoints.Unlock()
+
+	for _, clientOptions := range targets {
+		opts := options.MergeClientOptions(clientOptions)
+		opts.ServerMonitor = nil
+		ctx, cancel := context.WithTimeout(context.Background(), defaultConnectionTimeout)
+		conn, err := mongo.Connect(ctx, opts)
+		cancel()
+		if err != nil {
+			log.Printf(errConfiguringFailPoint, err)
+			continue
+		}
+		c := &Client{client: conn, clientOptions: clientOptions, defaultTimeout: defaultConnectionTimeout}
+		if err := c.DisableFailPoints(); err != nil {
+			log.Printf(errConfiguringFailPoint, err)
+		}
+		_ = conn.Disconnect(context.Background())
+	}
+}
+
+// FailPoint runs configureFailPoint on the admin database of the primary.
+// The server must run with enableTestCommands. mode is "alwaysOn", "off",
+// {times: n}, {skip: n} or {activationProbability: p}; data configures the
+// fail point, e.g. for failCommand: {failCommands: ["insert"], errorCode: 91,
+// errorLabels: [...], closeConnection: true, blockConnection: true,
+// blockTimeMS: 500}.
+func (c *Client) FailPoint(name string, mode any, data map[string]any) error {
+	if name == "" {
+		return errFailPointName
+	}
+	parsedMode, err := parseFailPointMode(mode)
+	if err != nil {
+		return err
+	}
+
+	cmd := bson.D{{Key: "configureFailPoint", Value: name}, {Key: "mode", Value: parsedMode}}
+	if len(data) > 0 {
+		cmd = append(cmd, bson.E{Key: "data", Value: data})
+	}
+	if _, err := c.runCommand("admin", cmd); err != nil {
+		log.Printf(errConfiguringFailPoint, err)
+		return err
+	}
+
+	c.trackFailPoint(name, parsedMode != failPointOff)
+	log.Printf("Fail point %s set to %v", name, parsedMode)
+	return nil
+}
+
+// DisableFailPoint turns a fail point off.
+func (c *Client) DisableFailPoint(name string) error {
+	return c.FailPoint(name, failPointOff, nil)
+}
+
+// DisableFailPoints turns off every fail point enabled through the extension
+// on this deployment, by any VU. They are turned off when the test ends;
+// this turns them off earlier, e.g. from teardown().
+func (c *Client) DisableFailPoints() error {
+	var errs []error
+	for _, name := range c.trackedFailPoints() {
+		if err := c.DisableFailPoint(name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithFailPoint enables a fail point, calls fn and disables the fail point
+// again, even when fn throws. It returns fn's result.
+func (c *Client) WithFailPoint(name string, mode any, data map[string]any, fn sobek.Callable) (sobek.Value, error) {
+	if fn == nil {
+		return nil, errFailPointCallback
+	}
+	if err := c.FailPoint(name, mode, data); err != nil {
+		return nil, err
+	}
+
+	result, err := fn(sobek.Undefined())
+	if disableErr := c.DisableFailPoint(name); disableErr != nil && err == nil {
+		err = disableErr
+	}
+	return result, err
+}
+
+// parseFailPointMode validates a fail point mode.
+func parseFailPointMode(mode any) (any, error) {
+	switch m := mode.(type) {
+	case string:
+		if m == "alwaysOn" || m == failPointOff {
+			return m, nil
+		}
+		return nil, fmt.Errorf("fail point mode must be \"alwaysOn\", \"off\" or an object, got %q", m)
+	case map[string]any:
+		if len(m) != 1 {
+			return nil, errors.New("fail point mode must have exactly one of times, skip or activationProbability")
+		}
+		if p, ok, err := floatOption(m, "activationProbability"); ok || err != nil {
+			if err != nil {
+				return nil, err
+			}
+			if p < 0 || p > 1 {
+				return nil, fmt.Errorf("activationProbability must be between 0 and 1, got %v", p)
+			}
+			return bson.D{{Key: "activationProbability", Value: p}}, nil
+		}
+		for _, key := range []string{"times", "skip"} {
+			n, err := optionalNonNegativeInt(m, key)
+			if err != nil {
+				return nil, err
+			}
+			if n != nil {
+				return bson.D{{Key: key, Value: *n}}, nil
+			}
+		}
+		return nil, errors.New("fail point mode must have exactly one of times, skip or activationProbability")
+	}
+	return nil, fmt.Errorf("unsupported fail point mode %T", mode)
+}
+
+// deploymentKey identifies the deployment a client is connected to.
+func (c *Client) deploymentKey() string {
+	if c.clientOptions == nil {
+		return ""
+	}
+	hosts := append([]string(nil), c.clientOptions.Hosts...)
+	sort.Strings(hosts)
+	return strings.Join(hosts, ",")
+}
+
+func (c *Client) trackFailPoint(name string, enabled bool) {
+	enabledFailPoints.Lock()
+	defer enabledFailPoints.Unlock()
+
+	key := c.deploymentKey()
+	tracked, ok := enabledFailPoints.byDeployment[key]
+	if !ok {
+		tracked = &deploymentFailPoints{names: map[string]struct{}{}}
+		enabledFailPoints.byDeployment[key] = tracked
+	}
+	if enabled {
+		tracked.names[name] = struct{}{}
+		tracked.clientOptions = c.clientOptions
+	} else {
+		delete(tracked.names, name)
+	}
+}
+
+func (c *Client) trackedFailPoints() []string {
+	enabledFailPoints.Lock()
+	defer enabledFailPoints.Unlock()
+
+	tracked, ok := enabledFailPoints.byDeployment[c.deploymentKey()]
+	if !ok {
+		return []string{}
+	}
+	names := make([]string, 0, len(tracked.names))
+	for name := range tracked.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
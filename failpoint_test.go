@@ -0,0 +1,99 @@
+package xk6_mongo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestParseFailPointMode(t *testing.T) {
+	tests := []struct {
+		mode any
+		want any
+	}{
+		{"alwaysOn", "alwaysOn"},
+		{"off", "off"},
+		{map[string]any{"times": int64(2)}, bson.D{{Key: "times", Value: int64(2)}}},
+		{map[string]any{"skip": 3.0}, bson.D{{Key: "skip", Value: int64(3)}}},
+		{map[string]any{"activationProbability": 0.5}, bson.D{{Key: "activationProbability", Value: 0.5}}},
+	}
+	for _, tt := range tests {
+		got, err := parseFailPointMode(tt.mode)
+		if err != nil {
+			t.Errorf("parseFailPointMode(%v) failed: %v", tt.mode, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseFailPointMode(%v) = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+
+	invalid := []any{
+		"sometimes",
+		nil,
+		map[string]any{},
+		map[string]any{"times": -1},
+		map[string]any{"times": 1, "skip": 1},
+		map[string]any{"activationProbability": 2.0},
+		map[string]any{"count": 1},
+	}
+	for _, mode := range invalid {
+		if _, err := parseFailPointMode(mode); err == nil {
+			t.Errorf("Expected error for mode %v", mode)
+		}
+	}
+}
+
+func TestFailPointValidation(t *testing.T) {
+	c := &Client{}
+	if err := c.FailPoint("", "alwaysOn", nil); err != errFailPointName {
+		t.Errorf("Expected errFailPointName, got %v", err)
+	}
+	if err := c.FailPoint("failCommand", "sometimes", nil); err == nil {
+		t.Error("Expected error for an invalid mode")
+	}
+	if _, err := c.WithFailPoint("failCommand", "alwaysOn", nil, nil); err != errFailPointCallback {
+		t.Errorf("Expected errFailPointCallback, got %v", err)
+	}
+}
+
+func TestTrackFailPoints(t *testing.T) {
+	a := &Client{clientOptions: options.Client().SetHosts([]string{"b:27017", "a:27017"})}
+	b := &Client{clientOptions: options.Client().SetHosts([]string{"a:27017", "b:27017"})}
+	other := &Client{clientOptions: options.Client().SetHosts([]string{"c:27017"})}
+
+	a.trackFailPoint("failCommand", true)
+	a.trackFailPoint("hangBeforeWrite", true)
+	defer func() {
+		a.trackFailPoint("failCommand", false)
+		a.trackFailPoint("hangBeforeWrite", false)
+	}()
+
+	if got := b.trackedFailPoints(); !reflect.DeepEqual(got, []string{"failCommand", "hangBeforeWrite"}) {
+		t.Errorf("trackedFailPoints = %v, want both fail points", got)
+	}
+	if got := other.trackedFailPoints(); len(got) != 0 {
+		t.Errorf("Expected no fail points on another deployment, got %v", got)
+	}
+
+	b.trackFailPoint("failCommand", false)
+	if got := a.trackedFailPoints(); !reflect.DeepEqual(got, []string{"hangBeforeWrite"}) {
+		t.Errorf("trackedFailPoints = %v, want [hangBeforeWrite]", got)
+	}
+}
+
+func TestDisableTrackedFailPointsUnreachable(t *testing.T) {
+	c := &Client{clientOptions: options.Client().SetHosts([]string{"127.0.0.1:1"}).
+		SetServerSelectionTimeout(100 * time.Millisecond)}
+	c.trackFailPoint("failCommand", true)
+	defer c.trackFailPoint("failCommand", false)
+
+	// Fail points that cannot be disabled stay tracked for a later attempt.
+	disableTrackedFailPoints()
+	if got := c.trackedFailPoints(); !reflect.DeepEqual(got, []string{"failCommand"}) {
+		t.Errorf("trackedFailPoints = %v, want [failCommand]", got)
+	}
+}
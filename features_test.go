@@ -4,6 +4,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/grafana/sobek"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -38,7 +39,7 @@ func TestAllFeatures(t *testing.T) {
 			"active": true,
 		}
 
-		err := client.Insert(db, col, doc)
+		err := client.Insert(db, col, doc, nil)
 		if err != nil {
 			t.Fatalf("Insert failed: %v", err)
 		}
@@ -63,10 +64,13 @@ func TestAllFeatures(t *testing.T) {
 			bson.M{"_id": "test-4", "name": "Diana", "age": 28, "active": true},
 		}
 
-		err := client.InsertMany(db, col, docs)
+		result, err := client.InsertMany(db, col, docs, nil)
 		if err != nil {
 			t.Fatalf("InsertMany failed: %v", err)
 		}
+		if result.InsertedCount != 3 {
+			t.Errorf("Expected 3 inserted documents, got %d", result.InsertedCount)
+		}
 		t.Log("✅ InsertMany successful")
 	})
 
@@ -115,7 +119,7 @@ func TestAllFeatures(t *testing.T) {
 	})
 
 	t.Run("UpdateOne_Operation", func(t *testing.T) {
-		err := client.UpdateOne(db, col, bson.M{"_id": "test-1"}, bson.M{"age": 31, "updated": true})
+		err := client.UpdateOne(db, col, bson.M{"_id": "test-1"}, bson.M{"age": 31, "updated": true}, nil)
 		if err != nil {
 			t.Fatalf("UpdateOne failed: %v", err)
 		}
@@ -128,7 +132,7 @@ func TestAllFeatures(t *testing.T) {
 	})
 
 	t.Run("UpdateMany_Operation", func(t *testing.T) {
-		err := client.UpdateMany(db, col, bson.M{"active": true}, bson.M{"verified": true})
+		err := client.UpdateMany(db, col, bson.M{"active": true}, bson.M{"verified": true}, nil)
 		if err != nil {
 			t.Fatalf("UpdateMany failed: %v", err)
 		}
@@ -140,8 +144,28 @@ func TestAllFeatures(t *testing.T) {
 		t.Logf("✅ UpdateMany successful: updated %d documents", len(results))
 	})
 
+	t.Run("UpdateOne_WithArrayFilters", func(t *testing.T) {
+		_ = client.Insert(db, col, bson.M{"_id": "grades-1", "grades": []int{80, 90, 95}}, nil)
+		err := client.UpdateOne(db, col,
+			bson.M{"_id": "grades-1"},
+			bson.M{"$set": bson.M{"grades.$[elem]": 100}},
+			map[string]any{"arrayFilters": []any{bson.M{"elem": bson.M{"$gte": 90}}}},
+		)
+		if err != nil {
+			t.Fatalf("UpdateOne with arrayFilters failed: %v", err)
+		}
+
+		doc, _ := client.FindOne(db, col, bson.M{"_id": "grades-1"})
+		grades, _ := doc["grades"].(bson.A)
+		if len(grades) != 3 || grades[0] != int32(80) || grades[1] != int32(100) || grades[2] != int32(100) {
+			t.Errorf("Unexpected grades after arrayFilters update: %v", doc["grades"])
+		}
+		_ = client.DeleteOne(db, col, bson.M{"_id": "grades-1"}, nil)
+		t.Log("✅ UpdateOne with arrayFilters successful")
+	})
+
 	t.Run("Upsert_Operation", func(t *testing.T) {
-		err := client.Upsert(db, col, bson.M{"_id": "test-5"}, bson.M{"name": "Eve", "age": 29})
+		err := client.Upsert(db, col, bson.M{"_id": "test-5"}, bson.M{"name": "Eve", "age": 29}, nil)
 		if err != nil {
 			t.Fatalf("Upsert failed: %v", err)
 		}
@@ -158,6 +182,7 @@ func TestAllFeatures(t *testing.T) {
 			db, col,
 			bson.M{"_id": "test-5"},
 			bson.M{"$set": bson.M{"age": 30}},
+			nil,
 		)
 		if err != nil {
 			t.Fatalf("FindOneAndUpdate failed: %v", err)
@@ -168,8 +193,91 @@ func TestAllFeatures(t *testing.T) {
 		t.Log("✅ FindOneAndUpdate successful")
 	})
 
+	t.Run("FindOneAndUpdate_WithOptions", func(t *testing.T) {
+		before, err := client.FindOneAndUpdate(
+			db, col,
+			bson.M{"_id": "test-5"},
+			bson.M{"$inc": bson.M{"age": 1}},
+			map[string]any{"returnDocument": "before", "projection": bson.M{"age": 1}},
+		)
+		if err != nil {
+			t.Fatalf("FindOneAndUpdate failed: %v", err)
+		}
+		if before["age"].(int32) != 30 {
+			t.Errorf("Expected pre-image age 30, got %v", before["age"])
+		}
+		if _, exists := before["name"]; exists {
+			t.Error("Projection failed: name field should not exist")
+		}
+
+		missing, err := client.FindOneAndUpdate(db, col, bson.M{"_id": "missing"}, bson.M{"age": 1}, nil)
+		if err != nil || missing != nil {
+			t.Errorf("Expected nil result for unmatched filter, got %v, %v", missing, err)
+		}
+		t.Log("✅ FindOneAndUpdate with options successful")
+	})
+
+	t.Run("FindOneAndReplace_Operation", func(t *testing.T) {
+		result, err := client.FindOneAndReplace(
+			db, col,
+			bson.M{"_id": "test-5"},
+			bson.M{"name": "Eve", "age": 40, "replaced": true},
+			nil,
+		)
+		if err != nil {
+			t.Fatalf("FindOneAndReplace failed: %v", err)
+		}
+		if result["replaced"] != true {
+			t.Errorf("Expected replaced document, got %v", result)
+		}
+		t.Log("✅ FindOneAndReplace successful")
+	})
+
+	t.Run("ReplaceOne_Operation", func(t *testing.T) {
+		result, err := client.ReplaceOne(db, col, bson.M{"_id": "test-5"}, bson.M{"name": "Eve", "age": 41}, nil)
+		if err != nil {
+			t.Fatalf("ReplaceOne failed: %v", err)
+		}
+		if result.MatchedCount != 1 || result.ModifiedCount != 1 {
+			t.Errorf("Expected 1 matched and modified, got %+v", result)
+		}
+
+		doc, err := client.FindOne(db, col, bson.M{"_id": "test-5"})
+		if err != nil {
+			t.Fatalf("FindOne failed: %v", err)
+		}
+		if _, exists := doc["replaced"]; exists {
+			t.Error("ReplaceOne kept a field missing from the replacement")
+		}
+
+		result, err = client.ReplaceOne(db, col, bson.M{"_id": "test-6"}, bson.M{"name": "Frank"}, map[string]any{"upsert": true})
+		if err != nil {
+			t.Fatalf("ReplaceOne upsert failed: %v", err)
+		}
+		if result.UpsertedCount != 1 || result.UpsertedID != "test-6" {
+			t.Errorf("Expected upsert of test-6, got %+v", result)
+		}
+		_ = client.DeleteOne(db, col, bson.M{"_id": "test-6"}, nil)
+		t.Log("✅ ReplaceOne successful")
+	})
+
+	t.Run("FindOneAndDelete_Operation", func(t *testing.T) {
+		_ = client.Insert(db, col, bson.M{"_id": "job-1", "queue": "jobs", "priority": 1}, nil)
+		_ = client.Insert(db, col, bson.M{"_id": "job-2", "queue": "jobs", "priority": 5}, nil)
+
+		result, err := client.FindOneAndDelete(db, col, bson.M{"queue": "jobs"}, map[string]any{"sort": bson.M{"priority": -1}})
+		if err != nil {
+			t.Fatalf("FindOneAndDelete failed: %v", err)
+		}
+		if result["_id"] != "job-2" {
+			t.Errorf("Expected highest priority job-2, got %v", result["_id"])
+		}
+		_ = client.DeleteMany(db, col, bson.M{"queue": "jobs"}, nil)
+		t.Log("✅ FindOneAndDelete successful")
+	})
+
 	t.Run("CountDocuments_Operation", func(t *testing.T) {
-		count, err := client.CountDocuments(db, col, bson.M{"active": true})
+		count, err := client.CountDocuments(db, col, bson.M{"active": true}, nil)
 		if err != nil {
 			t.Fatalf("CountDocuments failed: %v", err)
 		}
@@ -179,6 +287,29 @@ func TestAllFeatures(t *testing.T) {
 		t.Logf("✅ CountDocuments successful: %d documents", count)
 	})
 
+	t.Run("CountDocuments_WithOptions", func(t *testing.T) {
+		count, err := client.CountDocuments(db, col, bson.M{"active": true}, map[string]any{"skip": int64(1), "limit": int64(1)})
+		if err != nil {
+			t.Fatalf("CountDocuments with options failed: %v", err)
+		}
+		if count != 1 {
+			t.Errorf("Expected count 1 with skip/limit, got %d", count)
+		}
+		t.Log("✅ CountDocuments with options successful")
+	})
+
+	t.Run("EstimatedDocumentCount_Operation", func(t *testing.T) {
+		estimated, err := client.EstimatedDocumentCount(db, col, map[string]any{"maxTime": int64(1000)})
+		if err != nil {
+			t.Fatalf("EstimatedDocumentCount failed: %v", err)
+		}
+		exact, _ := client.CountDocuments(db, col, nil, nil)
+		if estimated != exact {
+			t.Errorf("Expected estimated count %d, got %d", exact, estimated)
+		}
+		t.Logf("✅ EstimatedDocumentCount successful: %d documents", estimated)
+	})
+
 	t.Run("Distinct_Operation", func(t *testing.T) {
 		values, err := client.Distinct(db, col, "active", bson.M{})
 		if err != nil {
@@ -200,7 +331,7 @@ func TestAllFeatures(t *testing.T) {
 			}},
 		}
 
-		results, err := client.Aggregate(db, col, pipeline)
+		results, err := client.Aggregate(db, col, pipeline, nil)
 		if err != nil {
 			t.Fatalf("Aggregate failed: %v", err)
 		}
@@ -213,6 +344,87 @@ func TestAllFeatures(t *testing.T) {
 		t.Logf("✅ Aggregate successful: count=%v, avgAge=%v", results[0]["count"], results[0]["avgAge"])
 	})
 
+	t.Run("Aggregate_WithOptionsAndOut", func(t *testing.T) {
+		out := col + "_active"
+		pipeline := []any{
+			bson.M{"$match": bson.M{"active": true}},
+			bson.M{"$out": out},
+		}
+
+		target, err := client.AggregateWrite(db, col, pipeline, map[string]any{
+			"allowDiskUse": true,
+			"comment":      "features-test",
+			"readConcern":  "local",
+		})
+		if err != nil {
+			t.Fatalf("AggregateWrite failed: %v", err)
+		}
+		if target.Stage != "$out" || target.Database != db || target.Collection != out {
+			t.Errorf("Unexpected write target %+v", *target)
+		}
+		defer client.DropCollection(db, out)
+
+		count, err := client.CountDocuments(db, out, nil, nil)
+		if err != nil {
+			t.Fatalf("CountDocuments failed: %v", err)
+		}
+		if count != 3 {
+			t.Errorf("Expected 3 documents written by $out, got %d", count)
+		}
+
+		docs, err := client.AggregateDatabase(db, []any{
+			bson.M{"$documents": []any{bson.M{"n": 1}, bson.M{"n": 2}}},
+		}, nil)
+		if err != nil {
+			t.Fatalf("AggregateDatabase failed: %v", err)
+		}
+		if len(docs) != 2 {
+			t.Errorf("Expected 2 documents from $documents, got %d", len(docs))
+		}
+		t.Logf("✅ Aggregate with $out successful: %d documents", count)
+	})
+
+	t.Run("Explain_Find", func(t *testing.T) {
+		result, err := client.Explain(db, col, sobek.New().ToValue(map[string]any{
+			"find": map[string]any{"filter": bson.M{"active": true}},
+		}), "executionStats")
+		if err != nil {
+			t.Fatalf("Explain failed: %v", err)
+		}
+		if len(result.Summary.Stages) == 0 || result.Summary.Stages[0] != "COLLSCAN" {
+			t.Errorf("Expected a COLLSCAN on an unindexed field, got %v", result.Summary.Stages)
+		}
+		if result.Summary.NReturned != 3 {
+			t.Errorf("Expected nReturned 3, got %d", result.Summary.NReturned)
+		}
+		t.Logf("✅ Explain successful: stages=%v docsExamined=%d", result.Summary.Stages, result.Summary.DocsExamined)
+	})
+
+	t.Run("StatsSnapshot_Diff", func(t *testing.T) {
+		before, err := client.StatsSnapshot(db, []string{col})
+		if err != nil {
+			t.Fatalf("StatsSnapshot failed: %v", err)
+		}
+		if err := client.Insert(db, col, bson.M{"name": "Stats", "age": 40}, nil); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+		defer client.DeleteOne(db, col, bson.M{"name": "Stats"}, nil)
+		after, err := client.StatsSnapshot(db, []string{col})
+		if err != nil {
+			t.Fatalf("StatsSnapshot failed: %v", err)
+		}
+
+		diff, err := client.DiffStats(before, after)
+		if err != nil {
+			t.Fatalf("DiffStats failed: %v", err)
+		}
+		stats := diff["collections"].(bson.M)[col].(bson.M)
+		if stats["count"] != int64(1) {
+			t.Errorf("Expected 1 new document, got %v", stats["count"])
+		}
+		t.Logf("✅ Stats diff successful: %v", stats)
+	})
+
 	t.Run("BulkWrite_Operation", func(t *testing.T) {
 		operations := []mongo.WriteModel{
 			mongo.NewInsertOneModel().SetDocument(bson.M{"_id": "bulk-1", "name": "Frank"}),
@@ -221,7 +433,7 @@ func TestAllFeatures(t *testing.T) {
 			mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": "test-2"}),
 		}
 
-		inserted, modified, err := client.BulkWrite(db, col, operations)
+		inserted, modified, err := client.BulkWrite(db, col, operations, nil)
 		if err != nil {
 			t.Fatalf("BulkWrite failed: %v", err)
 		}
@@ -235,12 +447,12 @@ func TestAllFeatures(t *testing.T) {
 	})
 
 	t.Run("DeleteOne_Operation", func(t *testing.T) {
-		err := client.DeleteOne(db, col, bson.M{"_id": "test-3"})
+		err := client.DeleteOne(db, col, bson.M{"_id": "test-3"}, nil)
 		if err != nil {
 			t.Fatalf("DeleteOne failed: %v", err)
 		}
 
-		count, _ := client.CountDocuments(db, col, bson.M{"_id": "test-3"})
+		count, _ := client.CountDocuments(db, col, bson.M{"_id": "test-3"}, nil)
 		if count != 0 {
 			t.Error("DeleteOne did not delete document")
 		}
@@ -248,12 +460,12 @@ func TestAllFeatures(t *testing.T) {
 	})
 
 	t.Run("DeleteMany_Operation", func(t *testing.T) {
-		err := client.DeleteMany(db, col, bson.M{"active": true})
+		err := client.DeleteMany(db, col, bson.M{"active": true}, nil)
 		if err != nil {
 			t.Fatalf("DeleteMany failed: %v", err)
 		}
 
-		count, _ := client.CountDocuments(db, col, bson.M{"active": true})
+		count, _ := client.CountDocuments(db, col, bson.M{"active": true}, nil)
 		if count != 0 {
 			t.Errorf("Expected 0 active documents after DeleteMany, got %d", count)
 		}
@@ -270,7 +482,7 @@ func TestAllFeatures(t *testing.T) {
 
 	t.Run("CreateIndex_Operation", func(t *testing.T) {
 		// Insert a document so the collection exists
-		_ = client.Insert(db, col, bson.M{"_id": "idx-test-1", "name": "IndexTest", "email": "idx@test.com"})
+		_ = client.Insert(db, col, bson.M{"_id": "idx-test-1", "name": "IndexTest", "email": "idx@test.com"}, nil)
 
 		name, err := client.CreateIndex(db, col, bson.M{"name": 1}, nil)
 		if err != nil {
@@ -346,7 +558,7 @@ func TestAllFeatures(t *testing.T) {
 	t.Run("DropDatabase_Operation", func(t *testing.T) {
 		// Create a temporary database to drop
 		tempDB := "featurestest_temp"
-		_ = client.Insert(tempDB, "tempcol", bson.M{"_id": "temp-1"})
+		_ = client.Insert(tempDB, "tempcol", bson.M{"_id": "temp-1"}, nil)
 
 		err := client.DropDatabase(tempDB)
 		if err != nil {
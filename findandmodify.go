@@ -0,0 +1,253 @@
+package xk6_mongo
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FindOneAndUpdate atomically updates the first document matching filter and
+// returns it, or null when nothing matched. Options: sort, projection,
+// upsert, returnDocument ("before" or "after", default "after"),
+// arrayFilters, hint, collation, let, comment, maxTime and
+// bypassDocumentValidation.
+func (c *Client) FindOneAndUpdate(database string, collection string, filter any, update any, opts map[string]any) (bson.M, error) {
+	if filter == nil {
+		return nil, errFilterNil
+	}
+
+	parsed, err := parseCommandOptions(opts, optArrayFilters, optBypassDocumentValidation, optCollation,
+		optComment, optHint, optLet, optMaxTime, optProjection, optReturnDocument, optSort, optUpsert)
+	if err != nil {
+		return nil, err
+	}
+
+	col, err := c.getCollection(database, collection)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	updateDoc, err := prepareUpdateDocument(update)
+	if err != nil {
+		log.Printf(errPreparingUpdateDoc, err)
+		return nil, err
+	}
+
+	var result *mongo.SingleResult
+	err = c.do("findOneAndUpdate", updateKind(filter, updateDoc), func(ctx context.Context) error {
+		result = col.FindOneAndUpdate(ctx, filter, updateDoc, parsed.findOneAndUpdate())
+		if err := result.Err(); !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf(errFindingAndUpdating, err)
+		return nil, err
+	}
+	return decodeSingleResult(result, errFindingAndUpdating)
+}
+
+// FindOneAndReplace atomically replaces the first document matching filter
+// and returns it, or null when nothing matched. It accepts the same options
+// as FindOneAndUpdate except arrayFilters.
+func (c *Client) FindOneAndReplace(database string, collection string, filter any, replacement any, opts map[string]any) (bson.M, error) {
+	if filter == nil {
+		return nil, errFilterNil
+	}
+	if err := validateReplacementDocument(replacement); err != nil {
+		return nil, err
+	}
+
+	parsed, err := parseCommandOptions(opts, optBypassDocumentValidation, optCollation, optComment,
+		optHint, optLet, optMaxTime, optProjection, optReturnDocument, optSort, optUpsert)
+	if err != nil {
+		return nil, err
+	}
+
+	col, err := c.getCollection(database, collection)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	var result *mongo.SingleResult
+	err = c.do("findOneAndReplace", updateKind(filter, replacement), func(ctx context.Context) error {
+		result = col.FindOneAndReplace(ctx, filter, replacement, parsed.findOneAndReplace())
+		if err := result.Err(); !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf(errFindingAndReplacing, err)
+		return nil, err
+	}
+	return decodeSingleResult(result, errFindingAndReplacing)
+}
+
+// FindOneAndDelete atomically deletes the first document matching filter and
+// returns it, or null when nothing matched. Options: sort, projection, hint,
+// collation, let, comment and maxTime.
+func (c *Client) FindOneAndDelete(database string, collection string, filter any, opts map[string]any) (bson.M, error) {
+	if filter == nil {
+		return nil, errFilterNil
+	}
+
+	parsed, err := parseCommandOptions(opts, optCollation, optComment, optHint, optLet, optMaxTime,
+		optProjection, optSort)
+	if err != nil {
+		return nil, err
+	}
+
+	col, err := c.getCollection(database, collection)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	var result *mongo.SingleResult
+	err = c.do("findOneAndDelete", deleteOneKind(filter), func(ctx context.Context) error {
+		result = col.FindOneAndDelete(ctx, filter, parsed.findOneAndDelete())
+		if err := result.Err(); !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf(errFindingAndDeleting, err)
+		return nil, err
+	}
+	return decodeSingleResult(result, errFindingAndDeleting)
+}
+
+// decodeSingleResult decodes a find-and-modify result, mapping "no document
+// matched" to a nil result so scripts can poll queues without exceptions.
+func decodeSingleResult(result *mongo.SingleResult, errFormat string) (bson.M, error) {
+	var out bson.M
+	if err := result.Decode(&out); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		log.Printf(errFormat, err)
+		return nil, err
+	}
+	return out, nil
+}
+
+// validateReplacementDocument rejects replacements containing update
+// operators, which the server would otherwise reject with a less helpful
+// message.
+func validateReplacementDocument(doc any) error {
+	if doc == nil {
+		return errDocumentNil
+	}
+	if isPipelineUpdate(doc) || updateDocumentHasOperator(doc) {
+		return errReplacementOp
+	}
+	return nil
+}
+
+func (o *commandOptions) findOneAndUpdate() *options.FindOneAndUpdateOptions {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if o.arrayFilters != nil {
+		opts.SetArrayFilters(*o.arrayFilters)
+	}
+	if o.bypassDocumentValidation != nil {
+		opts.SetBypassDocumentValidation(*o.bypassDocumentValidation)
+	}
+	if o.collation != nil {
+		opts.SetCollation(o.collation)
+	}
+	if o.comment != nil {
+		opts.SetComment(o.comment)
+	}
+	if o.hint != nil {
+		opts.SetHint(o.hint)
+	}
+	if o.let != nil {
+		opts.SetLet(o.let)
+	}
+	if o.maxTime != nil {
+		opts.SetMaxTime(*o.maxTime)
+	}
+	if o.projection != nil {
+		opts.SetProjection(o.projection)
+	}
+	if o.returnDocument != nil {
+		opts.SetReturnDocument(*o.returnDocument)
+	}
+	if o.sort != nil {
+		opts.SetSort(o.sort)
+	}
+	if o.upsert != nil {
+		opts.SetUpsert(*o.upsert)
+	}
+	return opts
+}
+
+func (o *commandOptions) findOneAndReplace() *options.FindOneAndReplaceOptions {
+	opts := options.FindOneAndReplace().SetReturnDocument(options.After)
+	if o.bypassDocumentValidation != nil {
+		opts.SetBypassDocumentValidation(*o.bypassDocumentValidation)
+	}
+	if o.collation != nil {
+		opts.SetCollation(o.collation)
+	}
+	if o.comment != nil {
+		opts.SetComment(o.comment)
+	}
+	if o.hint != nil {
+		opts.SetHint(o.hint)
+	}
+	if o.let != nil {
+		opts.SetLet(o.let)
+	}
+	if o.maxTime != nil {
+		opts.SetMaxTime(*o.maxTime)
+	}
+	if o.projection != nil {
+		opts.SetProjection(o.projection)
+	}
+	if o.returnDocument != nil {
+		opts.SetReturnDocument(*o.returnDocument)
+	}
+	if o.sort != nil {
+		opts.SetSort(o.sort)
+	}
+	if o.upsert != nil {
+		opts.SetUpsert(*o.upsert)
+	}
+	return opts
+}
+
+func (o *commandOptions) findOneAndDelete() *options.FindOneAndDeleteOptions {
+	opts := options.FindOneAndDelete()
+	if o.collation != nil {
+		opts.SetCollation(o.collation)
+	}
+	if o.comment != nil {
+		opts.SetComment(o.comment)
+	}
+	if o.hint != nil {
+		opts.SetHint(o.hint)
+	}
+	if o.let != nil {
+		opts.SetLet(o.let)
+	}
+	if o.maxTime != nil {
+		opts.SetMaxTime(*o.maxTime)
+	}
+	if o.projection != nil {
+		opts.SetProjection(o.projection)
+	}
+	if o.sort != nil {
+		opts.SetSort(o.sort)
+	}
+	return opts
+}
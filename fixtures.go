@@ -0,0 +1,551 @@
+package xk6_mongo
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.k6.io/k6/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	errImportingFile = "Error while importing %s: %v"
+	errExportingFile = "Error while exporting %s: %v"
+
+	defaultImportBatchSize = 1000
+)
+
+// File formats, named after the mongoimport and mongoexport modes they are
+// compatible with.
+const (
+	formatJSONL      = "jsonl"
+	formatEJSONArray = "ejson-array"
+	formatCSV        = "csv"
+)
+
+var (
+	errPathEmpty       = errors.New("path cannot be empty")
+	errCSVFieldsNeeded = errors.New("option fields is required to export csv")
+)
+
+// ImportResult reports the outcome of an ImportFile call.
+type ImportResult struct {
+	// Read is the number of documents read from the file.
+	Read           int64   `js:"read"`
+	Inserted       int64   `js:"inserted"`
+	Duplicates     int64   `js:"duplicates"`
+	WriteErrors    int64   `js:"writeErrors"`
+	Batches        int64   `js:"batches"`
+	DurationMillis float64 `js:"durationMillis"`
+}
+
+// ExportResult reports the outcome of an ExportFile call.
+type ExportResult struct {
+	Exported       int64   `js:"exported"`
+	DurationMillis float64 `js:"durationMillis"`
+}
+
+// ImportFile inserts the documents of a local file in batches, like
+// mongoimport. Options: format ("jsonl" for one Extended JSON document per
+// line, "ejson-array" for a JSON array of documents as written by
+// mongoexport --jsonArray, or "csv"; by default from the file extension),
+// batchSize (default 1000), the insertMany options ordered,
+// bypassDocumentValidation and ignoreDuplicates and, for csv, fields (the
+// column names when the file has no header line), fieldTypes (a type per
+// column: auto, string, int, long, double, decimal, bool, date, objectId or
+// skip; default auto) and ignoreBlanks. Dotted column names create nested
+// documents.
+func (c *Client) ImportFile(database string, collection string, path string, opts map[string]any) (*ImportResult, error) {
+	if path == "" {
+		return nil, errPathEmpty
+	}
+	parsed, err := parseCommandOptions(opts, "format", optBatchSize, "fields", "fieldTypes", "ignoreBlanks",
+		optBypassDocumentValidation, optIgnoreDuplicates, optOrdered)
+	if err != nil {
+		return nil, err
+	}
+	format, err := fileFormat(opts, path)
+	if err != nil {
+		return nil, err
+	}
+	batchSize := defaultImportBatchSize
+	if parsed.batchSize != nil {
+		batchSize = int(*parsed.batchSize)
+	}
+
+	col, err := c.getCollection(database, collection)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var next func() (bson.D, error)
+	switch format {
+	case formatJSONL, formatEJSONArray:
+		next, err = extJSONReader(bufio.NewReader(f), format == formatEJSONArray)
+	case formatCSV:
+		next, err = csvReader(f, opts)
+	}
+	if err != nil {
+		log.Printf(errImportingFile, path, err)
+		return nil, err
+	}
+
+	start := time.Now()
+	result := &ImportResult{}
+	docs := make([]any, 0, batchSize)
+	flush := func() error {
+		if len(docs) == 0 {
+			return nil
+		}
+		inserted, err := c.insertMany("importFile", col, docs, parsed)
+		if err != nil {
+			return err
+		}
+		result.Inserted += inserted.InsertedCount
+		result.Duplicates += inserted.DuplicateCount
+		result.WriteErrors += int64(len(inserted.WriteErrors))
+		result.Batches++
+		c.recordWrite("importFile", database, collection, false, int(inserted.InsertedCount))
+		docs = docs[:0]
+		return nil
+	}
+
+	for {
+		doc, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err == nil {
+			result.Read++
+			docs = append(docs, doc)
+			if len(docs) == batchSize {
+				err = flush()
+			}
+		}
+		if err != nil {
+			log.Printf(errImportingFile, path, err)
+			return nil, fmt.Errorf("import stopped after %d documents: %w", result.Inserted, err)
+		}
+	}
+	if err := flush(); err != nil {
+		log.Printf(errImportingFile, path, err)
+		return nil, fmt.Errorf("import stopped after %d documents: %w", result.Inserted, err)
+	}
+
+	result.DurationMillis = metrics.D(time.Since(start))
+	log.Printf("Imported %d documents from %s into %s.%s", result.Inserted, path, database, collection)
+	return result, nil
+}
+
+// ExportFile writes the documents of a collection to a local file, like
+// mongoexport. Options: format (see ImportFile), filter, sort, skip, limit,
+// projection, canonical (write canonical instead of relaxed Extended JSON)
+// and, for csv, fields (required; the columns to write, dotted names
+// reading nested fields).
+func (c *Client) ExportFile(database string, collection string, path string, opts map[string]any) (*ExportResult, error) {
+	if path == "" {
+		return nil, errPathEmpty
+	}
+	parsed, err := parseCommandOptions(opts, "format", "filter", "fields", "canonical",
+		optSort, optSkip, optLimit, optProjection)
+	if err != nil {
+		return nil, err
+	}
+	format, err := fileFormat(opts, path)
+	if err != nil {
+		return nil, err
+	}
+	canonical, _, err := boolOption(opts, "canonical")
+	if err != nil {
+		return nil, err
+	}
+	fields, _, err := stringsOption(opts, "fields")
+	if err != nil {
+		return nil, err
+	}
+	if format == formatCSV && len(fields) == 0 {
+		return nil, errCSVFieldsNeeded
+	}
+	filter := any(bson.D{})
+	if value, ok := lookupOption(opts, "filter"); ok && value != nil {
+		filter = value
+	}
+
+	col, err := c.getCollection(database, collection)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	start := time.Now()
+	w := bufio.NewWriter(f)
+	var write func(bson.Raw) error
+	var finish func() error
+	switch format {
+	case formatJSONL, formatEJSONArray:
+		write, finish = extJSONWriter(w, canonical, format == formatEJSONArray)
+	case formatCSV:
+		write, finish, err = csvWriter(w, fields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &ExportResult{}
+	err = c.scan("exportFile", col, filter, parsed.find(), func(doc bson.Raw) error {
+		result.Exported++
+		return write(doc)
+	})
+	if err == nil {
+		err = finish()
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		log.Printf(errExportingFile, path, err)
+		return nil, err
+	}
+
+	result.DurationMillis = metrics.D(time.Since(start))
+	log.Printf("Exported %d documents from %s.%s to %s", result.Exported, database, collection, path)
+	return result, nil
+}
+
+// scan calls fn for every document of a query. The cursor is iterated
+// without the operation timeout, which only applies to opening it, so that
+// large collections can be read.
+func (c *Client) scan(op string, col *mongo.Collection, filter any, opts *options.FindOptions, fn func(bson.Raw) error) error {
+	var cursor *mongo.Cursor
+	err := c.do(op, opRead, func(ctx context.Context) error {
+		var err error
+		cursor, err = col.Find(ctx, filter, opts)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if c.vu != nil && c.vu.Context() != nil {
+		ctx = c.vu.Context()
+	}
+	defer cursor.Close(context.Background())
+	for cursor.Next(ctx) {
+		if err := fn(cursor.Current); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// fileFormat returns the format option, or the format matching the file
+// extension.
+func fileFormat(opts map[string]any, path string) (string, error) {
+	format, ok, err := stringOption(opts, "format")
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			return formatCSV, nil
+		case ".jsonl", ".ndjson", ".json":
+			return formatJSONL, nil
+		}
+		return "", fmt.Errorf("cannot tell the format of %s, set the format option", path)
+	}
+	switch format {
+	case formatJSONL, formatEJSONArray, formatCSV:
+		return format, nil
+	}
+	return "", fmt.Errorf("option format must be %q, %q or %q, got %q", formatJSONL, formatEJSONArray, formatCSV, format)
+}
+
+// extJSONReader reads Extended JSON documents, canonical or relaxed, either
+// one after the other (one per line as written by mongoexport) or as the
+// elements of an array.
+func extJSONReader(r io.Reader, array bool) (func() (bson.D, error), error) {
+	dec := json.NewDecoder(r)
+	if array {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			return nil, errors.New("expected a JSON array of documents")
+		}
+	}
+
+	var n int64
+	return func() (bson.D, error) {
+		if array && !dec.More() {
+			return nil, io.EOF
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("document %d: %w", n+1, err)
+		}
+		n++
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON(raw, false, &doc); err != nil {
+			return nil, fmt.Errorf("document %d: %w", n, err)
+		}
+		return doc, nil
+	}, nil
+}
+
+// extJSONWriter writes Extended JSON documents, one per line or as an
+// array.
+func extJSONWriter(w *bufio.Writer, canonical, array bool) (func(bson.Raw) error, func() error) {
+	first := true
+	write := func(doc bson.Raw) error {
+		data, err := bson.MarshalExtJSON(doc, canonical, false)
+		if err != nil {
+			return err
+		}
+		switch {
+		case array && first:
+			w.WriteString("[")
+		case array:
+			w.WriteString(",")
+		}
+		first = false
+		w.Write(data)
+		_, err = w.WriteString("\n")
+		return err
+	}
+	finish := func() error {
+		if !array {
+			return nil
+		}
+		if first {
+			w.WriteString("[")
+		}
+		_, err := w.WriteString("]\n")
+		return err
+	}
+	return write, finish
+}
+
+// csvReader reads CSV rows as documents, converting each column to its
+// field type.
+func csvReader(r io.Reader, opts map[string]any) (func() (bson.D, error), error) {
+	fields, hasFields, err := stringsOption(opts, "fields")
+	if err != nil {
+		return nil, err
+	}
+	ignoreBlanks, _, err := boolOption(opts, "ignoreBlanks")
+	if err != nil {
+		return nil, err
+	}
+	types := map[string]string{}
+	if value, ok := lookupOption(opts, "fieldTypes"); ok && value != nil {
+		raw, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("option fieldTypes must be an object, got %T", value)
+		}
+		for field, t := range raw {
+			name, ok := t.(string)
+			if !ok || csvConverters[name] == nil {
+				return nil, fmt.Errorf("option fieldTypes: unknown type %v for field %s", t, field)
+			}
+			types[field] = name
+		}
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	if !hasFields {
+		if fields, err = reader.Read(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return func() (bson.D, error) { return nil, io.EOF }, nil
+			}
+			return nil, err
+		}
+	}
+	for field := range types {
+		if !slices.Contains(fields, field) {
+			return nil, fmt.Errorf("option fieldTypes: unknown field %s", field)
+		}
+	}
+
+	return func() (bson.D, error) {
+		record, err := reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) > len(fields) {
+			return nil, fmt.Errorf("line %d: %d columns for %d fields", line, len(record), len(fields))
+		}
+		doc := bson.D{}
+		for i, value := range record {
+			t := types[fields[i]]
+			if t == "skip" || (ignoreBlanks && value == "") {
+				continue
+			}
+			if t == "" {
+				t = "auto"
+			}
+			converted, err := csvConverters[t](value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d, field %s: %w", line, fields[i], err)
+			}
+			doc = setPath(doc, strings.Split(fields[i], "."), converted)
+		}
+		return doc, nil
+	}, nil
+}
+
+// csvConverters convert a CSV value to the BSON type named by fieldTypes.
+var csvConverters = map[string]func(string) (any, error){
+	"auto": func(s string) (any, error) {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+		return s, nil
+	},
+	"string": func(s string) (any, error) { return s, nil },
+	"int": func(s string) (any, error) {
+		n, err := strconv.ParseInt(s, 10, 32)
+		return int32(n), err
+	},
+	"long":    func(s string) (any, error) { return strconv.ParseInt(s, 10, 64) },
+	"double":  func(s string) (any, error) { return strconv.ParseFloat(s, 64) },
+	"decimal": func(s string) (any, error) { return primitive.ParseDecimal128(s) },
+	"bool":    func(s string) (any, error) { return strconv.ParseBool(s) },
+	"date": func(s string) (any, error) {
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return primitive.NewDateTimeFromTime(t), nil
+			}
+		}
+		if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return primitive.DateTime(ms), nil
+		}
+		return nil, fmt.Errorf("invalid date %q", s)
+	},
+	"objectId": func(s string) (any, error) {
+		s = strings.TrimSuffix(strings.TrimPrefix(s, "ObjectId("), ")")
+		return primitive.ObjectIDFromHex(s)
+	},
+	"skip": func(string) (any, error) { return nil, nil },
+}
+
+// setPath sets a dotted field, creating the nested documents on the way.
+func setPath(doc bson.D, path []string, value any) bson.D {
+	for i := range doc {
+		if doc[i].Key != path[0] {
+			continue
+		}
+		if len(path) > 1 {
+			if sub, ok := doc[i].Value.(bson.D); ok {
+				doc[i].Value = setPath(sub, path[1:], value)
+				return doc
+			}
+		}
+		doc[i].Value = value
+		return doc
+	}
+	if len(path) > 1 {
+		return append(doc, bson.E{Key: path[0], Value: setPath(bson.D{}, path[1:], value)})
+	}
+	return append(doc, bson.E{Key: path[0], Value: value})
+}
+
+// csvWriter writes a header line with the fields, then one row per
+// document, formatting values like mongoexport.
+func csvWriter(w io.Writer, fields []string) (func(bson.Raw) error, func() error, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(fields); err != nil {
+		return nil, nil, err
+	}
+	row := make([]string, len(fields))
+	write := func(doc bson.Raw) error {
+		for i, field := range fields {
+			value, err := doc.LookupErr(strings.Split(field, ".")...)
+			if err != nil {
+				row[i] = ""
+				continue
+			}
+			if row[i], err = csvValue(value); err != nil {
+				return fmt.Errorf("field %s: %w", field, err)
+			}
+		}
+		return writer.Write(row)
+	}
+	finish := func() error {
+		writer.Flush()
+		return writer.Error()
+	}
+	return write, finish, nil
+}
+
+func csvValue(value bson.RawValue) (string, error) {
+	switch value.Type {
+	case bson.TypeString:
+		return value.StringValue(), nil
+	case bson.TypeInt32:
+		return strconv.FormatInt(int64(value.Int32()), 10), nil
+	case bson.TypeInt64:
+		return strconv.FormatInt(value.Int64(), 10), nil
+	case bson.TypeDouble:
+		return strconv.FormatFloat(value.Double(), 'g', -1, 64), nil
+	case bson.TypeBoolean:
+		return strconv.FormatBool(value.Boolean()), nil
+	case bson.TypeDateTime:
+		return value.Time().UTC().Format("2006-01-02T15:04:05.000Z"), nil
+	case bson.TypeObjectID:
+		return "ObjectId(" + value.ObjectID().Hex() + ")", nil
+	case bson.TypeDecimal128:
+		return value.Decimal128().String(), nil
+	case bson.TypeNull, bson.TypeUndefined:
+		return "", nil
+	}
+	// Documents, arrays and the other types are written as relaxed
+	// Extended JSON.
+	data, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: value}}, false, false)
+	if err != nil {
+		return "", err
+	}
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return "", err
+	}
+	return string(wrapper["v"]), nil
+}
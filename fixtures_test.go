@@ -0,0 +1,291 @@
+package xk6_mongo
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func readAll(t *testing.T, next func() (bson.D, error)) []bson.D {
+	t.Helper()
+	var docs []bson.D
+	for {
+		doc, err := next()
+		if errors.Is(err, io.EOF) {
+			return docs
+		}
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		docs = append(docs, doc)
+	}
+}
+
+func TestExtJSONReader(t *testing.T) {
+	lines := `{"_id": {"$oid": "5f1d7f9b2c3b4a5d6e7f8a9b"}, "n": {"$numberLong": "7"}, "at": {"$date": "2024-01-02T03:04:05Z"}}
+
+{"_id": 2, "price": {"$numberDecimal": "9.99"}, "tags": ["a", "b"]}
+`
+	next, err := extJSONReader(strings.NewReader(lines), false)
+	if err != nil {
+		t.Fatalf("extJSONReader failed: %v", err)
+	}
+	docs := readAll(t, next)
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents, got %d", len(docs))
+	}
+	first := docs[0].Map()
+	if _, ok := first["_id"].(primitive.ObjectID); !ok {
+		t.Errorf("Expected an ObjectId, got %T", first["_id"])
+	}
+	if first["n"] != int64(7) {
+		t.Errorf("Expected a long, got %T %v", first["n"], first["n"])
+	}
+	if at, ok := first["at"].(primitive.DateTime); !ok || !at.Time().Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("Expected a date, got %T %v", first["at"], first["at"])
+	}
+	if _, ok := docs[1].Map()["price"].(primitive.Decimal128); !ok {
+		t.Errorf("Expected a decimal, got %T", docs[1].Map()["price"])
+	}
+
+	next, err = extJSONReader(strings.NewReader(`[{"a": 1}, {"a": 2}, {"a": 3}]`), true)
+	if err != nil {
+		t.Fatalf("extJSONReader failed: %v", err)
+	}
+	if docs := readAll(t, next); len(docs) != 3 {
+		t.Errorf("Expected 3 documents, got %d", len(docs))
+	}
+
+	if _, err := extJSONReader(strings.NewReader(`{"a": 1}`), true); err == nil {
+		t.Error("Expected an error for an object instead of an array")
+	}
+	next, _ = extJSONReader(strings.NewReader(`{"a": 1} {"a": `), false)
+	if _, err := next(); err != nil {
+		t.Fatalf("Expected the first document, got %v", err)
+	}
+	if _, err := next(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("Expected an error for a truncated document, got %v", err)
+	}
+}
+
+func TestExtJSONWriter(t *testing.T) {
+	id := primitive.NewObjectID()
+	raw, _ := bson.Marshal(bson.D{{Key: "_id", Value: id}, {Key: "n", Value: int64(5)}})
+
+	for _, tt := range []struct {
+		name             string
+		canonical, array bool
+		docs             int
+		want             string
+	}{
+		{"relaxed lines", false, false, 1, `{"_id":{"$oid":"` + id.Hex() + `"},"n":5}` + "\n"},
+		{"canonical lines", true, false, 1, `{"_id":{"$oid":"` + id.Hex() + `"},"n":{"$numberLong":"5"}}` + "\n"},
+		{"array", false, true, 2, `[{"_id":{"$oid":"` + id.Hex() + `"},"n":5}` + "\n" + `,{"_id":{"$oid":"` + id.Hex() + `"},"n":5}` + "\n]\n"},
+	} {
+		var buf bytes.Buffer
+		w := bufio.NewWriter(&buf)
+		write, finish := extJSONWriter(w, tt.canonical, tt.array)
+		for range tt.docs {
+			if err := write(raw); err != nil {
+				t.Fatalf("%s: write failed: %v", tt.name, err)
+			}
+		}
+		if err := finish(); err != nil {
+			t.Fatalf("%s: finish failed: %v", tt.name, err)
+		}
+		w.Flush()
+		if buf.String() != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	_, finish := extJSONWriter(w, false, true)
+	_ = finish()
+	w.Flush()
+	if buf.String() != "[]\n" {
+		t.Errorf("Expected an empty array, got %q", buf.String())
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	data := "_id,name,address.city,address.zip,age,joined,active,score\n" +
+		"ObjectId(5f1d7f9b2c3b4a5d6e7f8a9b),Ann,Paris,75001,31,2024-01-02,true,\n" +
+		"5f1d7f9b2c3b4a5d6e7f8a9c,Bob,Oslo,0150,x,2024-01-03T10:00:00Z,false,2.5\n"
+	next, err := csvReader(strings.NewReader(data), map[string]any{
+		"fieldTypes": map[string]any{
+			"_id": "objectId", "address.zip": "string", "age": "string", "joined": "date", "active": "bool",
+		},
+		"ignoreBlanks": true,
+	})
+	if err != nil {
+		t.Fatalf("csvReader failed: %v", err)
+	}
+	docs := readAll(t, next)
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents, got %d", len(docs))
+	}
+	ann := docs[0].Map()
+	if _, ok := ann["_id"].(primitive.ObjectID); !ok {
+		t.Errorf("Expected an ObjectId, got %T", ann["_id"])
+	}
+	if address, ok := ann["address"].(bson.D); !ok || !reflect.DeepEqual(address, bson.D{{Key: "city", Value: "Paris"}, {Key: "zip", Value: "75001"}}) {
+		t.Errorf("Expected a nested address, got %#v", ann["address"])
+	}
+	if _, ok := ann["score"]; ok {
+		t.Error("Expected blank fields to be skipped")
+	}
+	if _, ok := ann["joined"].(primitive.DateTime); !ok || ann["active"] != true {
+		t.Errorf("Expected a date and a bool, got %T and %v", ann["joined"], ann["active"])
+	}
+	if bob := docs[1].Map(); bob["score"] != 2.5 || bob["age"] != "x" {
+		t.Errorf("Expected auto and string fields, got %v and %v", bob["score"], bob["age"])
+	}
+
+	next, err = csvReader(strings.NewReader("1,2\n"), map[string]any{"fields": []any{"a", "b"}})
+	if err != nil {
+		t.Fatalf("csvReader failed: %v", err)
+	}
+	if docs := readAll(t, next); len(docs) != 1 || docs[0].Map()["b"] != int64(2) {
+		t.Errorf("Expected the fields option to name the columns, got %v", docs)
+	}
+
+	if _, err := csvReader(strings.NewReader("a\n"), map[string]any{"fieldTypes": map[string]any{"a": "uuid"}}); err == nil {
+		t.Error("Expected an error for an unknown type")
+	}
+	if _, err := csvReader(strings.NewReader("a\n"), map[string]any{"fieldTypes": map[string]any{"b": "int"}}); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+	next, _ = csvReader(strings.NewReader("a\nnope\n"), map[string]any{"fieldTypes": map[string]any{"a": "int"}})
+	if _, err := next(); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected a conversion error naming the line, got %v", err)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("5f1d7f9b2c3b4a5d6e7f8a9b")
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	raw, _ := bson.Marshal(bson.D{
+		{Key: "_id", Value: id},
+		{Key: "name", Value: "Ann, Jr."},
+		{Key: "address", Value: bson.D{{Key: "city", Value: "Paris"}}},
+		{Key: "at", Value: at},
+		{Key: "tags", Value: bson.A{"a", int32(1)}},
+	})
+
+	var buf bytes.Buffer
+	write, finish, err := csvWriter(&buf, []string{"_id", "name", "address.city", "at", "tags", "missing"})
+	if err != nil {
+		t.Fatalf("csvWriter failed: %v", err)
+	}
+	if err := write(raw); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := finish(); err != nil {
+		t.Fatalf("finish failed: %v", err)
+	}
+	want := "_id,name,address.city,at,tags,missing\n" +
+		`ObjectId(5f1d7f9b2c3b4a5d6e7f8a9b),"Ann, Jr.",Paris,2024-01-02T03:04:05.000Z,"[""a"",1]",` + "\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestFileFormat(t *testing.T) {
+	tests := []struct {
+		path   string
+		opts   map[string]any
+		want   string
+		hasErr bool
+	}{
+		{"data.jsonl", nil, formatJSONL, false},
+		{"data.JSON", nil, formatJSONL, false},
+		{"data.csv", nil, formatCSV, false},
+		{"data.txt", map[string]any{"format": "ejson-array"}, formatEJSONArray, false},
+		{"data.txt", nil, "", true},
+		{"data.json", map[string]any{"format": "xml"}, "", true},
+	}
+	for _, tt := range tests {
+		got, err := fileFormat(tt.opts, tt.path)
+		if (err != nil) != tt.hasErr || got != tt.want {
+			t.Errorf("%s %v: expected %q (error %v), got %q (%v)", tt.path, tt.opts, tt.want, tt.hasErr, got, err)
+		}
+	}
+}
+
+func TestImportExportValidation(t *testing.T) {
+	client := &Client{}
+	if _, err := client.ImportFile("db", "col", "", nil); err != errPathEmpty {
+		t.Errorf("Expected errPathEmpty, got %v", err)
+	}
+	if _, err := client.ImportFile("db", "col", "data.jsonl", map[string]any{"headerline": true}); err == nil {
+		t.Error("Expected an error for an unknown option")
+	}
+	if _, err := client.ExportFile("db", "col", "data.csv", nil); err != errCSVFieldsNeeded {
+		t.Errorf("Expected errCSVFieldsNeeded, got %v", err)
+	}
+}
+
+func TestImportExportFile(t *testing.T) {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("MONGODB_URI not set")
+	}
+
+	client := new(Mongo).NewClient(uri)
+	if client == nil {
+		t.Fatal("Failed to create client")
+	}
+	defer func() {
+		_ = client.Disconnect()
+	}()
+
+	db, col, copied := "fixturetest", "source", "copy"
+	_ = client.DropDatabase(db)
+	defer func() {
+		_ = client.DropDatabase(db)
+	}()
+
+	docs := []any{
+		bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "n", Value: int64(1)}, {Key: "price", Value: primitive.NewDecimal128(999, 0)}},
+		bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "n", Value: int32(2)}, {Key: "at", Value: primitive.NewDateTimeFromTime(time.Now())}},
+	}
+	if _, err := client.InsertMany(db, col, docs, nil); err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+
+	for _, format := range []string{formatJSONL, formatEJSONArray} {
+		path := filepath.Join(t.TempDir(), "export."+format)
+		exported, err := client.ExportFile(db, col, path, map[string]any{"format": format, "canonical": true})
+		if err != nil || exported.Exported != 2 {
+			t.Fatalf("%s: ExportFile exported %v: %v", format, exported, err)
+		}
+		_ = client.DropCollection(db, copied)
+		imported, err := client.ImportFile(db, copied, path, map[string]any{"format": format, "batchSize": int64(1)})
+		if err != nil || imported.Inserted != 2 || imported.Batches != 2 {
+			t.Fatalf("%s: ImportFile imported %v: %v", format, imported, err)
+		}
+
+		original, _ := client.FindAll(db, col)
+		restored, _ := client.FindAll(db, copied)
+		if !reflect.DeepEqual(original, restored) {
+			t.Errorf("%s: expected types to survive the round trip, got %v and %v", format, original, restored)
+		}
+
+		again, err := client.ImportFile(db, copied, path, map[string]any{"format": format, "ignoreDuplicates": true})
+		if err != nil || again.Read != 2 || again.Inserted != 0 || again.Duplicates != 2 {
+			t.Errorf("%s: expected a second import to only find duplicates, got %v: %v", format, again, err)
+		}
+	}
+}
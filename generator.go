@@ -0,0 +1,774 @@
+package xk6_mongo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// genKey marks a template value as a field generator, e.g.
+// {$gen: "int", min: 1, max: 100}.
+const genKey = "$gen"
+
+// Distributions of the int and float generators.
+const (
+	distUniform     = "uniform"
+	distNormal      = "normal"
+	distExponential = "exponential"
+	distZipf        = "zipf"
+)
+
+var (
+	errTemplateEmpty = errors.New("generator template must be an object")
+	errCountNegative = errors.New("count cannot be negative")
+)
+
+// namedSequences are the sequences shared by every generator of the
+// process, so that VUs draw distinct values from a sequence with a name.
+var namedSequences = struct {
+	sync.Mutex
+	byName map[string]*atomic.Int64
+}{byName: map[string]*atomic.Int64{}}
+
+// fieldGenerator produces one value of a template.
+type fieldGenerator func(r *randStream) any
+
+// randStream is a random stream used by one caller at a time. It also holds
+// the state that generators keep per stream.
+type randStream struct {
+	*rand.Rand
+	zipfs map[zipfParams]*rand.Zipf
+}
+
+type zipfParams struct {
+	s    float64
+	imax uint64
+}
+
+// zipf returns the stream's Zipf sampler for s and imax, building it on first
+// use: building one is much more expensive than drawing from it.
+func (r *randStream) zipf(s float64, imax uint64) *rand.Zipf {
+	key := zipfParams{s: s, imax: imax}
+	z, ok := r.zipfs[key]
+	if !ok {
+		if r.zipfs == nil {
+			r.zipfs = map[zipfParams]*rand.Zipf{}
+		}
+		z = rand.NewZipf(r.Rand, s, 1, imax)
+		r.zipfs[key] = z
+	}
+	return z
+}
+
+// Generator produces synthetic documents from a template without building
+// them in JS.
+type Generator struct {
+	document fieldGenerator
+	seed     *uint64
+
+	mu   sync.Mutex
+	rand *randStream
+	// streams counts the random streams handed out by newRand.
+	streams atomic.Uint64
+}
+
+// Generator compiles a document template. Every field of the template is
+// either a constant, a nested template or a field generator
+// {$gen: kind, ...}. Kinds: objectId, sequence, uuid, int, float, bool,
+// date, now, enum, string, name, firstName, lastName, email, address, lorem
+// and array. Options: seed, to produce the same documents on every run.
+func (m *Mongo) Generator(template sobek.Value, opts map[string]any) (*Generator, error) {
+	if err := checkAllowedOptions(opts, "seed"); err != nil {
+		return nil, err
+	}
+	obj, ok := template.(*sobek.Object)
+	if !ok || obj.ClassName() == "Array" {
+		return nil, errTemplateEmpty
+	}
+	document, err := compileDocument(obj, "")
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Generator{document: document}
+	seed, ok, err := intOption(opts, "seed")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		s := uint64(seed)
+		g.seed = &s
+	}
+	g.rand = g.newRand()
+	return g, nil
+}
+
+// newRand returns an independent random stream, so that concurrent callers
+// do not share one. Seeded generators derive the streams from the seed.
+func (g *Generator) newRand() *randStream {
+	stream := g.streams.Add(1)
+	if g.seed != nil {
+		return &randStream{Rand: rand.New(rand.NewPCG(*g.seed, stream))}
+	}
+	return &randStream{Rand: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))}
+}
+
+// generate produces a document using r.
+func (g *Generator) generate(r *randStream) bson.D {
+	doc, _ := g.document(r).(bson.D)
+	return doc
+}
+
+// Next returns a document, to pass to insert as is.
+func (g *Generator) Next() bson.D {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.generate(g.rand)
+}
+
+// Batch returns count documents, to pass to insertMany as is.
+func (g *Generator) Batch(count int64) ([]any, error) {
+	if count < 0 {
+		return nil, errCountNegative
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	docs := make([]any, count)
+	for i := range docs {
+		docs[i] = g.generate(g.rand)
+	}
+	return docs, nil
+}
+
+// InsertModels returns count insert operations, to pass to bulkWrite as is.
+func (g *Generator) InsertModels(count int64) ([]mongo.WriteModel, error) {
+	if count < 0 {
+		return nil, errCountNegative
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	models := make([]mongo.WriteModel, count)
+	for i := range models {
+		models[i] = mongo.NewInsertOneModel().SetDocument(g.generate(g.rand))
+	}
+	return models, nil
+}
+
+// compileValue compiles a template value: a field generator, a nested
+// document, an array of template values or a constant.
+func compileValue(value sobek.Value, path string) (fieldGenerator, error) {
+	obj, ok := value.(*sobek.Object)
+	if !ok {
+		constant := exportValue(value)
+		return func(*randStream) any { return constant }, nil
+	}
+	if obj.ClassName() == "Array" {
+		keys := obj.Keys()
+		items := make([]fieldGenerator, 0, len(keys))
+		for _, key := range keys {
+			item, err := compileValue(obj.Get(key), path+"."+key)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return func(r *randStream) any {
+			out := make(bson.A, len(items))
+			for i, item := range items {
+				out[i] = item(r)
+			}
+			return out
+		}, nil
+	}
+	if spec := obj.Get(genKey); spec != nil && !sobek.IsUndefined(spec) {
+		return compileGenerator(obj, path)
+	}
+	// Dates and Go values returned by the extension are constants.
+	if _, isObject := obj.Export().(map[string]any); !isObject {
+		constant := obj.Export()
+		return func(*randStream) any { return constant }, nil
+	}
+	return compileDocument(obj, path)
+}
+
+func compileDocument(obj *sobek.Object, path string) (fieldGenerator, error) {
+	keys := obj.Keys()
+	fields := make([]fieldGenerator, 0, len(keys))
+	for _, key := range keys {
+		field, err := compileValue(obj.Get(key), strings.TrimPrefix(path+"."+key, "."))
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return func(r *randStream) any {
+		doc := make(bson.D, len(keys))
+		for i, key := range keys {
+			doc[i] = bson.E{Key: key, Value: fields[i](r)}
+		}
+		return doc
+	}, nil
+}
+
+func exportValue(value sobek.Value) any {
+	if value == nil || sobek.IsUndefined(value) || sobek.IsNull(value) {
+		return nil
+	}
+	return value.Export()
+}
+
+// compileGenerator compiles a {$gen: kind, ...} field generator.
+func compileGenerator(obj *sobek.Object, path string) (fieldGenerator, error) {
+	spec, ok := obj.Export().(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("field %s: invalid generator", path)
+	}
+	kind, ok := spec[genKey].(string)
+	if !ok {
+		return nil, fmt.Errorf("field %s: %s must be a string, got %T", path, genKey, spec[genKey])
+	}
+	params := make(map[string]any, len(spec)-1)
+	for key, value := range spec {
+		if key != genKey {
+			params[key] = value
+		}
+	}
+
+	var gen fieldGenerator
+	var err error
+	switch kind {
+	case "objectId":
+		gen, err = objectIDGenerator(params)
+	case "sequence":
+		gen, err = sequenceGenerator(params)
+	case "uuid":
+		gen, err = uuidGenerator(params)
+	case "int":
+		gen, err = numberGenerator(params, true)
+	case "float":
+		gen, err = numberGenerator(params, false)
+	case "bool":
+		gen, err = boolGenerator(params)
+	case "date":
+		gen, err = dateGenerator(params)
+	case "now":
+		gen, err = nowGenerator(params)
+	case "enum":
+		gen, err = enumGenerator(params)
+	case "string":
+		gen, err = stringGenerator(params)
+	case "name", "firstName", "lastName", "email", "address":
+		gen, err = personGenerator(kind, params)
+	case "lorem":
+		gen, err = loremGenerator(params)
+	case "array":
+		gen, err = arrayGenerator(obj, params, path)
+	default:
+		err = fmt.Errorf("unknown generator %q", kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("field %s: %w", path, err)
+	}
+	return gen, nil
+}
+
+func objectIDGenerator(params map[string]any) (fieldGenerator, error) {
+	if err := checkAllowedOptions(params); err != nil {
+		return nil, err
+	}
+	return func(*randStream) any { return primitive.NewObjectID() }, nil
+}
+
+// sequenceGenerator counts from start by step. Sequences with a name are
+// shared by every generator of the process, so that VUs never draw the same
+// value.
+func sequenceGenerator(params map[string]any) (fieldGenerator, error) {
+	if err := checkAllowedOptions(params, "start", "step", "name", "prefix"); err != nil {
+		return nil, err
+	}
+	start, ok, err := intOption(params, "start")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		start = 1
+	}
+	step, ok, err := intOption(params, "step")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		step = 1
+	}
+	if step == 0 {
+		return nil, errors.New("option step cannot be 0")
+	}
+	name, _, err := stringOption(params, "name")
+	if err != nil {
+		return nil, err
+	}
+	prefix, hasPrefix, err := stringOption(params, "prefix")
+	if err != nil {
+		return nil, err
+	}
+
+	counter := &atomic.Int64{}
+	if name != "" {
+		namedSequences.Lock()
+		if shared, ok := namedSequences.byName[name]; ok {
+			counter = shared
+		} else {
+			namedSequences.byName[name] = counter
+		}
+		namedSequences.Unlock()
+	}
+	return func(*randStream) any {
+		n := start + (counter.Add(1)-1)*step
+		if hasPrefix {
+			return fmt.Sprintf("%s%d", prefix, n)
+		}
+		return n
+	}, nil
+}
+
+// uuidGenerator produces version 4 (random) or 7 (time ordered) UUIDs as
+// BSON binary subtype 4, or as strings with format "string".
+func uuidGenerator(params map[string]any) (fieldGenerator, error) {
+	if err := checkAllowedOptions(params, "version", "format"); err != nil {
+		return nil, err
+	}
+	version, ok, err := intOption(params, "version")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		version = 4
+	}
+	if version != 4 && version != 7 {
+		return nil, fmt.Errorf("option version must be 4 or 7, got %d", version)
+	}
+	format, _, err := stringOption(params, "format")
+	if err != nil {
+		return nil, err
+	}
+	if format != "" && format != "binary" && format != "string" {
+		return nil, fmt.Errorf("option format must be \"binary\" or \"string\", got %q", format)
+	}
+
+	return func(r *randStream) any {
+		u := newUUID(r, int(version))
+		if format == "string" {
+			return formatUUID(u)
+		}
+		return primitive.Binary{Subtype: bson.TypeBinaryUUID, Data: u[:]}
+	}, nil
+}
+
+func newUUID(r *randStream, version int) [16]byte {
+	var u [16]byte
+	binary.BigEndian.PutUint64(u[:8], r.Uint64())
+	binary.BigEndian.PutUint64(u[8:], r.Uint64())
+	if version == 7 {
+		ms := uint64(time.Now().UnixMilli())
+		u[0], u[1], u[2] = byte(ms>>40), byte(ms>>32), byte(ms>>24)
+		u[3], u[4], u[5] = byte(ms>>16), byte(ms>>8), byte(ms)
+	}
+	u[6] = u[6]&0x0f | byte(version)<<4
+	u[8] = u[8]&0x3f | 0x80
+	return u
+}
+
+func formatUUID(u [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// numberGenerator draws numbers between min and max (default 0 and 100)
+// from a distribution: uniform (default), normal (mean and stddev, default
+// the middle of the range and a sixth of it), exponential (mean, default a
+// quarter of the range above min) or, for ints, zipf (s > 1, default 1.1,
+// favouring values close to min). Floats are rounded to precision decimals
+// when set. Values outside the range are clamped.
+func numberGenerator(params map[string]any, integer bool) (fieldGenerator, error) {
+	if err := checkAllowedOptions(params, "min", "max", "distribution", "mean", "stddev", "s", "precision"); err != nil {
+		return nil, err
+	}
+	lo, hi := 0.0, 100.0
+	if v, ok, err := floatOption(params, "min"); err != nil {
+		return nil, err
+	} else if ok {
+		lo = v
+	}
+	if v, ok, err := floatOption(params, "max"); err != nil {
+		return nil, err
+	} else if ok {
+		hi = v
+	}
+	if hi < lo {
+		return nil, fmt.Errorf("option max (%v) is less than min (%v)", hi, lo)
+	}
+	if integer {
+		lo, hi = math.Ceil(lo), math.Floor(hi)
+		if hi < lo {
+			return nil, errors.New("no integer between min and max")
+		}
+	}
+
+	dist, _, err := stringOption(params, "distribution")
+	if err != nil {
+		return nil, err
+	}
+	mean, meanSet, err := floatOption(params, "mean")
+	if err != nil {
+		return nil, err
+	}
+	stddev, stddevSet, err := floatOption(params, "stddev")
+	if err != nil {
+		return nil, err
+	}
+
+	var draw func(r *randStream) float64
+	switch dist {
+	case "", distUniform:
+		if integer {
+			span := uint64(hi-lo) + 1
+			draw = func(r *randStream) float64 { return lo + float64(r.Uint64N(span)) }
+		} else {
+			draw = func(r *randStream) float64 { return lo + r.Float64()*(hi-lo) }
+		}
+	case distNormal:
+		if !meanSet {
+			mean = (lo + hi) / 2
+		}
+		if !stddevSet {
+			stddev = (hi - lo) / 6
+		}
+		draw = func(r *randStream) float64 { return r.NormFloat64()*stddev + mean }
+	case distExponential:
+		if !meanSet {
+			mean = lo + (hi-lo)/4
+		}
+		scale := mean - lo
+		if scale <= 0 {
+			return nil, errors.New("option mean must be greater than min")
+		}
+		draw = func(r *randStream) float64 { return lo + r.ExpFloat64()*scale }
+	case distZipf:
+		if !integer {
+			return nil, errors.New("the zipf distribution only applies to int")
+		}
+		s, ok, err := floatOption(params, "s")
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			s = 1.1
+		}
+		if s <= 1 {
+			return nil, fmt.Errorf("option s must be greater than 1, got %v", s)
+		}
+		imax := uint64(hi - lo)
+		draw = func(r *randStream) float64 { return lo + float64(r.zipf(s, imax).Uint64()) }
+	default:
+		return nil, fmt.Errorf("unknown distribution %q", dist)
+	}
+
+	precision, hasPrecision, err := intOption(params, "precision")
+	if err != nil {
+		return nil, err
+	}
+	scale := math.Pow(10, float64(precision))
+	return func(r *randStream) any {
+		v := min(max(draw(r), lo), hi)
+		if integer {
+			return int64(math.Round(v))
+		}
+		if hasPrecision {
+			return math.Round(v*scale) / scale
+		}
+		return v
+	}, nil
+}
+
+func boolGenerator(params map[string]any) (fieldGenerator, error) {
+	if err := checkAllowedOptions(params, "probability"); err != nil {
+		return nil, err
+	}
+	p, ok, err := floatOption(params, "probability")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		p = 0.5
+	}
+	if p < 0 || p > 1 {
+		return nil, fmt.Errorf("option probability must be between 0 and 1, got %v", p)
+	}
+	return func(r *randStream) any { return r.Float64() < p }, nil
+}
+
+// dateGenerator draws dates uniformly between from and to (ISO strings,
+// Dates or milliseconds since the epoch), by default over the last year.
+func dateGenerator(params map[string]any) (fieldGenerator, error) {
+	if err := checkAllowedOptions(params, "from", "to"); err != nil {
+		return nil, err
+	}
+	to := time.Now()
+	from := to.AddDate(-1, 0, 0)
+	var err error
+	if value, ok := lookupOption(params, "from"); ok && value != nil {
+		if from, err = timeValue(value); err != nil {
+			return nil, fmt.Errorf("option from: %w", err)
+		}
+	}
+	if value, ok := lookupOption(params, "to"); ok && value != nil {
+		if to, err = timeValue(value); err != nil {
+			return nil, fmt.Errorf("option to: %w", err)
+		}
+	}
+	if to.Before(from) {
+		return nil, errors.New("option to is before from")
+	}
+	span := to.Sub(from).Milliseconds() + 1
+	return func(r *randStream) any {
+		return primitive.NewDateTimeFromTime(from.Add(time.Duration(r.Int64N(span)) * time.Millisecond))
+	}, nil
+}
+
+func timeValue(value any) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		return time.Parse(time.RFC3339Nano, v)
+	}
+	if ms, ok := toInt64(value); ok {
+		return time.UnixMilli(ms), nil
+	}
+	return time.Time{}, fmt.Errorf("expected an ISO date string, a Date or milliseconds, got %T", value)
+}
+
+func nowGenerator(params map[string]any) (fieldGenerator, error) {
+	if err := checkAllowedOptions(params); err != nil {
+		return nil, err
+	}
+	return func(*randStream) any { return primitive.NewDateTimeFromTime(time.Now()) }, nil
+}
+
+// enumGenerator picks one of values, in proportion to weights when set.
+func enumGenerator(params map[string]any) (fieldGenerator, error) {
+	if err := checkAllowedOptions(params, "values", "weights"); err != nil {
+		return nil, err
+	}
+	raw, _ := lookupOption(params, "values")
+	values, ok := documentArray(raw)
+	if !ok || len(values) == 0 {
+		return nil, errors.New("option values must be a non-empty array")
+	}
+
+	rawWeights, ok := lookupOption(params, "weights")
+	if !ok || rawWeights == nil {
+		return func(r *randStream) any { return values[r.IntN(len(values))] }, nil
+	}
+	weights, ok := documentArray(rawWeights)
+	if !ok || len(weights) != len(values) {
+		return nil, errors.New("option weights must be an array with one weight per value")
+	}
+	cumulative := make([]float64, len(weights))
+	total := 0.0
+	for i, w := range weights {
+		f, ok := toFloat64(w)
+		if !ok || f < 0 {
+			return nil, fmt.Errorf("option weights must hold non-negative numbers, got %v", w)
+		}
+		total += f
+		cumulative[i] = total
+	}
+	if total == 0 {
+		return nil, errors.New("option weights cannot all be 0")
+	}
+	return func(r *randStream) any {
+		x := r.Float64() * total
+		return values[sort.SearchFloat64s(cumulative, x)]
+	}, nil
+}
+
+const alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// stringGenerator produces random alphanumeric strings of length characters
+// (default 16), or of a length between min and max.
+func stringGenerator(params map[string]any) (fieldGenerator, error) {
+	if err := checkAllowedOptions(params, "length", "min", "max", "prefix"); err != nil {
+		return nil, err
+	}
+	lo, hi, err := lengthRange(params, "length", 16)
+	if err != nil {
+		return nil, err
+	}
+	prefix, _, err := stringOption(params, "prefix")
+	if err != nil {
+		return nil, err
+	}
+	return func(r *randStream) any {
+		n := lo + r.IntN(hi-lo+1)
+		var b strings.Builder
+		b.Grow(len(prefix) + n)
+		b.WriteString(prefix)
+		for range n {
+			b.WriteByte(alphanumeric[r.IntN(len(alphanumeric))])
+		}
+		return b.String()
+	}, nil
+}
+
+// lengthRange reads a fixed size option or a min/max range.
+func lengthRange(params map[string]any, name string, fallback int64) (int, int, error) {
+	fixed, err := optionalNonNegativeInt(params, name)
+	if err != nil {
+		return 0, 0, err
+	}
+	lo, err := optionalNonNegativeInt(params, "min")
+	if err != nil {
+		return 0, 0, err
+	}
+	hi, err := optionalNonNegativeInt(params, "max")
+	if err != nil {
+		return 0, 0, err
+	}
+	switch {
+	case fixed != nil && (lo != nil || hi != nil):
+		return 0, 0, fmt.Errorf("option %s cannot be combined with min and max", name)
+	case fixed != nil:
+		return int(*fixed), int(*fixed), nil
+	case lo == nil && hi == nil:
+		return int(fallback), int(fallback), nil
+	case lo == nil || hi == nil:
+		return 0, 0, errors.New("options min and max must be set together")
+	case *hi < *lo:
+		return 0, 0, fmt.Errorf("option max (%d) is less than min (%d)", *hi, *lo)
+	}
+	return int(*lo), int(*hi), nil
+}
+
+// personGenerator produces names, e-mail addresses and postal addresses.
+func personGenerator(kind string, params map[string]any) (fieldGenerator, error) {
+	allowed := []string{}
+	if kind == "email" {
+		allowed = append(allowed, "domain")
+	}
+	if err := checkAllowedOptions(params, allowed...); err != nil {
+		return nil, err
+	}
+	pick := func(r *randStream, list []string) string { return list[r.IntN(len(list))] }
+
+	switch kind {
+	case "firstName":
+		return func(r *randStream) any { return pick(r, firstNames) }, nil
+	case "lastName":
+		return func(r *randStream) any { return pick(r, lastNames) }, nil
+	case "name":
+		return func(r *randStream) any { return pick(r, firstNames) + " " + pick(r, lastNames) }, nil
+	case "email":
+		domain, _, err := stringOption(params, "domain")
+		if err != nil {
+			return nil, err
+		}
+		return func(r *randStream) any {
+			d := domain
+			if d == "" {
+				d = pick(r, emailDomains)
+			}
+			return fmt.Sprintf("%s.%s%d@%s", strings.ToLower(pick(r, firstNames)),
+				strings.ToLower(pick(r, lastNames)), r.IntN(10000), d)
+		}, nil
+	}
+	return func(r *randStream) any {
+		city := cities[r.IntN(len(cities))]
+		return bson.D{
+			{Key: "street", Value: fmt.Sprintf("%d %s", 1+r.IntN(9999), pick(r, streets))},
+			{Key: "city", Value: city.name},
+			{Key: "state", Value: city.state},
+			{Key: "zip", Value: fmt.Sprintf("%05d", r.IntN(100000))},
+			{Key: "country", Value: "US"},
+		}
+	}, nil
+}
+
+// loremGenerator produces lorem ipsum text of exactly bytes bytes, or of
+// words words (default 20 words).
+func loremGenerator(params map[string]any) (fieldGenerator, error) {
+	if err := checkAllowedOptions(params, "bytes", "words"); err != nil {
+		return nil, err
+	}
+	size, err := optionalNonNegativeInt(params, "bytes")
+	if err != nil {
+		return nil, err
+	}
+	words, err := optionalNonNegativeInt(params, "words")
+	if err != nil {
+		return nil, err
+	}
+	if size != nil && words != nil {
+		return nil, errors.New("options bytes and words cannot be combined")
+	}
+	if size == nil && words == nil {
+		n := int64(20)
+		words = &n
+	}
+
+	return func(r *randStream) any {
+		var b strings.Builder
+		for i := 0; ; i++ {
+			if words != nil && int64(i) == *words {
+				return b.String()
+			}
+			if size != nil && int64(b.Len()) >= *size {
+				return b.String()[:*size]
+			}
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(loremWords[r.IntN(len(loremWords))])
+		}
+	}, nil
+}
+
+// arrayGenerator produces arrays of items values generated by of, or of a
+// length between min and max.
+func arrayGenerator(obj *sobek.Object, params map[string]any, path string) (fieldGenerator, error) {
+	if err := checkAllowedOptions(params, "of", "items", "min", "max"); err != nil {
+		return nil, err
+	}
+	of := obj.Get("of")
+	if of == nil || sobek.IsUndefined(of) {
+		return nil, errors.New("option of is required")
+	}
+	item, err := compileValue(of, path+".of")
+	if err != nil {
+		return nil, err
+	}
+	lo, hi, err := lengthRange(params, "items", 3)
+	if err != nil {
+		return nil, err
+	}
+	return func(r *randStream) any {
+		out := make(bson.A, lo+r.IntN(hi-lo+1))
+		for i := range out {
+			out[i] = item(r)
+		}
+		return out
+	}, nil
+}
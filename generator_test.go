@@ -0,0 +1,258 @@
+package xk6_mongo
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTestGenerator compiles a template written in JS.
+func newTestGenerator(t *testing.T, template string, opts map[string]any) (*Generator, error) {
+	t.Helper()
+	value, err := sobek.New().RunString("(" + template + ")")
+	if err != nil {
+		t.Fatalf("RunString failed: %v", err)
+	}
+	return new(Mongo).Generator(value, opts)
+}
+
+func mustGenerator(t *testing.T, template string, opts map[string]any) *Generator {
+	t.Helper()
+	g, err := newTestGenerator(t, template, opts)
+	if err != nil {
+		t.Fatalf("Generator failed: %v", err)
+	}
+	return g
+}
+
+func TestGeneratorDocument(t *testing.T) {
+	g := mustGenerator(t, `{
+		_id: {$gen: "objectId"},
+		seq: {$gen: "sequence", start: 10, step: 5},
+		code: {$gen: "sequence", prefix: "ord-"},
+		uuid: {$gen: "uuid"},
+		uuid7: {$gen: "uuid", version: 7, format: "string"},
+		qty: {$gen: "int", min: 1, max: 5},
+		price: {$gen: "float", min: 1, max: 2, precision: 2},
+		active: {$gen: "bool", probability: 1},
+		createdAt: {$gen: "date", from: "2024-01-01T00:00:00Z", to: "2024-01-31T00:00:00Z"},
+		status: {$gen: "enum", values: ["new", "paid"], weights: [0, 1]},
+		customer: {name: {$gen: "name"}, email: {$gen: "email", domain: "shop.test"}, address: {$gen: "address"}},
+		note: {$gen: "lorem", bytes: 100},
+		tags: {$gen: "array", of: {$gen: "string", length: 4}, items: 3},
+		source: "k6",
+		fixed: [1, {$gen: "int", min: 7, max: 7}]
+	}`, nil)
+
+	first, second := g.Next(), g.Next()
+	keys := make([]string, 0, len(first))
+	for _, e := range first {
+		keys = append(keys, e.Key)
+	}
+	want := []string{"_id", "seq", "code", "uuid", "uuid7", "qty", "price", "active", "createdAt",
+		"status", "customer", "note", "tags", "source", "fixed"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("Expected template field order, got %v", keys)
+	}
+
+	doc, next := first.Map(), second.Map()
+	if _, ok := doc["_id"].(primitive.ObjectID); !ok {
+		t.Errorf("Expected an ObjectId, got %T", doc["_id"])
+	}
+	if doc["seq"] != int64(10) || next["seq"] != int64(15) {
+		t.Errorf("Expected sequence 10, 15, got %v, %v", doc["seq"], next["seq"])
+	}
+	if doc["code"] != "ord-1" || next["code"] != "ord-2" {
+		t.Errorf("Expected prefixed sequence, got %v, %v", doc["code"], next["code"])
+	}
+	if u, ok := doc["uuid"].(primitive.Binary); !ok || u.Subtype != bson.TypeBinaryUUID || len(u.Data) != 16 || u.Data[6]>>4 != 4 {
+		t.Errorf("Expected a binary UUIDv4, got %v", doc["uuid"])
+	}
+	if u, ok := doc["uuid7"].(string); !ok || len(u) != 36 || u[14] != '7' {
+		t.Errorf("Expected a UUIDv7 string, got %v", doc["uuid7"])
+	}
+	if qty, ok := doc["qty"].(int64); !ok || qty < 1 || qty > 5 {
+		t.Errorf("Expected an int between 1 and 5, got %v", doc["qty"])
+	}
+	if price, ok := doc["price"].(float64); !ok || price < 1 || price > 2 || price*100 != float64(int(price*100+0.5)) {
+		t.Errorf("Expected a float between 1 and 2 with 2 decimals, got %v", doc["price"])
+	}
+	if doc["active"] != true {
+		t.Errorf("Expected true, got %v", doc["active"])
+	}
+	created, ok := doc["createdAt"].(primitive.DateTime)
+	if !ok || created.Time().Before(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) ||
+		created.Time().After(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected a date in January 2024, got %v", doc["createdAt"])
+	}
+	if doc["status"] != "paid" {
+		t.Errorf("Expected the only weighted value, got %v", doc["status"])
+	}
+	customer := doc["customer"].(bson.D).Map()
+	if name, _ := customer["name"].(string); !strings.Contains(name, " ") {
+		t.Errorf("Expected a full name, got %v", customer["name"])
+	}
+	if email, _ := customer["email"].(string); !strings.HasSuffix(email, "@shop.test") {
+		t.Errorf("Expected an email at shop.test, got %v", customer["email"])
+	}
+	if address, ok := customer["address"].(bson.D); !ok || len(address) != 5 {
+		t.Errorf("Expected an address document, got %v", customer["address"])
+	}
+	if note, _ := doc["note"].(string); len(note) != 100 {
+		t.Errorf("Expected 100 bytes of lorem, got %d", len(note))
+	}
+	if tags, ok := doc["tags"].(bson.A); !ok || len(tags) != 3 || len(tags[0].(string)) != 4 {
+		t.Errorf("Expected 3 strings of 4 characters, got %v", doc["tags"])
+	}
+	if doc["source"] != "k6" {
+		t.Errorf("Expected a constant, got %v", doc["source"])
+	}
+	if fixed, ok := doc["fixed"].(bson.A); !ok || !reflect.DeepEqual(fixed, bson.A{int64(1), int64(7)}) {
+		t.Errorf("Expected [1, 7], got %#v", doc["fixed"])
+	}
+}
+
+func TestGeneratorDistributions(t *testing.T) {
+	for _, dist := range []string{"uniform", "normal", "exponential", "zipf"} {
+		g := mustGenerator(t, `{v: {$gen: "int", min: 10, max: 20, distribution: "`+dist+`"}}`, nil)
+		docs, err := g.Batch(500)
+		if err != nil {
+			t.Fatalf("Batch failed: %v", err)
+		}
+		for _, d := range docs {
+			if v := d.(bson.D)[0].Value.(int64); v < 10 || v > 20 {
+				t.Fatalf("%s: value %d outside [10, 20]", dist, v)
+			}
+		}
+	}
+
+	g := mustGenerator(t, `{v: {$gen: "int", min: 0, max: 1000, distribution: "zipf", s: 2}}`, nil)
+	low := 0
+	for range 1000 {
+		if g.Next()[0].Value.(int64) < 10 {
+			low++
+		}
+	}
+	if low < 800 {
+		t.Errorf("Expected zipf values to concentrate near min, got %d of 1000 below 10", low)
+	}
+	if len(g.rand.zipfs) != 1 {
+		t.Errorf("Expected one zipf sampler for the stream, got %d", len(g.rand.zipfs))
+	}
+	if r := g.newRand(); r.zipf(2, 1000) == g.rand.zipf(2, 1000) {
+		t.Error("Expected each stream to build its own zipf sampler")
+	}
+}
+
+func TestGeneratorSeed(t *testing.T) {
+	template := `{n: {$gen: "int", max: 1000000}, name: {$gen: "name"}, id: {$gen: "uuid"}}`
+	a := mustGenerator(t, template, map[string]any{"seed": int64(42)})
+	b := mustGenerator(t, template, map[string]any{"seed": int64(42)})
+	first, _ := a.Batch(5)
+	second, _ := b.Batch(5)
+	if !reflect.DeepEqual(first, second) {
+		t.Error("Expected seeded generators to produce the same documents")
+	}
+}
+
+func TestGeneratorNamedSequence(t *testing.T) {
+	template := `{n: {$gen: "sequence", name: "generator-test"}}`
+	a, b := mustGenerator(t, template, nil), mustGenerator(t, template, nil)
+	seen := map[int64]bool{}
+	for range 5 {
+		for _, g := range []*Generator{a, b} {
+			n := g.Next()[0].Value.(int64)
+			if seen[n] {
+				t.Fatalf("Expected named sequences to be shared, got %d twice", n)
+			}
+			seen[n] = true
+		}
+	}
+}
+
+func TestGeneratorModels(t *testing.T) {
+	g := mustGenerator(t, `{_id: {$gen: "sequence"}}`, nil)
+	models, err := g.InsertModels(3)
+	if err != nil || len(models) != 3 {
+		t.Fatalf("Expected 3 models, got %d (%v)", len(models), err)
+	}
+	if _, err := g.Batch(-1); err == nil {
+		t.Error("Expected an error for a negative count")
+	}
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown kind":        `{a: {$gen: "nope"}}`,
+		"unknown option":      `{a: {$gen: "int", maximum: 3}}`,
+		"max below min":       `{a: {$gen: "int", min: 5, max: 1}}`,
+		"unknown dist":        `{a: {$gen: "float", distribution: "pareto"}}`,
+		"zipf float":          `{a: {$gen: "float", distribution: "zipf"}}`,
+		"uuid version":        `{a: {$gen: "uuid", version: 5}}`,
+		"empty enum":          `{a: {$gen: "enum", values: []}}`,
+		"weights length":      `{a: {$gen: "enum", values: [1, 2], weights: [1]}}`,
+		"bad date":            `{a: {$gen: "date", from: "yesterday"}}`,
+		"array without of":    `{a: {$gen: "array", items: 2}}`,
+		"nested error":        `{a: {b: {$gen: "sequence", step: 0}}}`,
+		"lorem bytes & words": `{a: {$gen: "lorem", bytes: 10, words: 2}}`,
+		"template array":      `[1, 2]`,
+	}
+	for name, template := range tests {
+		if _, err := newTestGenerator(t, template, nil); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	_, err := newTestGenerator(t, `{a: {b: {$gen: "nope"}}}`, nil)
+	if err == nil || !strings.Contains(err.Error(), "a.b") {
+		t.Errorf("Expected the error to name the field, got %v", err)
+	}
+	if _, err := newTestGenerator(t, `{}`, map[string]any{"sead": int64(1)}); err == nil {
+		t.Error("Expected an error for an unknown option")
+	}
+}
+
+func TestGeneratorInserts(t *testing.T) {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("MONGODB_URI not set")
+	}
+
+	client := new(Mongo).NewClient(uri)
+	if client == nil {
+		t.Fatal("Failed to create client")
+	}
+	defer func() {
+		_ = client.Disconnect()
+	}()
+
+	db, col := "generatortest", "docs"
+	_ = client.DropCollection(db, col)
+	defer func() {
+		_ = client.DropCollection(db, col)
+	}()
+
+	g := mustGenerator(t, `{_id: {$gen: "uuid"}, name: {$gen: "name"}, n: {$gen: "int"}}`, nil)
+	if err := client.Insert(db, col, g, nil); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	docs, _ := g.Batch(10)
+	if _, err := client.InsertMany(db, col, docs, nil); err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+	models, _ := g.InsertModels(5)
+	if inserted, _, err := client.BulkWrite(db, col, models, nil); err != nil || inserted != 5 {
+		t.Fatalf("BulkWrite inserted %d: %v", inserted, err)
+	}
+
+	count, err := client.CountDocuments(db, col, bson.M{}, nil)
+	if err != nil || count != 16 {
+		t.Errorf("Expected 16 documents, got %d (%v)", count, err)
+	}
+}
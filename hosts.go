@@ -0,0 +1,53 @@
+package xk6_mongo
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const errDiscoveringHosts = "Error while discovering hosts: %v"
+
+// knownHosts lists the data-bearing members of the deployment as reported by
+// the hello command. Standalone servers and mongos routers do not report
+// other members, so the seed list from the connection string is used.
+func (c *Client) knownHosts(ctx context.Context) ([]string, error) {
+	var hello struct {
+		Hosts    []string `bson:"hosts"`
+		Passives []string `bson:"passives"`
+	}
+	err := c.client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		log.Printf(errDiscoveringHosts, err)
+		return nil, err
+	}
+
+	if hosts := append(hello.Hosts, hello.Passives...); len(hosts) > 0 {
+		return hosts, nil
+	}
+	if c.clientOptions != nil {
+		return c.clientOptions.Hosts, nil
+	}
+	return nil, nil
+}
+
+// connectToHost opens a direct connection to a single member, reusing the
+// client's credentials and TLS settings. Commands such as serverStatus
+// describe the server they run on, so they must not be routed by the
+// driver's server selection.
+func (c *Client) connectToHost(ctx context.Context, host string) (*mongo.Client, error) {
+	opts := options.Client().SetHosts([]string{host}).SetDirect(true).SetMaxPoolSize(1)
+	if base := c.clientOptions; base != nil {
+		opts.Auth = base.Auth
+		opts.TLSConfig = base.TLSConfig
+		opts.AppName = base.AppName
+		opts.Compressors = base.Compressors
+		opts.ServerAPIOptions = base.ServerAPIOptions
+		opts.ConnectTimeout = base.ConnectTimeout
+		opts.Dialer = base.Dialer
+	}
+	return mongo.Connect(ctx, opts)
+}
@@ -0,0 +1,137 @@
+package xk6_mongo
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	errInsertWriteErrors = "InsertMany reported %d write errors, first: %v"
+
+	duplicateKeyCode = 11000
+)
+
+// InsertManyResult reports the outcome of an InsertMany call.
+type InsertManyResult struct {
+	// InsertedIDs holds the _id of every inserted document, in input order.
+	// Unacknowledged inserts report the _id of every document sent.
+	InsertedIDs   []any `js:"insertedIds"`
+	InsertedCount int64 `js:"insertedCount"`
+	// DuplicateCount is the number of documents skipped because their key
+	// already existed, when ignoreDuplicates is set.
+	DuplicateCount int64        `js:"duplicateCount"`
+	WriteErrors    []WriteError `js:"writeErrors"`
+}
+
+// WriteError is the error of a single document of a batch.
+type WriteError struct {
+	// Index is the position of the document in the input array.
+	Index   int    `js:"index"`
+	Code    int    `js:"code"`
+	Message string `js:"message"`
+}
+
+// InsertMany inserts documents. Options: ordered (default true; when false
+// the server keeps inserting after a failed document), bypassDocumentValidation,
+// comment, ignoreDuplicates (count documents rejected with a duplicate key
+// error as duplicates instead of write errors) and unacknowledged (see
+// Insert). Per-document failures are returned in writeErrors instead of
+// being thrown; command failures such as network or write concern errors
+// are thrown.
+func (c *Client) InsertMany(database string, collection string, docs []any, opts map[string]any) (*InsertManyResult, error) {
+	if len(docs) == 0 {
+		return nil, errDocsEmpty
+	}
+
+	parsed, err := parseCommandOptions(opts, optBypassDocumentValidation, optComment,
+		optIgnoreDuplicates, optOrdered, optUnacknowledged)
+	if err != nil {
+		return nil, err
+	}
+
+	col, unacknowledged, err := c.writeCollection(database, collection, parsed.unacknowledged)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	result, err := c.insertMany("insertMany", col, docs, parsed)
+	if err != nil {
+		log.Printf(errInsertingDocuments, err)
+		return nil, err
+	}
+	if len(result.WriteErrors) > 0 {
+		log.Printf(errInsertWriteErrors, len(result.WriteErrors), result.WriteErrors[0].Message)
+	}
+	c.recordWrite("insertMany", database, collection, unacknowledged, int(result.InsertedCount))
+	return result, nil
+}
+
+// insertMany inserts docs, turning the per-document errors of a
+// BulkWriteException into the writeErrors of the result.
+func (c *Client) insertMany(op string, col *mongo.Collection, docs []any, parsed *commandOptions) (*InsertManyResult, error) {
+	var inserted *mongo.InsertManyResult
+	err := c.do(op, insertKind(docs...), func(ctx context.Context) error {
+		var err error
+		inserted, err = col.InsertMany(ctx, docs, parsed.insertMany())
+		return err
+	})
+
+	var bwe mongo.BulkWriteException
+	if err != nil && (!errors.As(err, &bwe) || bwe.WriteConcernError != nil || len(bwe.WriteErrors) == 0) {
+		return nil, err
+	}
+	ordered := parsed.ordered == nil || *parsed.ordered
+	return newInsertManyResult(inserted, bwe.WriteErrors, ordered, parsed.ignoreDuplicates != nil && *parsed.ignoreDuplicates), nil
+}
+
+// newInsertManyResult builds the result of an insert. The driver reports the
+// _id of every input document, even after a BulkWriteException, so the
+// documents that failed are removed and, for ordered inserts, the documents
+// after the first failure, which the server did not try.
+func newInsertManyResult(inserted *mongo.InsertManyResult, writeErrors []mongo.BulkWriteError, ordered, ignoreDuplicates bool) *InsertManyResult {
+	result := &InsertManyResult{InsertedIDs: []any{}, WriteErrors: []WriteError{}}
+	if inserted != nil && inserted.InsertedIDs != nil {
+		ids := inserted.InsertedIDs
+		failed := make(map[int]bool, len(writeErrors))
+		for _, we := range writeErrors {
+			failed[we.Index] = true
+			if ordered && we.Index < len(ids) {
+				ids = ids[:we.Index]
+			}
+		}
+		for i, id := range ids {
+			if !failed[i] {
+				result.InsertedIDs = append(result.InsertedIDs, id)
+			}
+		}
+	}
+	result.InsertedCount = int64(len(result.InsertedIDs))
+
+	for _, we := range writeErrors {
+		if ignoreDuplicates && we.Code == duplicateKeyCode {
+			result.DuplicateCount++
+			continue
+		}
+		result.WriteErrors = append(result.WriteErrors, WriteError{Index: we.Index, Code: we.Code, Message: we.Message})
+	}
+	return result
+}
+
+func (o *commandOptions) insertMany() *options.InsertManyOptions {
+	opts := options.InsertMany()
+	if o.bypassDocumentValidation != nil {
+		opts.SetBypassDocumentValidation(*o.bypassDocumentValidation)
+	}
+	if o.comment != nil {
+		opts.SetComment(o.comment)
+	}
+	if o.ordered != nil {
+		opts.SetOrdered(*o.ordered)
+	}
+	return opts
+}
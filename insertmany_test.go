@@ -0,0 +1,131 @@
+package xk6_mongo
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestNewInsertManyResult(t *testing.T) {
+	// The driver returns the _id of every input document along with the
+	// write errors.
+	inserted := &mongo.InsertManyResult{InsertedIDs: []any{"a", "b", "c", "d", "e"}}
+	writeErrors := []mongo.BulkWriteError{
+		{WriteError: mongo.WriteError{Index: 1, Code: 11000, Message: "E11000 duplicate key"}},
+		{WriteError: mongo.WriteError{Index: 3, Code: 121, Message: "Document failed validation"}},
+	}
+
+	result := newInsertManyResult(inserted, writeErrors, false, false)
+	if result.InsertedCount != 3 || !reflect.DeepEqual(result.InsertedIDs, []any{"a", "c", "e"}) ||
+		result.DuplicateCount != 0 || len(result.WriteErrors) != 2 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	if we := result.WriteErrors[1]; we.Index != 3 || we.Code != 121 || we.Message != "Document failed validation" {
+		t.Errorf("Unexpected write error %+v", we)
+	}
+
+	result = newInsertManyResult(inserted, writeErrors, false, true)
+	if result.InsertedCount != 3 || result.DuplicateCount != 1 || len(result.WriteErrors) != 1 || result.WriteErrors[0].Code != 121 {
+		t.Errorf("Expected the duplicate key error to be counted as a duplicate, got %+v", result)
+	}
+
+	result = newInsertManyResult(inserted, writeErrors[:1], true, true)
+	if result.InsertedCount != 1 || !reflect.DeepEqual(result.InsertedIDs, []any{"a"}) || result.DuplicateCount != 1 {
+		t.Errorf("Expected an ordered insert to stop at the first error, got %+v", result)
+	}
+
+	all := make([]mongo.BulkWriteError, 5)
+	for i := range all {
+		all[i] = mongo.BulkWriteError{WriteError: mongo.WriteError{Index: i, Code: duplicateKeyCode}}
+	}
+	result = newInsertManyResult(inserted, all, false, true)
+	if result.InsertedCount != 0 || len(result.InsertedIDs) != 0 || result.DuplicateCount != 5 {
+		t.Errorf("Expected every document counted as a duplicate, got %+v", result)
+	}
+
+	result = newInsertManyResult(nil, nil, true, false)
+	if result.InsertedIDs == nil || result.WriteErrors == nil || result.InsertedCount != 0 {
+		t.Errorf("Expected empty arrays for a nil result, got %+v", result)
+	}
+}
+
+func TestInsertManyOptions(t *testing.T) {
+	client := &Client{}
+	docs := []any{bson.M{"_id": 1}}
+	if _, err := client.InsertMany("db", "col", docs, map[string]any{"upsert": true}); err == nil {
+		t.Error("Expected an error for an unsupported option")
+	}
+	if _, err := client.InsertMany("db", "col", docs, map[string]any{"ordered": "no"}); err == nil {
+		t.Error("Expected an error for a non-boolean ordered option")
+	}
+
+	parsed, err := parseCommandOptions(map[string]any{
+		"ordered": false, "bypassDocumentValidation": true, "comment": "seed",
+	}, optBypassDocumentValidation, optComment, optOrdered)
+	if err != nil {
+		t.Fatalf("parseCommandOptions failed: %v", err)
+	}
+	opts := parsed.insertMany()
+	if opts.Ordered == nil || *opts.Ordered || opts.BypassDocumentValidation == nil ||
+		!*opts.BypassDocumentValidation || opts.Comment != "seed" {
+		t.Errorf("Unexpected insert options %+v", opts)
+	}
+}
+
+func TestInsertManyPartialFailure(t *testing.T) {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("MONGODB_URI not set")
+	}
+
+	client := new(Mongo).NewClient(uri)
+	if client == nil {
+		t.Fatal("Failed to create client")
+	}
+	defer func() {
+		_ = client.Disconnect()
+	}()
+
+	db, col := "insertmanytest", "partial"
+	_ = client.DropCollection(db, col)
+	defer func() {
+		_ = client.DropCollection(db, col)
+	}()
+
+	if err := client.Insert(db, col, bson.M{"_id": 2}, nil); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	docs := []any{bson.M{"_id": 1}, bson.M{"_id": 2}, bson.M{"_id": 3}}
+
+	result, err := client.InsertMany(db, col, docs, map[string]any{"ordered": false})
+	if err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+	if result.InsertedCount != 2 || len(result.WriteErrors) != 1 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	if we := result.WriteErrors[0]; we.Index != 1 || we.Code != duplicateKeyCode {
+		t.Errorf("Unexpected write error %+v", we)
+	}
+
+	result, err = client.InsertMany(db, col, docs, map[string]any{"ordered": false, "ignoreDuplicates": true})
+	if err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+	if result.InsertedCount != 0 || result.DuplicateCount != 3 || len(result.WriteErrors) != 0 {
+		t.Errorf("Expected every document counted as a duplicate, got %+v", result)
+	}
+
+	_ = client.DropCollection(db, col)
+	_ = client.Insert(db, col, bson.M{"_id": 2}, nil)
+	result, err = client.InsertMany(db, col, docs, nil)
+	if err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+	if result.InsertedCount != 1 || len(result.InsertedIDs) != 1 || len(result.WriteErrors) != 1 {
+		t.Errorf("Expected an ordered insert to stop at the duplicate, got %+v", result)
+	}
+}
@@ -0,0 +1,127 @@
+package xk6_mongo
+
+import (
+	"time"
+
+	"go.k6.io/k6/metrics"
+)
+
+// mongoMetrics holds the custom k6 metrics emitted by the extension.
+type mongoMetrics struct {
+	changeStreamLag    *metrics.Metric
+	changeStreamEvents *metrics.Metric
+	tailableLatency    *metrics.Metric
+	tailableDocuments  *metrics.Metric
+	planAssertions     *metrics.Metric
+	planViolations     *metrics.Metric
+
+	serverOpcounters           *metrics.Metric
+	serverConnectionsCurrent   *metrics.Metric
+	serverConnectionsAvailable *metrics.Metric
+	serverCacheDirtyBytes      *metrics.Metric
+	serverCacheUsedBytes       *metrics.Metric
+	serverTicketsAvailable     *metrics.Metric
+	serverReplicationLag       *metrics.Metric
+	serverGlobalLockQueue      *metrics.Metric
+	activeOperations           *metrics.Metric
+
+	failoverUnavailable *metrics.Metric
+
+	topologyChanges   *metrics.Metric
+	serverKindChanges *metrics.Metric
+	heartbeatRTT      *metrics.Metric
+	heartbeatFailures *metrics.Metric
+
+	retries      *metrics.Metric
+	retryGiveUps *metrics.Metric
+
+	writes        *metrics.Metric
+	documentsSent *metrics.Metric
+}
+
+// registerMetrics registers the extension's metrics. The registry returns the
+// existing metric when a name is registered again, so every VU can call it.
+func registerMetrics(registry *metrics.Registry) *mongoMetrics {
+	return &mongoMetrics{
+		changeStreamLag:    registry.MustNewMetric("mongo_changestream_lag", metrics.Trend, metrics.Time),
+		changeStreamEvents: registry.MustNewMetric("mongo_changestream_events", metrics.Counter),
+		tailableLatency:    registry.MustNewMetric("mongo_tailable_delivery_latency", metrics.Trend, metrics.Time),
+		tailableDocuments:  registry.MustNewMetric("mongo_tailable_documents", metrics.Counter),
+		planAssertions:     registry.MustNewMetric("mongo_plan_assertions", metrics.Rate),
+		planViolations:     registry.MustNewMetric("mongo_plan_violations", metrics.Counter),
+
+		serverOpcounters:           registry.MustNewMetric("mongo_server_opcounters_delta", metrics.Gauge),
+		serverConnectionsCurrent:   registry.MustNewMetric("mongo_server_connections_current", metrics.Gauge),
+		serverConnectionsAvailable: registry.MustNewMetric("mongo_server_connections_available", metrics.Gauge),
+		serverCacheDirtyBytes:      registry.MustNewMetric("mongo_server_wt_cache_dirty_bytes", metrics.Gauge, metrics.Data),
+		serverCacheUsedBytes:       registry.MustNewMetric("mongo_server_wt_cache_used_bytes", metrics.Gauge, metrics.Data),
+		serverTicketsAvailable:     registry.MustNewMetric("mongo_server_tickets_available", metrics.Gauge),
+		serverReplicationLag:       registry.MustNewMetric("mongo_server_replication_lag", metrics.Gauge, metrics.Time),
+		serverGlobalLockQueue:      registry.MustNewMetric("mongo_server_globallock_queue", metrics.Gauge),
+		activeOperations:           registry.MustNewMetric("mongo_active_operations", metrics.Gauge),
+
+		failoverUnavailable: registry.MustNewMetric("mongo_failover_unavailable_ms", metrics.Trend, metrics.Time),
+
+		topologyChanges:   registry.MustNewMetric("mongo_topology_changes", metrics.Counter),
+		serverKindChanges: registry.MustNewMetric("mongo_server_kind_changes", metrics.Counter),
+		heartbeatRTT:      registry.MustNewMetric("mongo_heartbeat_rtt", metrics.Trend, metrics.Time),
+		heartbeatFailures: registry.MustNewMetric("mongo_heartbeat_failures", metrics.Counter),
+
+		retries:      registry.MustNewMetric("mongo_retries", metrics.Counter),
+		retryGiveUps: registry.MustNewMetric("mongo_retry_giveups", metrics.Counter),
+
+		writes:        registry.MustNewMetric("mongo_writes", metrics.Counter),
+		documentsSent: registry.MustNewMetric("mongo_documents_sent", metrics.Counter),
+	}
+}
+
+// emit pushes a sample tagged with the current VU tags plus extra. It is a
+// no-op outside of a running VU, e.g. in the init context or in unit tests.
+func (c *Client) emit(metric *metrics.Metric, value float64, extra map[string]string) {
+	if c.vu == nil || metric == nil {
+		return
+	}
+	state := c.vu.State()
+	if state == nil {
+		return
+	}
+
+	tagsAndMeta := state.Tags.GetCurrentValues()
+	tags := tagsAndMeta.Tags
+	if len(extra) > 0 {
+		tags = tags.WithTagsFromMap(extra)
+	}
+
+	metrics.PushIfNotDone(c.vu.Context(), state.Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{Metric: metric, Tags: tags},
+		Time:       time.Now(),
+		Value:      value,
+		Metadata:   tagsAndMeta.Metadata,
+	})
+}
+
+// emitCheck records a check result the same way k6's check() does, so it is
+// counted by the checks metric and shown in the end-of-test summary.
+func (c *Client) emitCheck(name string, passed bool, extra map[string]string) {
+	if c.vu == nil {
+		return
+	}
+	state := c.vu.State()
+	if state == nil || state.BuiltinMetrics == nil {
+		return
+	}
+
+	tags := make(map[string]string, len(extra)+1)
+	for key, value := range extra {
+		tags[key] = value
+	}
+	if state.Options.SystemTags.Has(metrics.TagCheck) {
+		tags["check"] = name
+	}
+
+	value := 0.0
+	if passed {
+		value = 1
+	}
+	c.emit(state.BuiltinMetrics.Checks, value, tags)
+}
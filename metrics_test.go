@@ -0,0 +1,53 @@
+package xk6_mongo
+
+import (
+	"testing"
+	"time"
+
+	"go.k6.io/k6/js/modulestest"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
+)
+
+// newMetricsTestClient returns a client wired to a test VU in the VU context
+// together with the channel its samples are pushed to.
+func newMetricsTestClient(t *testing.T) (*Client, chan metrics.SampleContainer) {
+	t.Helper()
+
+	rt := modulestest.NewRuntime(t)
+	m, ok := new(RootModule).NewModuleInstance(rt.VU).(*Mongo)
+	if !ok {
+		t.Fatal("Expected a *Mongo module instance")
+	}
+
+	registry := rt.VU.InitEnv().Registry
+	samples := make(chan metrics.SampleContainer, 100)
+	rt.MoveToVUContext(&lib.State{
+		Samples:        samples,
+		Tags:           lib.NewVUStateTags(registry.RootTagSet()),
+		BuiltinMetrics: metrics.RegisterBuiltinMetrics(registry),
+		Options:        lib.Options{SystemTags: &metrics.DefaultSystemTagSet},
+	})
+
+	return &Client{vu: rt.VU, metrics: m.metrics}, samples
+}
+
+// collectSamples drains every sample pushed so far.
+func collectSamples(samples chan metrics.SampleContainer) []metrics.Sample {
+	var out []metrics.Sample
+	for {
+		select {
+		case container := <-samples:
+			out = append(out, container.GetSamples()...)
+		default:
+			return out
+		}
+	}
+}
+
+func TestEmitWithoutVU(t *testing.T) {
+	client := &Client{}
+	// Must not panic outside of a VU.
+	client.emit(nil, 1, nil)
+	client.recordChangeEvent(nil, time.Now())
+}
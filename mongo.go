@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -18,18 +20,47 @@ import (
 // Register the extension on module initialization, available to
 // import from JS as "k6/x/mongo".
 func init() {
-	k6modules.Register("k6/x/mongo", new(Mongo))
+	k6modules.Register("k6/x/mongo", new(RootModule))
+}
+
+// RootModule is the global module object that creates a Mongo instance for
+// every VU importing the extension.
+type RootModule struct {
+	testEndWatch sync.Once
 }
 
 // Mongo is the k6 extension for a Mongo client.
-type Mongo struct{}
+type Mongo struct {
+	vu      k6modules.VU
+	metrics *mongoMetrics
+}
 
 // Client is the Mongo client wrapper.
 type Client struct {
 	client         *mongo.Client
+	clientOptions  *options.ClientOptions
+	vu             k6modules.VU
+	metrics        *mongoMetrics
+	topology       *topologyWatcher
 	defaultTimeout time.Duration
 	retryWrites    bool
 	retryReads     bool
+	retry          atomic.Pointer[retryPolicy]
+}
+
+// NewModuleInstance implements the k6modules.Module interface.
+func (r *RootModule) NewModuleInstance(vu k6modules.VU) k6modules.Instance {
+	r.testEndWatch.Do(func() { watchTestEnd(vu) })
+	m := &Mongo{vu: vu}
+	if initEnv := vu.InitEnv(); initEnv != nil {
+		m.metrics = registerMetrics(initEnv.Registry)
+	}
+	return m
+}
+
+// Exports implements the k6modules.Instance interface.
+func (m *Mongo) Exports() k6modules.Exports {
+	return k6modules.Exports{Default: m}
 }
 
 type UpsertOneModel struct {
@@ -39,8 +70,9 @@ type UpsertOneModel struct {
 
 // Session wraps a mongo.Session for transaction support.
 type Session struct {
-	session mongo.Session
-	client  *Client
+	session       mongo.Session
+	client        *Client
+	inTransaction bool
 }
 
 const (
@@ -55,7 +87,7 @@ func (m *Mongo) NewClient(connURI string) *Client {
 	return m.NewClientWithOptions(connURI, nil)
 }
 
-func (*Mongo) NewClientWithOptions(connURI string, opts any) *Client {
+func (m *Mongo) NewClientWithOptions(connURI string, opts any) *Client {
 	log.Print("start creating new client")
 
 	if connURI == "" {
@@ -69,6 +101,24 @@ func (*Mongo) NewClientWithOptions(connURI string, opts any) *Client {
 		return nil
 	}
 
+	// Retry writes and reads are enabled by default (can be overridden in client options)
+	retryWrites := clientOptions.RetryWrites == nil || *clientOptions.RetryWrites
+	retryReads := clientOptions.RetryReads == nil || *clientOptions.RetryReads
+
+	c := &Client{
+		clientOptions:  clientOptions,
+		vu:             m.vu,
+		metrics:        m.metrics,
+		defaultTimeout: defaultOperationTimeout,
+		retryWrites:    retryWrites,
+		retryReads:     retryReads,
+	}
+
+	// The topology watcher must be attached before connecting to see the
+	// initial server discovery.
+	c.topology = newTopologyWatcher(c)
+	clientOptions.SetServerMonitor(c.topology.serverMonitor(clientOptions.ServerMonitor))
+
 	// Create context with timeout for connection
 	ctx, cancel := context.WithTimeout(context.Background(), defaultConnectionTimeout)
 	defer cancel()
@@ -89,16 +139,8 @@ func (*Mongo) NewClientWithOptions(connURI string, opts any) *Client {
 
 	log.Print("created new client and verified connection")
 
-	// Enable retry writes and reads by default (can be overridden in client options)
-	retryWrites := true
-	retryReads := true
-
-	return &Client{
-		client:         client,
-		defaultTimeout: defaultOperationTimeout,
-		retryWrites:    retryWrites,
-		retryReads:     retryReads,
-	}
+	c.client = client
+	return c
 }
 
 // getContext creates a context with the default timeout
@@ -107,11 +149,11 @@ func (c *Client) getContext() (context.Context, context.CancelFunc) {
 }
 
 // getCollection returns a collection and validates input
-func (c *Client) getCollection(database, collection string) (*mongo.Collection, error) {
+func (c *Client) getCollection(database, collection string, opts ...*options.CollectionOptions) (*mongo.Collection, error) {
 	if err := validateDatabaseAndCollection(database, collection); err != nil {
 		return nil, err
 	}
-	return c.client.Database(database).Collection(collection), nil
+	return c.client.Database(database).Collection(collection, opts...), nil
 }
 
 // validateDatabaseAndCollection validates database and collection names
@@ -132,63 +174,60 @@ func validateDatabaseAndCollection(database, collection string) error {
 	return nil
 }
 
-func (c *Client) Insert(database string, collection string, doc any) error {
+// Insert inserts a document, or the next document of a generator. Options:
+// unacknowledged, to send it with w:0 (true) or w:1 (false) instead of the
+// client's write concern.
+func (c *Client) Insert(database string, collection string, doc any, opts map[string]any) error {
 	if doc == nil {
 		return errDocumentNil
 	}
-
-	col, err := c.getCollection(database, collection)
-	if err != nil {
-		log.Printf(errValidatingCollection, err)
-		return err
+	if g, ok := doc.(*Generator); ok {
+		doc = g.Next()
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
-	_, err = col.InsertOne(ctx, doc)
+	parsed, err := parseCommandOptions(opts, optUnacknowledged)
 	if err != nil {
-		log.Printf(errInsertingDocument, err)
 		return err
 	}
-	log.Print("Document inserted successfully")
-	return nil
-}
-
-func (c *Client) InsertMany(database string, collection string, docs []any) error {
-	if len(docs) == 0 {
-		return errDocsEmpty
-	}
 
-	col, err := c.getCollection(database, collection)
+	col, unacknowledged, err := c.writeCollection(database, collection, parsed.unacknowledged)
 	if err != nil {
 		log.Printf(errValidatingCollection, err)
 		return err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
-	_, err = col.InsertMany(ctx, docs)
+	err = c.do("insert", insertKind(doc), func(ctx context.Context) error {
+		_, err := col.InsertOne(ctx, doc)
+		return err
+	})
 	if err != nil {
-		log.Printf(errInsertingDocuments, err)
+		log.Printf(errInsertingDocument, err)
 		return err
 	}
+	c.recordWrite("insert", database, collection, unacknowledged, 1)
+	log.Print("Document inserted successfully")
 	return nil
 }
 
-func (c *Client) Upsert(database string, collection string, filter any, upsert any) error {
+// Upsert updates the document matching filter, inserting it if none exists.
+// It accepts the same options as UpdateOne; upsert is always enabled.
+func (c *Client) Upsert(database string, collection string, filter any, upsert any, opts map[string]any) error {
 	if filter == nil {
 		return errFilterNil
 	}
 
-	col, err := c.getCollection(database, collection)
+	parsed, err := parseUpdateOptions(opts)
 	if err != nil {
-		log.Printf(errValidatingCollection, err)
 		return err
 	}
+	enabled := true
+	parsed.upsert = &enabled
 
-	opts := options.Update().SetUpsert(true)
+	col, unacknowledged, err := c.writeCollection(database, collection, parsed.unacknowledged)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return err
+	}
 
 	updateDoc, err := prepareUpdateDocument(upsert)
 	if err != nil {
@@ -196,14 +235,15 @@ func (c *Client) Upsert(database string, collection string, filter any, upsert a
 		return err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
-	_, err = col.UpdateOne(ctx, filter, updateDoc, opts)
+	err = c.do("upsert", updateKind(filter, updateDoc), func(ctx context.Context) error {
+		_, err := col.UpdateOne(ctx, filter, updateDoc, parsed.update())
+		return err
+	})
 	if err != nil {
 		log.Printf(errPerformingUpsert, err)
 		return err
 	}
+	c.recordWrite("upsert", database, collection, unacknowledged, 0)
 	return nil
 }
 
@@ -226,13 +266,18 @@ const (
 	errDroppingCollection    = "Error while dropping the collection: %v"
 	errCountingDocuments     = "Error while counting documents: %v"
 	errFindingAndUpdating    = "Error while finding and updating document: %v"
+	errFindingAndReplacing   = "Error while finding and replacing document: %v"
+	errFindingAndDeleting    = "Error while finding and deleting document: %v"
+	errReplacingDocument     = "Error while replacing the document: %v"
+	errReplacingDocuments    = "Error while replacing the documents: %v"
 	errCreatingIndex         = "Error while creating index: %v"
 	errDroppingIndex         = "Error while dropping index: %v"
 	errListingIndexes        = "Error while listing indexes: %v"
-	errWatchingCollection    = "Error while watching collection: %v"
+	errWatchingChanges       = "Error while watching changes: %v"
 	errStartingSession       = "Error while starting session: %v"
 	errDroppingDatabase      = "Error while dropping database: %v"
 	errListingCollections    = "Error while listing collections: %v"
+	errOpeningChangeStream   = "Error while opening change stream: %v"
 )
 
 var (
@@ -244,6 +289,10 @@ var (
 	errIndexNameEmpty = errors.New("index name cannot be empty")
 	errKeysNil        = errors.New("index keys cannot be nil")
 	errDatabaseEmpty  = errors.New("database name cannot be empty")
+	errNoVU           = errors.New("operation requires a k6 VU context")
+	errReplacementOp  = errors.New("replacement document cannot contain update operators")
+
+	errSessionUnacknowledged = errors.New("option unacknowledged is not supported in a session")
 )
 
 func (c *Client) Find(database string, collection string, filter any, sort any, limit int64) ([]bson.M, error) {
@@ -257,23 +306,8 @@ func (c *Client) Find(database string, collection string, filter any, sort any,
 		return nil, err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
 	opts := options.Find().SetSort(sort).SetLimit(limit)
-	cur, err := col.Find(ctx, filter, opts)
-	if err != nil {
-		log.Printf(errFindingDocuments, err)
-		return nil, err
-	}
-	defer cur.Close(ctx)
-
-	var results []bson.M
-	if err = cur.All(ctx, &results); err != nil {
-		log.Printf(errDecodingDocuments, err)
-		return nil, err
-	}
-	return results, nil
+	return c.findAll("find", col, filter, opts)
 }
 
 // FindWithOptions provides advanced find options including batch size control
@@ -284,9 +318,6 @@ func (c *Client) FindWithOptions(database string, collection string, filter any,
 		return nil, err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
 	opts := options.Find()
 
 	// Apply options from map
@@ -306,48 +337,7 @@ func (c *Client) FindWithOptions(database string, collection string, filter any,
 		opts.SetProjection(projection)
 	}
 
-	cur, err := col.Find(ctx, filter, opts)
-	if err != nil {
-		log.Printf(errFindingDocuments, err)
-		return nil, err
-	}
-	defer cur.Close(ctx)
-
-	var results []bson.M
-	if err = cur.All(ctx, &results); err != nil {
-		log.Printf(errDecodingDocuments, err)
-		return nil, err
-	}
-	return results, nil
-}
-
-func (c *Client) Aggregate(database string, collection string, pipeline any) ([]bson.M, error) {
-	if pipeline == nil {
-		return nil, errPipelineNil
-	}
-
-	col, err := c.getCollection(database, collection)
-	if err != nil {
-		log.Printf(errValidatingCollection, err)
-		return nil, err
-	}
-
-	ctx, cancel := c.getContext()
-	defer cancel()
-
-	cur, err := col.Aggregate(ctx, pipeline)
-	if err != nil {
-		log.Printf(errAggregating, err)
-		return nil, err
-	}
-	defer cur.Close(ctx)
-
-	var results []bson.M
-	if err = cur.All(ctx, &results); err != nil {
-		log.Printf(errDecodingDocuments, err)
-		return nil, err
-	}
-	return results, nil
+	return c.findAll("find", col, filter, opts)
 }
 
 func (c *Client) FindOne(database string, collection string, filter any) (bson.M, error) {
@@ -357,11 +347,10 @@ func (c *Client) FindOne(database string, collection string, filter any) (bson.M
 		return nil, err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
 	var result bson.M
-	err = col.FindOne(ctx, filter).Decode(&result)
+	err = c.do("findOne", opRead, func(ctx context.Context) error {
+		return col.FindOne(ctx, filter).Decode(&result)
+	})
 	if err != nil {
 		log.Printf(errFindingDocument, err)
 		return nil, err
@@ -370,12 +359,20 @@ func (c *Client) FindOne(database string, collection string, filter any) (bson.M
 	return result, nil
 }
 
-func (c *Client) UpdateOne(database string, collection string, filter any, data any) error {
+// UpdateOne updates the first document matching filter. Options:
+// arrayFilters, hint, collation, let, comment, upsert,
+// bypassDocumentValidation and unacknowledged (see Insert).
+func (c *Client) UpdateOne(database string, collection string, filter any, data any, opts map[string]any) error {
 	if filter == nil {
 		return errFilterNil
 	}
 
-	col, err := c.getCollection(database, collection)
+	parsed, err := parseUpdateOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	col, unacknowledged, err := c.writeCollection(database, collection, parsed.unacknowledged)
 	if err != nil {
 		log.Printf(errValidatingCollection, err)
 		return err
@@ -387,24 +384,32 @@ func (c *Client) UpdateOne(database string, collection string, filter any, data
 		return err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
-	_, err = col.UpdateOne(ctx, filter, update)
+	err = c.do("updateOne", updateKind(filter, update), func(ctx context.Context) error {
+		_, err := col.UpdateOne(ctx, filter, update, parsed.update())
+		return err
+	})
 	if err != nil {
 		log.Printf(errUpdatingDocument, err)
 		return err
 	}
+	c.recordWrite("updateOne", database, collection, unacknowledged, 0)
 
 	return nil
 }
 
-func (c *Client) UpdateMany(database string, collection string, filter any, data any) error {
+// UpdateMany updates every document matching filter. It accepts the same
+// options as UpdateOne.
+func (c *Client) UpdateMany(database string, collection string, filter any, data any, opts map[string]any) error {
 	if filter == nil {
 		return errFilterNil
 	}
 
-	col, err := c.getCollection(database, collection)
+	parsed, err := parseUpdateOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	col, unacknowledged, err := c.writeCollection(database, collection, parsed.unacknowledged)
 	if err != nil {
 		log.Printf(errValidatingCollection, err)
 		return err
@@ -416,14 +421,15 @@ func (c *Client) UpdateMany(database string, collection string, filter any, data
 		return err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
-	_, err = col.UpdateMany(ctx, filter, update)
+	err = c.do("updateMany", updateManyKind(update), func(ctx context.Context) error {
+		_, err := col.UpdateMany(ctx, filter, update, parsed.update())
+		return err
+	})
 	if err != nil {
 		log.Printf(errUpdatingDocuments, err)
 		return err
 	}
+	c.recordWrite("updateMany", database, collection, unacknowledged, 0)
 
 	return nil
 }
@@ -435,37 +441,52 @@ func (c *Client) FindAll(database string, collection string) ([]bson.M, error) {
 		return nil, err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
 	// Use an empty filter to match all documents
-	cur, err := col.Find(ctx, bson.D{})
-	if err != nil {
-		log.Printf(errFindingDocuments, err)
-		return nil, err
-	}
-	defer cur.Close(ctx)
+	return c.findAll("findAll", col, bson.D{}, options.Find())
+}
 
+// findAll runs a find and decodes every result, retrying the whole read as
+// the retry policy allows.
+func (c *Client) findAll(op string, col *mongo.Collection, filter any, opts *options.FindOptions) ([]bson.M, error) {
 	var results []bson.M
-	if err = cur.All(ctx, &results); err != nil {
-		log.Printf(errDecodingDocuments, err)
+	err := c.do(op, opRead, func(ctx context.Context) error {
+		cur, err := col.Find(ctx, filter, opts)
+		if err != nil {
+			log.Printf(errFindingDocuments, err)
+			return err
+		}
+		defer cur.Close(ctx)
+
+		if err = cur.All(ctx, &results); err != nil {
+			log.Printf(errDecodingDocuments, err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
-
 	return results, nil
 }
 
-func (c *Client) DeleteOne(database string, collection string, filter any) error {
+// DeleteOne deletes the first document matching filter. Options: hint,
+// collation, let and comment.
+func (c *Client) DeleteOne(database string, collection string, filter any, opts map[string]any) error {
+	parsed, err := parseDeleteOptions(opts)
+	if err != nil {
+		return err
+	}
+
 	col, err := c.getCollection(database, collection)
 	if err != nil {
 		log.Printf(errValidatingCollection, err)
 		return err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
-	_, err = col.DeleteOne(ctx, filter)
+	err = c.do("deleteOne", deleteOneKind(filter), func(ctx context.Context) error {
+		_, err := col.DeleteOne(ctx, filter, parsed.delete())
+		return err
+	})
 	if err != nil {
 		log.Printf(errDeletingDocument, err)
 		return err
@@ -474,17 +495,24 @@ func (c *Client) DeleteOne(database string, collection string, filter any) error
 	return nil
 }
 
-func (c *Client) DeleteMany(database string, collection string, filter any) error {
+// DeleteMany deletes every document matching filter. It accepts the same
+// options as DeleteOne.
+func (c *Client) DeleteMany(database string, collection string, filter any, opts map[string]any) error {
+	parsed, err := parseDeleteOptions(opts)
+	if err != nil {
+		return err
+	}
+
 	col, err := c.getCollection(database, collection)
 	if err != nil {
 		log.Printf(errValidatingCollection, err)
 		return err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
-	_, err = col.DeleteMany(ctx, filter)
+	err = c.do("deleteMany", opIdempotentWrite, func(ctx context.Context) error {
+		_, err := col.DeleteMany(ctx, filter, parsed.delete())
+		return err
+	})
 	if err != nil {
 		log.Printf(errDeletingDocuments, err)
 		return err
@@ -504,10 +532,12 @@ func (c *Client) Distinct(database string, collection string, field string, filt
 		return nil, err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
-	result, err := col.Distinct(ctx, field, filter)
+	var result []any
+	err = c.do("distinct", opRead, func(ctx context.Context) error {
+		var err error
+		result, err = col.Distinct(ctx, field, filter)
+		return err
+	})
 	if err != nil {
 		log.Printf(errGettingDistinctValues, err)
 		return nil, err
@@ -523,29 +553,40 @@ func (c *Client) DropCollection(database string, collection string) error {
 		return err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
-	err = col.Drop(ctx)
+	err = c.do("dropCollection", opIdempotentWrite, col.Drop)
 	if err != nil {
 		log.Printf(errDroppingCollection, err)
 		return err
 	}
+	c.forgetWrites(database, collection)
 
 	return nil
 }
 
-func (c *Client) CountDocuments(database string, collection string, filter any) (int64, error) {
+// CountDocuments counts the documents matching filter. Options: skip, limit,
+// hint, collation, comment and maxTime.
+func (c *Client) CountDocuments(database string, collection string, filter any, opts map[string]any) (int64, error) {
+	parsed, err := parseCommandOptions(opts, optCollation, optComment, optHint, optLimit, optMaxTime, optSkip)
+	if err != nil {
+		return 0, err
+	}
+
 	col, err := c.getCollection(database, collection)
 	if err != nil {
 		log.Printf(errValidatingCollection, err)
 		return 0, err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
+	if filter == nil {
+		filter = bson.D{}
+	}
 
-	count, err := col.CountDocuments(ctx, filter)
+	var count int64
+	err = c.do("countDocuments", opRead, func(ctx context.Context) error {
+		var err error
+		count, err = col.CountDocuments(ctx, filter, parsed.count())
+		return err
+	})
 	if err != nil {
 		log.Printf(errCountingDocuments, err)
 		return 0, err
@@ -553,28 +594,33 @@ func (c *Client) CountDocuments(database string, collection string, filter any)
 	return count, nil
 }
 
-func (c *Client) FindOneAndUpdate(database string, collection string, filter any, update any) (bson.M, error) {
-	if filter == nil {
-		return nil, errFilterNil
+// EstimatedDocumentCount returns the collection's document count from its
+// metadata, which is much cheaper than CountDocuments but ignores filters and
+// may be inaccurate after unclean shutdowns or with orphaned documents in
+// sharded clusters. Options: comment and maxTime.
+func (c *Client) EstimatedDocumentCount(database string, collection string, opts map[string]any) (int64, error) {
+	parsed, err := parseCommandOptions(opts, optComment, optMaxTime)
+	if err != nil {
+		return 0, err
 	}
 
 	col, err := c.getCollection(database, collection)
 	if err != nil {
 		log.Printf(errValidatingCollection, err)
-		return nil, err
+		return 0, err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	var out bson.M
-	err = col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&out)
+	var count int64
+	err = c.do("estimatedDocumentCount", opRead, func(ctx context.Context) error {
+		var err error
+		count, err = col.EstimatedDocumentCount(ctx, parsed.estimatedCount())
+		return err
+	})
 	if err != nil {
-		log.Printf(errFindingAndUpdating, err)
-		return nil, err
+		log.Printf(errCountingDocuments, err)
+		return 0, err
 	}
-	return out, nil
+	return count, nil
 }
 
 func (c *Client) Disconnect() error {
@@ -590,26 +636,42 @@ func (c *Client) Disconnect() error {
 	return nil
 }
 
-// BulkWrite executes multiple write operations in a single call
-func (c *Client) BulkWrite(database string, collection string, operations []mongo.WriteModel) (int64, int64, error) {
+// BulkWrite executes multiple write operations in a single call. It accepts
+// the same options as Insert; unacknowledged bulk writes report zero
+// inserted and modified documents.
+func (c *Client) BulkWrite(database string, collection string, operations []mongo.WriteModel, opts map[string]any) (int64, int64, error) {
 	if len(operations) == 0 {
 		return 0, 0, errors.New("operations array cannot be empty")
 	}
 
-	col, err := c.getCollection(database, collection)
+	parsed, err := parseCommandOptions(opts, optUnacknowledged)
 	if err != nil {
-		log.Printf(errValidatingCollection, err)
 		return 0, 0, err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
+	col, unacknowledged, err := c.writeCollection(database, collection, parsed.unacknowledged)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return 0, 0, err
+	}
 
-	result, err := col.BulkWrite(ctx, operations)
+	var result *mongo.BulkWriteResult
+	err = c.do("bulkWrite", opWrite, func(ctx context.Context) error {
+		var err error
+		result, err = col.BulkWrite(ctx, operations)
+		return err
+	})
 	if err != nil {
 		log.Printf("Error while performing bulk write: %v", err)
 		return 0, 0, err
 	}
+	inserts := 0
+	for _, model := range operations {
+		if _, ok := model.(*mongo.InsertOneModel); ok {
+			inserts++
+		}
+	}
+	c.recordWrite("bulkWrite", database, collection, unacknowledged, inserts)
 
 	return result.InsertedCount, result.ModifiedCount, nil
 }
@@ -626,9 +688,6 @@ func (c *Client) CreateIndex(database string, collection string, keys any, index
 		return "", err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
 	opts := options.Index()
 	if indexOptions != nil {
 		if unique, ok := indexOptions["unique"].(bool); ok {
@@ -650,7 +709,12 @@ func (c *Client) CreateIndex(database string, collection string, keys any, index
 		Options: opts,
 	}
 
-	name, err := col.Indexes().CreateOne(ctx, model)
+	var name string
+	err = c.do("createIndex", opIdempotentWrite, func(ctx context.Context) error {
+		var err error
+		name, err = col.Indexes().CreateOne(ctx, model)
+		return err
+	})
 	if err != nil {
 		log.Printf(errCreatingIndex, err)
 		return "", err
@@ -671,10 +735,10 @@ func (c *Client) DropIndex(database string, collection string, name string) erro
 		return err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
-	_, err = col.Indexes().DropOne(ctx, name)
+	err = c.do("dropIndex", opIdempotentWrite, func(ctx context.Context) error {
+		_, err := col.Indexes().DropOne(ctx, name)
+		return err
+	})
 	if err != nil {
 		log.Printf(errDroppingIndex, err)
 		return err
@@ -691,19 +755,22 @@ func (c *Client) ListIndexes(database string, collection string) ([]bson.M, erro
 		return nil, err
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
+	var results []bson.M
+	err = c.do("listIndexes", opRead, func(ctx context.Context) error {
+		cursor, err := col.Indexes().List(ctx)
+		if err != nil {
+			log.Printf(errListingIndexes, err)
+			return err
+		}
+		defer cursor.Close(ctx)
 
-	cursor, err := col.Indexes().List(ctx)
+		if err = cursor.All(ctx, &results); err != nil {
+			log.Printf(errDecodingDocuments, err)
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		log.Printf(errListingIndexes, err)
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	var results []bson.M
-	if err = cursor.All(ctx, &results); err != nil {
-		log.Printf(errDecodingDocuments, err)
 		return nil, err
 	}
 	return results, nil
@@ -718,35 +785,7 @@ func (c *Client) Watch(database string, collection string, pipeline any, duratio
 		return nil, err
 	}
 
-	if pipeline == nil {
-		pipeline = []bson.M{}
-	}
-
-	watchDuration := time.Duration(durationMs) * time.Millisecond
-	if watchDuration <= 0 {
-		watchDuration = 5 * time.Second
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), watchDuration)
-	defer cancel()
-
-	cs, err := col.Watch(ctx, pipeline)
-	if err != nil {
-		log.Printf(errWatchingCollection, err)
-		return nil, err
-	}
-	defer cs.Close(ctx)
-
-	var results []bson.M
-	for cs.Next(ctx) {
-		var event bson.M
-		if err := cs.Decode(&event); err != nil {
-			continue
-		}
-		results = append(results, event)
-	}
-
-	return results, nil
+	return c.collectChangeEvents(col, pipeline, durationMs)
 }
 
 // StartSession creates a new session for transaction support.
@@ -761,23 +800,44 @@ func (c *Client) StartSession() (*Session, error) {
 
 // StartTransaction starts a new transaction on the session.
 func (s *Session) StartTransaction() error {
-	return s.session.StartTransaction()
+	if err := s.session.StartTransaction(); err != nil {
+		return err
+	}
+	s.inTransaction = true
+	return nil
 }
 
-// CommitTransaction commits the active transaction.
+// CommitTransaction commits the active transaction. Commits that fail with
+// an UnknownTransactionCommitResult label are retried by the retry policy.
 func (s *Session) CommitTransaction() error {
-	ctx, cancel := s.client.getContext()
-	defer cancel()
-	return s.session.CommitTransaction(ctx)
+	err := s.client.do("commitTransaction", opIdempotentWrite, s.session.CommitTransaction)
+	if err == nil {
+		s.inTransaction = false
+	}
+	return err
 }
 
 // AbortTransaction aborts the active transaction.
 func (s *Session) AbortTransaction() error {
+	s.inTransaction = false
 	ctx, cancel := s.client.getContext()
 	defer cancel()
 	return s.session.AbortTransaction(ctx)
 }
 
+// do runs fn in the session. Operations inside a transaction are not retried
+// one by one: an error aborts the transaction, which must be run again as a
+// whole.
+func (s *Session) do(op string, kind opKind, fn func(sc mongo.SessionContext) error) error {
+	withSession := func(ctx context.Context) error {
+		return mongo.WithSession(ctx, s.session, fn)
+	}
+	if s.inTransaction {
+		return s.client.attempt(kind, withSession)
+	}
+	return s.client.do(op, kind, withSession)
+}
+
 // EndSession ends the session and releases resources.
 func (s *Session) EndSession() {
 	s.session.EndSession(context.Background())
@@ -792,9 +852,7 @@ func (s *Session) Insert(database string, collection string, doc any) error {
 	if err != nil {
 		return err
 	}
-	ctx, cancel := s.client.getContext()
-	defer cancel()
-	return mongo.WithSession(ctx, s.session, func(sc mongo.SessionContext) error {
+	return s.do("insert", insertKind(doc), func(sc mongo.SessionContext) error {
 		_, err := col.InsertOne(sc, doc)
 		return err
 	})
@@ -806,10 +864,8 @@ func (s *Session) FindOne(database string, collection string, filter any) (bson.
 	if err != nil {
 		return nil, err
 	}
-	ctx, cancel := s.client.getContext()
-	defer cancel()
 	var result bson.M
-	err = mongo.WithSession(ctx, s.session, func(sc mongo.SessionContext) error {
+	err = s.do("findOne", opRead, func(sc mongo.SessionContext) error {
 		return col.FindOne(sc, filter).Decode(&result)
 	})
 	if err != nil {
@@ -819,10 +875,18 @@ func (s *Session) FindOne(database string, collection string, filter any) (bson.
 }
 
 // UpdateOne updates a single document within the session's transaction context.
-func (s *Session) UpdateOne(database string, collection string, filter any, data any) error {
+// It accepts the same options as Client.UpdateOne.
+func (s *Session) UpdateOne(database string, collection string, filter any, data any, opts map[string]any) error {
 	if filter == nil {
 		return errFilterNil
 	}
+	parsed, err := parseUpdateOptions(opts)
+	if err != nil {
+		return err
+	}
+	if parsed.unacknowledged != nil {
+		return errSessionUnacknowledged
+	}
 	col, err := s.client.getCollection(database, collection)
 	if err != nil {
 		return err
@@ -831,24 +895,25 @@ func (s *Session) UpdateOne(database string, collection string, filter any, data
 	if err != nil {
 		return err
 	}
-	ctx, cancel := s.client.getContext()
-	defer cancel()
-	return mongo.WithSession(ctx, s.session, func(sc mongo.SessionContext) error {
-		_, err := col.UpdateOne(sc, filter, update)
+	return s.do("updateOne", updateKind(filter, update), func(sc mongo.SessionContext) error {
+		_, err := col.UpdateOne(sc, filter, update, parsed.update())
 		return err
 	})
 }
 
 // DeleteOne deletes a single document within the session's transaction context.
-func (s *Session) DeleteOne(database string, collection string, filter any) error {
+// It accepts the same options as Client.DeleteOne.
+func (s *Session) DeleteOne(database string, collection string, filter any, opts map[string]any) error {
+	parsed, err := parseDeleteOptions(opts)
+	if err != nil {
+		return err
+	}
 	col, err := s.client.getCollection(database, collection)
 	if err != nil {
 		return err
 	}
-	ctx, cancel := s.client.getContext()
-	defer cancel()
-	return mongo.WithSession(ctx, s.session, func(sc mongo.SessionContext) error {
-		_, err := col.DeleteOne(sc, filter)
+	return s.do("deleteOne", deleteOneKind(filter), func(sc mongo.SessionContext) error {
+		_, err := col.DeleteOne(sc, filter, parsed.delete())
 		return err
 	})
 }
@@ -859,14 +924,12 @@ func (c *Client) DropDatabase(database string) error {
 		return errDatabaseEmpty
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
-
-	err := c.client.Database(database).Drop(ctx)
+	err := c.do("dropDatabase", opIdempotentWrite, c.client.Database(database).Drop)
 	if err != nil {
 		log.Printf(errDroppingDatabase, err)
 		return err
 	}
+	c.forgetWrites(database, "")
 	log.Printf("Database dropped successfully: %s", database)
 	return nil
 }
@@ -877,19 +940,22 @@ func (c *Client) ListCollections(database string) ([]bson.M, error) {
 		return nil, errDatabaseEmpty
 	}
 
-	ctx, cancel := c.getContext()
-	defer cancel()
+	var results []bson.M
+	err := c.do("listCollections", opRead, func(ctx context.Context) error {
+		cursor, err := c.client.Database(database).ListCollections(ctx, bson.D{})
+		if err != nil {
+			log.Printf(errListingCollections, err)
+			return err
+		}
+		defer cursor.Close(ctx)
 
-	cursor, err := c.client.Database(database).ListCollections(ctx, bson.D{})
+		if err = cursor.All(ctx, &results); err != nil {
+			log.Printf(errDecodingDocuments, err)
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		log.Printf(errListingCollections, err)
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	var results []bson.M
-	if err = cursor.All(ctx, &results); err != nil {
-		log.Printf(errDecodingDocuments, err)
 		return nil, err
 	}
 	return results, nil
@@ -0,0 +1,573 @@
+package xk6_mongo
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+)
+
+// normalizeOptionKey folds camelCase, snake_case and kebab-case spellings of
+// an option name into a single comparable form.
+func normalizeOptionKey(key string) string {
+	key = strings.ToLower(key)
+	return strings.NewReplacer("_", "", "-", "", " ", "").Replace(key)
+}
+
+// lookupOption returns the value stored under name in raw, accepting any
+// spelling that normalizes to the same key (e.g. batchSize and batch_size).
+func lookupOption(raw map[string]any, name string) (any, bool) {
+	if raw == nil {
+		return nil, false
+	}
+	if value, ok := raw[name]; ok {
+		return value, true
+	}
+	want := normalizeOptionKey(name)
+	for key, value := range raw {
+		if normalizeOptionKey(key) == want {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// toInt64 converts the numeric types produced by the JS runtime to int64.
+func toInt64(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float32:
+		return int64(v), float64(v) == math.Trunc(float64(v))
+	case float64:
+		return int64(v), v == math.Trunc(v)
+	}
+	return 0, false
+}
+
+// intOption reads an integer option, failing on values of the wrong type.
+func intOption(raw map[string]any, name string) (int64, bool, error) {
+	value, ok := lookupOption(raw, name)
+	if !ok || value == nil {
+		return 0, false, nil
+	}
+	n, ok := toInt64(value)
+	if !ok {
+		return 0, false, fmt.Errorf("option %s must be an integer, got %T", name, value)
+	}
+	return n, true, nil
+}
+
+// durationOption reads an option expressed in milliseconds.
+func durationOption(raw map[string]any, name string) (time.Duration, bool, error) {
+	ms, ok, err := intOption(raw, name)
+	if err != nil || !ok {
+		return 0, ok, err
+	}
+	if ms < 0 {
+		return 0, false, fmt.Errorf("option %s cannot be negative", name)
+	}
+	return time.Duration(ms) * time.Millisecond, true, nil
+}
+
+// stringOption reads a string option, failing on values of the wrong type.
+func stringOption(raw map[string]any, name string) (string, bool, error) {
+	value, ok := lookupOption(raw, name)
+	if !ok || value == nil {
+		return "", false, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", false, fmt.Errorf("option %s must be a string, got %T", name, value)
+	}
+	return s, true, nil
+}
+
+// boolOption reads a boolean option, failing on values of the wrong type.
+func boolOption(raw map[string]any, name string) (bool, bool, error) {
+	value, ok := lookupOption(raw, name)
+	if !ok || value == nil {
+		return false, false, nil
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, false, fmt.Errorf("option %s must be a boolean, got %T", name, value)
+	}
+	return b, true, nil
+}
+
+// floatOption reads a numeric option, failing on values of the wrong type.
+func floatOption(raw map[string]any, name string) (float64, bool, error) {
+	value, ok := lookupOption(raw, name)
+	if !ok || value == nil {
+		return 0, false, nil
+	}
+	f, ok := toFloat64(value)
+	if !ok {
+		return 0, false, fmt.Errorf("option %s must be a number, got %T", name, value)
+	}
+	return f, true, nil
+}
+
+// toFloat64 converts a JS number to a float64.
+func toFloat64(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	}
+	if n, ok := toInt64(value); ok {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// stringsOption reads an array of strings, failing on values of the wrong type.
+func stringsOption(raw map[string]any, name string) ([]string, bool, error) {
+	value, ok := lookupOption(raw, name)
+	if !ok || value == nil {
+		return nil, false, nil
+	}
+	if s, ok := value.([]string); ok {
+		return s, true, nil
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return nil, false, fmt.Errorf("option %s must be an array of strings, got %T", name, value)
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("option %s must be an array of strings, got element %T", name, item)
+		}
+		out = append(out, s)
+	}
+	return out, true, nil
+}
+
+// batchSizeOption reads a cursor batch size, which the server takes as int32.
+func batchSizeOption(raw map[string]any) (int32, bool, error) {
+	batchSize, ok, err := intOption(raw, "batchSize")
+	if err != nil || !ok {
+		return 0, ok, err
+	}
+	if batchSize <= 0 || batchSize > math.MaxInt32 {
+		return 0, false, fmt.Errorf("option batchSize out of range: %d", batchSize)
+	}
+	return int32(batchSize), true, nil
+}
+
+// Option names shared by the CRUD helpers.
+const (
+	optAllowDiskUse             = "allowDiskUse"
+	optArrayFilters             = "arrayFilters"
+	optBatchSize                = "batchSize"
+	optBypassDocumentValidation = "bypassDocumentValidation"
+	optCollation                = "collation"
+	optComment                  = "comment"
+	optHint                     = "hint"
+	optIgnoreDuplicates         = "ignoreDuplicates"
+	optLet                      = "let"
+	optLimit                    = "limit"
+	optMaxTime                  = "maxTime"
+	optOrdered                  = "ordered"
+	optProjection               = "projection"
+	optReadConcern              = "readConcern"
+	optReturnDocument           = "returnDocument"
+	optSkip                     = "skip"
+	optSort                     = "sort"
+	optUnacknowledged           = "unacknowledged"
+	optUpsert                   = "upsert"
+)
+
+// commandOptions holds the options shared by the CRUD commands, parsed from
+// the JS options object.
+type commandOptions struct {
+	allowDiskUse             *bool
+	arrayFilters             *options.ArrayFilters
+	batchSize                *int32
+	bypassDocumentValidation *bool
+	collation                *options.Collation
+	comment                  any
+	hint                     any
+	ignoreDuplicates         *bool
+	let                      any
+	limit                    *int64
+	maxTime                  *time.Duration
+	ordered                  *bool
+	projection               any
+	readConcern              *readconcern.ReadConcern
+	returnDocument           *options.ReturnDocument
+	skip                     *int64
+	sort                     any
+	unacknowledged           *bool
+	upsert                   *bool
+}
+
+// parseCommandOptions parses raw, rejecting any option not listed in allowed
+// so that typos fail loudly instead of being silently ignored.
+func parseCommandOptions(raw map[string]any, allowed ...string) (*commandOptions, error) {
+	if err := checkAllowedOptions(raw, allowed...); err != nil {
+		return nil, err
+	}
+
+	out := &commandOptions{}
+	var err error
+
+	if out.allowDiskUse, err = optionalBool(raw, optAllowDiskUse); err != nil {
+		return nil, err
+	}
+	if value, ok := lookupOption(raw, optArrayFilters); ok && value != nil {
+		filters, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("option %s must be an array, got %T", optArrayFilters, value)
+		}
+		out.arrayFilters = &options.ArrayFilters{Filters: filters}
+	}
+	batchSize, ok, err := batchSizeOption(raw)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		out.batchSize = &batchSize
+	}
+	if out.bypassDocumentValidation, err = optionalBool(raw, optBypassDocumentValidation); err != nil {
+		return nil, err
+	}
+	if value, ok := lookupOption(raw, optCollation); ok && value != nil {
+		if out.collation, err = collationFromValue(value); err != nil {
+			return nil, err
+		}
+	}
+	out.comment, _ = lookupOption(raw, optComment)
+	out.hint, _ = lookupOption(raw, optHint)
+	if out.ignoreDuplicates, err = optionalBool(raw, optIgnoreDuplicates); err != nil {
+		return nil, err
+	}
+	out.let, _ = lookupOption(raw, optLet)
+	if out.limit, err = optionalNonNegativeInt(raw, optLimit); err != nil {
+		return nil, err
+	}
+	maxTime, ok, err := durationOption(raw, optMaxTime)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		out.maxTime = &maxTime
+	}
+	if out.ordered, err = optionalBool(raw, optOrdered); err != nil {
+		return nil, err
+	}
+	out.projection, _ = lookupOption(raw, optProjection)
+	if value, ok := lookupOption(raw, optReadConcern); ok && value != nil {
+		if out.readConcern, err = readConcernFromValue(value); err != nil {
+			return nil, err
+		}
+	}
+	returnDocument, ok, err := stringOption(raw, optReturnDocument)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		rd, err := parseReturnDocument(returnDocument)
+		if err != nil {
+			return nil, err
+		}
+		out.returnDocument = &rd
+	}
+	if out.skip, err = optionalNonNegativeInt(raw, optSkip); err != nil {
+		return nil, err
+	}
+	out.sort, _ = lookupOption(raw, optSort)
+	if out.unacknowledged, err = optionalBool(raw, optUnacknowledged); err != nil {
+		return nil, err
+	}
+	if out.upsert, err = optionalBool(raw, optUpsert); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// checkAllowedOptions fails on the first key of raw that is not in allowed.
+func checkAllowedOptions(raw map[string]any, allowed ...string) error {
+	known := make(map[string]struct{}, len(allowed))
+	for _, name := range allowed {
+		known[normalizeOptionKey(name)] = struct{}{}
+	}
+	for key := range raw {
+		if _, ok := known[normalizeOptionKey(key)]; !ok {
+			return fmt.Errorf("unsupported option %q", key)
+		}
+	}
+	return nil
+}
+
+func optionalBool(raw map[string]any, name string) (*bool, error) {
+	b, ok, err := boolOption(raw, name)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func optionalNonNegativeInt(raw map[string]any, name string) (*int64, error) {
+	n, ok, err := intOption(raw, name)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("option %s cannot be negative", name)
+	}
+	return &n, nil
+}
+
+func parseReturnDocument(value string) (options.ReturnDocument, error) {
+	switch strings.ToLower(value) {
+	case "before":
+		return options.Before, nil
+	case "after":
+		return options.After, nil
+	}
+	return 0, fmt.Errorf("option %s must be \"before\" or \"after\", got %q", optReturnDocument, value)
+}
+
+// readConcernFromValue accepts a read concern level either as a string or as
+// an object such as {level: "majority"}.
+func readConcernFromValue(value any) (*readconcern.ReadConcern, error) {
+	level, ok := value.(string)
+	if raw, isMap := value.(map[string]any); isMap {
+		if err := checkAllowedOptions(raw, "level"); err != nil {
+			return nil, fmt.Errorf("option %s: %w", optReadConcern, err)
+		}
+		var err error
+		if level, ok, err = stringOption(raw, "level"); err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("option %s must be a string or an object, got %T", optReadConcern, value)
+	}
+
+	switch level {
+	case "local", "majority", "available", "linearizable", "snapshot":
+		return &readconcern.ReadConcern{Level: level}, nil
+	}
+	return nil, fmt.Errorf("option %s has unknown level %q", optReadConcern, level)
+}
+
+// collationFromValue builds a collation from a JS object such as
+// {locale: "en", strength: 2}.
+func collationFromValue(value any) (*options.Collation, error) {
+	raw, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("option %s must be an object, got %T", optCollation, value)
+	}
+	if err := checkAllowedOptions(raw, "locale", "caseLevel", "caseFirst", "strength",
+		"numericOrdering", "alternate", "maxVariable", "normalization", "backwards"); err != nil {
+		return nil, fmt.Errorf("option %s: %w", optCollation, err)
+	}
+
+	collation := &options.Collation{}
+	var err error
+	if collation.Locale, _, err = stringOption(raw, "locale"); err != nil {
+		return nil, err
+	}
+	if collation.Locale == "" {
+		return nil, fmt.Errorf("option %s requires a locale", optCollation)
+	}
+	if collation.CaseLevel, _, err = boolOption(raw, "caseLevel"); err != nil {
+		return nil, err
+	}
+	if collation.CaseFirst, _, err = stringOption(raw, "caseFirst"); err != nil {
+		return nil, err
+	}
+	strength, _, err := intOption(raw, "strength")
+	if err != nil {
+		return nil, err
+	}
+	collation.Strength = int(strength)
+	if collation.NumericOrdering, _, err = boolOption(raw, "numericOrdering"); err != nil {
+		return nil, err
+	}
+	if collation.Alternate, _, err = stringOption(raw, "alternate"); err != nil {
+		return nil, err
+	}
+	if collation.MaxVariable, _, err = stringOption(raw, "maxVariable"); err != nil {
+		return nil, err
+	}
+	if collation.Normalization, _, err = boolOption(raw, "normalization"); err != nil {
+		return nil, err
+	}
+	if collation.Backwards, _, err = boolOption(raw, "backwards"); err != nil {
+		return nil, err
+	}
+	return collation, nil
+}
+
+// parseUpdateOptions parses the options accepted by the update helpers.
+func parseUpdateOptions(raw map[string]any) (*commandOptions, error) {
+	return parseCommandOptions(raw, optArrayFilters, optBypassDocumentValidation, optCollation,
+		optComment, optHint, optLet, optUnacknowledged, optUpsert)
+}
+
+func (o *commandOptions) update() *options.UpdateOptions {
+	opts := options.Update()
+	if o.arrayFilters != nil {
+		opts.SetArrayFilters(*o.arrayFilters)
+	}
+	if o.bypassDocumentValidation != nil {
+		opts.SetBypassDocumentValidation(*o.bypassDocumentValidation)
+	}
+	if o.collation != nil {
+		opts.SetCollation(o.collation)
+	}
+	if o.comment != nil {
+		opts.SetComment(o.comment)
+	}
+	if o.hint != nil {
+		opts.SetHint(o.hint)
+	}
+	if o.let != nil {
+		opts.SetLet(o.let)
+	}
+	if o.upsert != nil {
+		opts.SetUpsert(*o.upsert)
+	}
+	return opts
+}
+
+// parseDeleteOptions parses the options accepted by the delete helpers.
+func parseDeleteOptions(raw map[string]any) (*commandOptions, error) {
+	return parseCommandOptions(raw, optCollation, optComment, optHint, optLet)
+}
+
+func (o *commandOptions) delete() *options.DeleteOptions {
+	opts := options.Delete()
+	if o.collation != nil {
+		opts.SetCollation(o.collation)
+	}
+	if o.comment != nil {
+		opts.SetComment(o.comment)
+	}
+	if o.hint != nil {
+		opts.SetHint(o.hint)
+	}
+	if o.let != nil {
+		opts.SetLet(o.let)
+	}
+	return opts
+}
+
+func (o *commandOptions) count() *options.CountOptions {
+	opts := options.Count()
+	if o.collation != nil {
+		opts.SetCollation(o.collation)
+	}
+	if o.comment != nil {
+		opts.SetComment(stringComment(o.comment))
+	}
+	if o.hint != nil {
+		opts.SetHint(o.hint)
+	}
+	if o.limit != nil {
+		opts.SetLimit(*o.limit)
+	}
+	if o.maxTime != nil {
+		opts.SetMaxTime(*o.maxTime)
+	}
+	if o.skip != nil {
+		opts.SetSkip(*o.skip)
+	}
+	return opts
+}
+
+// stringComment turns a comment into the string that the count and aggregate
+// helpers require. Comments that are not strings, such as {tag: "x"}, are
+// sent as JSON so that the profiler shows them as written.
+func stringComment(comment any) string {
+	if s, ok := comment.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(comment)
+	if err != nil {
+		return fmt.Sprint(comment)
+	}
+	return string(data)
+}
+
+func (o *commandOptions) find() *options.FindOptions {
+	opts := options.Find()
+	if o.batchSize != nil {
+		opts.SetBatchSize(*o.batchSize)
+	}
+	if o.limit != nil {
+		opts.SetLimit(*o.limit)
+	}
+	if o.projection != nil {
+		opts.SetProjection(o.projection)
+	}
+	if o.skip != nil {
+		opts.SetSkip(*o.skip)
+	}
+	if o.sort != nil {
+		opts.SetSort(o.sort)
+	}
+	return opts
+}
+
+func (o *commandOptions) estimatedCount() *options.EstimatedDocumentCountOptions {
+	opts := options.EstimatedDocumentCount()
+	if o.comment != nil {
+		opts.SetComment(o.comment)
+	}
+	if o.maxTime != nil {
+		opts.SetMaxTime(*o.maxTime)
+	}
+	return opts
+}
+
+// parseAggregateOptions parses the options accepted by the aggregate helpers.
+func parseAggregateOptions(raw map[string]any) (*commandOptions, error) {
+	return parseCommandOptions(raw, optAllowDiskUse, optBatchSize, optBypassDocumentValidation,
+		optCollation, optComment, optHint, optLet, optMaxTime, optReadConcern)
+}
+
+func (o *commandOptions) aggregate() *options.AggregateOptions {
+	opts := options.Aggregate()
+	if o.allowDiskUse != nil {
+		opts.SetAllowDiskUse(*o.allowDiskUse)
+	}
+	if o.batchSize != nil {
+		opts.SetBatchSize(*o.batchSize)
+	}
+	if o.bypassDocumentValidation != nil {
+		opts.SetBypassDocumentValidation(*o.bypassDocumentValidation)
+	}
+	if o.collation != nil {
+		opts.SetCollation(o.collation)
+	}
+	if o.comment != nil {
+		opts.SetComment(stringComment(o.comment))
+	}
+	if o.hint != nil {
+		opts.SetHint(o.hint)
+	}
+	if o.let != nil {
+		opts.SetLet(o.let)
+	}
+	if o.maxTime != nil {
+		opts.SetMaxTime(*o.maxTime)
+	}
+	return opts
+}
@@ -0,0 +1,182 @@
+package xk6_mongo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/grafana/sobek"
+)
+
+// Rules checked by AssertPlan, used as the rule tag of mongo_plan_violations.
+const (
+	ruleForbidStages    = "forbidStages"
+	ruleMaxDocsExamined = "maxDocsExamined"
+	ruleMaxRatio        = "maxRatio"
+	ruleExpectIndex     = "expectIndex"
+)
+
+var errNoPlanRules = errors.New("assertPlan needs at least one of forbidStages, maxDocsExamined, maxRatio or expectIndex")
+
+// PlanAssertion is the outcome of AssertPlan.
+type PlanAssertion struct {
+	Passed     bool           `js:"passed"`
+	Violations []string       `js:"violations"`
+	Summary    ExplainSummary `js:"summary"`
+}
+
+// planRules holds the expectations parsed from the AssertPlan options.
+type planRules struct {
+	name            string
+	forbidStages    []string
+	maxDocsExamined *int64
+	maxRatio        *float64
+	expectIndex     string
+}
+
+type planViolation struct {
+	rule    string
+	message string
+}
+
+// AssertPlan explains query (see Explain) with execution statistics and
+// checks the plan against opts: forbidStages (e.g. ["COLLSCAN"]),
+// maxDocsExamined, maxRatio (documents examined per document returned) and
+// expectIndex. The result is recorded as a k6 check named opts.name and in
+// the mongo_plan_assertions and mongo_plan_violations metrics; a plan that
+// breaks a rule is reported, not thrown.
+func (c *Client) AssertPlan(database string, collection string, query sobek.Value, opts map[string]any) (*PlanAssertion, error) {
+	rules, err := parsePlanRules(database, collection, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	explained, err := c.Explain(database, collection, query, "executionStats")
+	if err != nil {
+		return nil, err
+	}
+
+	violations := rules.check(explained.Summary)
+	result := &PlanAssertion{
+		Passed:     len(violations) == 0,
+		Violations: make([]string, 0, len(violations)),
+		Summary:    explained.Summary,
+	}
+	for _, v := range violations {
+		result.Violations = append(result.Violations, v.message)
+	}
+	if !result.Passed {
+		log.Printf("Query plan check %q failed: %s", rules.name, strings.Join(result.Violations, "; "))
+	}
+
+	c.recordPlanAssertion(rules.name, database+"."+collection, violations)
+	return result, nil
+}
+
+func parsePlanRules(database, collection string, raw map[string]any) (*planRules, error) {
+	if err := checkAllowedOptions(raw, "name", ruleForbidStages, ruleMaxDocsExamined,
+		ruleMaxRatio, ruleExpectIndex); err != nil {
+		return nil, err
+	}
+
+	rules := &planRules{}
+	var err error
+	if rules.name, _, err = stringOption(raw, "name"); err != nil {
+		return nil, err
+	}
+	if rules.name == "" {
+		rules.name = fmt.Sprintf("query plan on %s.%s", database, collection)
+	}
+	if rules.forbidStages, _, err = stringsOption(raw, ruleForbidStages); err != nil {
+		return nil, err
+	}
+	if rules.maxDocsExamined, err = optionalNonNegativeInt(raw, ruleMaxDocsExamined); err != nil {
+		return nil, err
+	}
+	ratio, ok, err := floatOption(raw, ruleMaxRatio)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if ratio < 0 {
+			return nil, fmt.Errorf("option %s cannot be negative", ruleMaxRatio)
+		}
+		rules.maxRatio = &ratio
+	}
+	if rules.expectIndex, _, err = stringOption(raw, ruleExpectIndex); err != nil {
+		return nil, err
+	}
+
+	if len(rules.forbidStages) == 0 && rules.maxDocsExamined == nil && rules.maxRatio == nil && rules.expectIndex == "" {
+		return nil, errNoPlanRules
+	}
+	return rules, nil
+}
+
+// check returns every rule the plan summary breaks.
+func (r *planRules) check(summary ExplainSummary) []planViolation {
+	var violations []planViolation
+
+	for _, forbidden := range r.forbidStages {
+		for _, stage := range summary.Stages {
+			if strings.EqualFold(stage, forbidden) {
+				violations = append(violations, planViolation{ruleForbidStages,
+					fmt.Sprintf("plan uses forbidden stage %s", stage)})
+				break
+			}
+		}
+	}
+
+	if r.maxDocsExamined != nil && summary.DocsExamined > *r.maxDocsExamined {
+		violations = append(violations, planViolation{ruleMaxDocsExamined,
+			fmt.Sprintf("examined %d documents, limit is %d", summary.DocsExamined, *r.maxDocsExamined)})
+	}
+
+	if r.maxRatio != nil {
+		// Queries returning nothing are measured against one document so that
+		// a full scan for no results still fails.
+		returned := summary.NReturned
+		if returned < 1 {
+			returned = 1
+		}
+		if ratio := float64(summary.DocsExamined) / float64(returned); ratio > *r.maxRatio {
+			violations = append(violations, planViolation{ruleMaxRatio,
+				fmt.Sprintf("examined %.1f documents per document returned, limit is %.1f", ratio, *r.maxRatio)})
+		}
+	}
+
+	if r.expectIndex != "" {
+		used := false
+		for _, index := range summary.IndexesUsed {
+			if index == r.expectIndex {
+				used = true
+				break
+			}
+		}
+		if !used {
+			violations = append(violations, planViolation{ruleExpectIndex,
+				fmt.Sprintf("expected index %s, plan used %v", r.expectIndex, summary.IndexesUsed)})
+		}
+	}
+
+	return violations
+}
+
+// recordPlanAssertion emits the check result and the plan metrics.
+func (c *Client) recordPlanAssertion(name string, namespace string, violations []planViolation) {
+	passed := len(violations) == 0
+	c.emitCheck(name, passed, nil)
+
+	if c.metrics == nil {
+		return
+	}
+	value := 0.0
+	if passed {
+		value = 1
+	}
+	c.emit(c.metrics.planAssertions, value, map[string]string{"namespace": namespace, "assertion": name})
+	for _, v := range violations {
+		c.emit(c.metrics.planViolations, 1, map[string]string{"namespace": namespace, "rule": v.rule})
+	}
+}
@@ -0,0 +1,120 @@
+package xk6_mongo
+
+import (
+	"testing"
+
+	"github.com/grafana/sobek"
+)
+
+func TestParsePlanRules(t *testing.T) {
+	rules, err := parsePlanRules("shop", "orders", map[string]any{
+		"forbidStages": []any{"COLLSCAN"},
+		"maxRatio":     1.5,
+	})
+	if err != nil {
+		t.Fatalf("parsePlanRules failed: %v", err)
+	}
+	if rules.name != "query plan on shop.orders" {
+		t.Errorf("Unexpected default name %q", rules.name)
+	}
+	if *rules.maxRatio != 1.5 {
+		t.Errorf("Expected maxRatio 1.5, got %v", *rules.maxRatio)
+	}
+
+	invalid := map[string]map[string]any{
+		"no rules":              {"name": "empty"},
+		"unknown rule":          {"forbidStage": []any{"COLLSCAN"}},
+		"stages not strings":    {"forbidStages": []any{1}},
+		"negative docsExamined": {"maxDocsExamined": int64(-1)},
+		"negative ratio":        {"maxRatio": -0.5},
+		"index not string":      {"expectIndex": 1},
+	}
+	for name, raw := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parsePlanRules("shop", "orders", raw); err == nil {
+				t.Error("Expected error for invalid rules")
+			}
+		})
+	}
+}
+
+func TestPlanRulesCheck(t *testing.T) {
+	collscan := ExplainSummary{Stages: []string{"COLLSCAN"}, DocsExamined: 1000, NReturned: 10}
+	ixscan := ExplainSummary{Stages: []string{"FETCH", "IXSCAN"}, IndexesUsed: []string{"by_user"},
+		KeysExamined: 10, DocsExamined: 10, NReturned: 10}
+	maxDocs := int64(100)
+	ratio := 2.0
+
+	tests := []struct {
+		name    string
+		rules   planRules
+		summary ExplainSummary
+		want    []string
+	}{
+		{"forbidden stage", planRules{forbidStages: []string{"collscan"}}, collscan, []string{ruleForbidStages}},
+		{"allowed stage", planRules{forbidStages: []string{"COLLSCAN"}}, ixscan, nil},
+		{"too many docs", planRules{maxDocsExamined: &maxDocs}, collscan, []string{ruleMaxDocsExamined}},
+		{"ratio", planRules{maxRatio: &ratio}, collscan, []string{ruleMaxRatio}},
+		{"ratio with no results", planRules{maxRatio: &ratio}, ExplainSummary{DocsExamined: 3}, []string{ruleMaxRatio}},
+		{"expected index", planRules{expectIndex: "by_user"}, ixscan, nil},
+		{"missing index", planRules{expectIndex: "by_user"}, collscan, []string{ruleExpectIndex}},
+		{"every rule", planRules{forbidStages: []string{"COLLSCAN"}, maxDocsExamined: &maxDocs,
+			maxRatio: &ratio, expectIndex: "by_user"}, collscan,
+			[]string{ruleForbidStages, ruleMaxDocsExamined, ruleMaxRatio, ruleExpectIndex}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rules.check(tt.summary)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d violations, got %v", len(tt.want), got)
+			}
+			for i, v := range got {
+				if v.rule != tt.want[i] {
+					t.Errorf("Expected rule %s, got %s", tt.want[i], v.rule)
+				}
+			}
+		})
+	}
+}
+
+func TestAssertPlanValidation(t *testing.T) {
+	client := &Client{}
+	query := sobek.New().ToValue(map[string]any{"find": map[string]any{}})
+	_, err := client.AssertPlan("db", "col", query, nil)
+	if err != errNoPlanRules {
+		t.Errorf("Expected errNoPlanRules, got %v", err)
+	}
+}
+
+func TestRecordPlanAssertionMetrics(t *testing.T) {
+	client, samples := newMetricsTestClient(t)
+
+	client.recordPlanAssertion("orders by user", "shop.orders", []planViolation{
+		{rule: ruleForbidStages}, {rule: ruleMaxRatio},
+	})
+
+	counts := map[string]int{}
+	for _, sample := range collectSamples(samples) {
+		counts[sample.Metric.Name]++
+		switch sample.Metric.Name {
+		case "checks":
+			if name, _ := sample.Tags.Get("check"); name != "orders by user" {
+				t.Errorf("Expected check tag, got %q", name)
+			}
+			if sample.Value != 0 {
+				t.Errorf("Expected failed check, got %v", sample.Value)
+			}
+		case "mongo_plan_assertions":
+			if sample.Value != 0 {
+				t.Errorf("Expected failed assertion, got %v", sample.Value)
+			}
+		case "mongo_plan_violations":
+			if ns, _ := sample.Tags.Get("namespace"); ns != "shop.orders" {
+				t.Errorf("Expected namespace shop.orders, got %q", ns)
+			}
+		}
+	}
+	if counts["checks"] != 1 || counts["mongo_plan_assertions"] != 1 || counts["mongo_plan_violations"] != 2 {
+		t.Errorf("Unexpected samples %v", counts)
+	}
+}
@@ -0,0 +1,276 @@
+package xk6_mongo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	errSettingProfilingLevel = "Error while setting profiling level: %v"
+	errHarvestingProfile     = "Error while harvesting profile: %v"
+)
+
+var errProfilingLevel = errors.New("profiling level must be 0, 1 or 2")
+
+// ProfileGroup summarizes the profiled operations that share a namespace,
+// an operation type and a query shape.
+type ProfileGroup struct {
+	Namespace    string  `js:"namespace"`
+	Op           string  `js:"op"`
+	Shape        string  `js:"shape"`
+	ShapeHash    string  `js:"shapeHash"`
+	Count        int64   `js:"count"`
+	TotalMillis  int64   `js:"totalMillis"`
+	P50Millis    float64 `js:"p50Millis"`
+	P95Millis    float64 `js:"p95Millis"`
+	MaxMillis    int64   `js:"maxMillis"`
+	PlanSummary  string  `js:"planSummary"`
+	DocsExamined int64   `js:"docsExamined"`
+	KeysExamined int64   `js:"keysExamined"`
+	NReturned    int64   `js:"nReturned"`
+	// Example is the command of the slowest operation in the group.
+	Example bson.M `js:"example"`
+
+	millis []int64
+	plans  map[string]int64
+}
+
+// SetProfilingLevel sets the database profiler level: 0 (off), 1 (slow
+// operations only) or 2 (every operation). Options: slowms, sampleRate and
+// filter. It returns the server's reply, whose "was" field holds the
+// previous level.
+func (c *Client) SetProfilingLevel(database string, level int64, opts map[string]any) (bson.M, error) {
+	if level < 0 || level > 2 {
+		return nil, errProfilingLevel
+	}
+	if err := checkAllowedOptions(opts, "slowms", "sampleRate", "filter"); err != nil {
+		return nil, err
+	}
+
+	cmd := bson.D{{Key: "profile", Value: level}}
+	slowms, ok, err := intOption(opts, "slowms")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		cmd = append(cmd, bson.E{Key: "slowms", Value: slowms})
+	}
+	sampleRate, ok, err := floatOption(opts, "sampleRate")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if sampleRate < 0 || sampleRate > 1 {
+			return nil, fmt.Errorf("option sampleRate must be between 0 and 1, got %v", sampleRate)
+		}
+		cmd = append(cmd, bson.E{Key: "sampleRate", Value: sampleRate})
+	}
+	if filter, ok := lookupOption(opts, "filter"); ok && filter != nil {
+		cmd = append(cmd, bson.E{Key: "filter", Value: filter})
+	}
+
+	reply, err := c.runCommand(database, cmd)
+	if err != nil {
+		log.Printf(errSettingProfilingLevel, err)
+		return nil, err
+	}
+	return reply, nil
+}
+
+// HarvestProfile reads system.profile entries recorded at or after since (a
+// Date, milliseconds since the epoch or an ISO-8601 string; null reads every
+// entry) and groups them by namespace, operation and query shape, slowest
+// groups first.
+func (c *Client) HarvestProfile(database string, since any) ([]*ProfileGroup, error) {
+	filter := bson.M{"ns": bson.M{"$ne": database + ".system.profile"}}
+	if since != nil {
+		from, ok := snapshotTime(since)
+		if !ok {
+			return nil, fmt.Errorf("unsupported profile start time %v", since)
+		}
+		filter["ts"] = bson.M{"$gte": from}
+	}
+
+	col, err := c.getCollection(database, "system.profile")
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	ctx, cancel := c.getContext()
+	defer cancel()
+
+	cur, err := col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "ts", Value: 1}}))
+	if err != nil {
+		log.Printf(errHarvestingProfile, err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var entries []bson.M
+	if err = cur.All(ctx, &entries); err != nil {
+		log.Printf(errDecodingDocuments, err)
+		return nil, err
+	}
+	return groupProfileEntries(entries), nil
+}
+
+// groupProfileEntries aggregates profiler entries by namespace, operation
+// and query shape.
+func groupProfileEntries(entries []bson.M) []*ProfileGroup {
+	groups := map[string]*ProfileGroup{}
+	var order []*ProfileGroup
+
+	for _, entry := range entries {
+		ns, _ := entry["ns"].(string)
+		op, _ := entry["op"].(string)
+		shape, hash := profileShape(entry)
+
+		key := ns + "\x00" + op + "\x00" + shape
+		if hash != "" {
+			key = ns + "\x00" + op + "\x00" + hash
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = &ProfileGroup{Namespace: ns, Op: op, Shape: shape, ShapeHash: hash, plans: map[string]int64{}}
+			groups[key] = group
+			order = append(order, group)
+		}
+		group.add(entry)
+	}
+
+	for _, group := range order {
+		group.finish()
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return order[i].TotalMillis > order[j].TotalMillis
+	})
+	return order
+}
+
+func (g *ProfileGroup) add(entry bson.M) {
+	millis, _ := toInt64(entry["millis"])
+	g.Count++
+	g.TotalMillis += millis
+	g.millis = append(g.millis, millis)
+	if g.Count == 1 || millis > g.MaxMillis {
+		g.MaxMillis = millis
+		g.Example, _ = entry["command"].(bson.M)
+	}
+	if plan, ok := entry["planSummary"].(string); ok {
+		g.plans[plan]++
+	}
+	docs, _ := toInt64(entry["docsExamined"])
+	keys, _ := toInt64(entry["keysExamined"])
+	returned, _ := toInt64(entry["nreturned"])
+	g.DocsExamined += docs
+	g.KeysExamined += keys
+	g.NReturned += returned
+}
+
+// finish computes the percentiles and picks the most frequent plan.
+func (g *ProfileGroup) finish() {
+	sort.Slice(g.millis, func(i, j int) bool { return g.millis[i] < g.millis[j] })
+	g.P50Millis = percentile(g.millis, 0.50)
+	g.P95Millis = percentile(g.millis, 0.95)
+
+	var best int64
+	for plan, n := range g.plans {
+		if n > best || (n == best && plan < g.PlanSummary) {
+			best = n
+			g.PlanSummary = plan
+		}
+	}
+}
+
+// percentile returns the nearest-rank percentile q of sorted values.
+func percentile(sorted []int64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return float64(sorted[rank])
+}
+
+// profileShape describes the query shape of a profiler entry: its filter or
+// pipeline with every value replaced by "?", and the shape hash reported by
+// the server (queryShapeHash or planCacheShapeHash on 8.0+, queryHash
+// before), which is preferred for grouping when present.
+func profileShape(entry bson.M) (string, string) {
+	var hash string
+	for _, field := range []string{"queryShapeHash", "planCacheShapeHash", "queryHash"} {
+		if h, ok := entry[field].(string); ok && h != "" {
+			hash = h
+			break
+		}
+	}
+
+	command, _ := stageDocument(entry["command"])
+	for _, field := range []string{"filter", "q", "query", "pipeline"} {
+		if value, ok := command[field]; ok {
+			var sb strings.Builder
+			writeQueryShape(&sb, value)
+			return sb.String(), hash
+		}
+	}
+	return "", hash
+}
+
+// writeQueryShape writes value with its field names and operators kept and
+// its literal values replaced by "?".
+func writeQueryShape(sb *strings.Builder, value any) {
+	if doc, ok := value.(bson.D); ok {
+		sb.WriteByte('{')
+		for i, e := range doc {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			fmt.Fprintf(sb, "%q:", e.Key)
+			writeQueryShape(sb, e.Value)
+		}
+		sb.WriteByte('}')
+		return
+	}
+	if doc, ok := stageDocument(value); ok {
+		sb.WriteByte('{')
+		for i, key := range sortedKeys(doc) {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			fmt.Fprintf(sb, "%q:", key)
+			writeQueryShape(sb, doc[key])
+		}
+		sb.WriteByte('}')
+		return
+	}
+	if items, ok := documentArray(value); ok {
+		// Arrays of documents, such as $or branches or pipeline stages, are
+		// part of the shape; arrays of values are not.
+		for _, item := range items {
+			if _, isDoc := stageDocument(item); !isDoc {
+				sb.WriteByte('?')
+				return
+			}
+		}
+		sb.WriteByte('[')
+		for i, item := range items {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			writeQueryShape(sb, item)
+		}
+		sb.WriteByte(']')
+		return
+	}
+	sb.WriteByte('?')
+}
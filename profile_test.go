@@ -0,0 +1,114 @@
+package xk6_mongo
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSetProfilingLevelValidation(t *testing.T) {
+	client := &Client{}
+
+	invalid := map[string]struct {
+		level int64
+		opts  map[string]any
+	}{
+		"level too high":   {3, nil},
+		"negative level":   {-1, nil},
+		"unknown option":   {1, map[string]any{"slowMs": "fast", "threshold": 5}},
+		"sampleRate range": {1, map[string]any{"sampleRate": 1.5}},
+		"slowms type":      {1, map[string]any{"slowms": "100"}},
+	}
+	for name, tt := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := client.SetProfilingLevel("db", tt.level, tt.opts); err == nil {
+				t.Error("Expected error for invalid profiling settings")
+			}
+		})
+	}
+}
+
+func TestProfileShape(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry bson.M
+		shape string
+		hash  string
+	}{
+		{"find filter", bson.M{"command": bson.M{"find": "orders", "filter": bson.M{"user": "u1", "total": bson.M{"$gt": 10}}}},
+			`{"total":{"$gt":?},"user":?}`, ""},
+		{"values in arrays", bson.M{"command": bson.M{"filter": bson.M{"status": bson.M{"$in": bson.A{"a", "b"}}}}},
+			`{"status":{"$in":?}}`, ""},
+		{"documents in arrays", bson.M{"command": bson.M{"filter": bson.M{"$or": bson.A{bson.M{"a": 1}, bson.M{"b": 2}}}}},
+			`{"$or":[{"a":?},{"b":?}]}`, ""},
+		{"update statement", bson.M{"command": bson.M{"q": bson.D{{Key: "_id", Value: 7}}, "u": bson.M{"$set": bson.M{"x": 1}}}},
+			`{"_id":?}`, ""},
+		{"server hash", bson.M{"queryHash": "ABCD1234", "command": bson.M{"filter": bson.M{"a": 1}}},
+			`{"a":?}`, "ABCD1234"},
+		{"insert", bson.M{"command": bson.M{"insert": "orders"}}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shape, hash := profileShape(tt.entry)
+			if shape != tt.shape || hash != tt.hash {
+				t.Errorf("profileShape = (%q, %q), want (%q, %q)", shape, hash, tt.shape, tt.hash)
+			}
+		})
+	}
+}
+
+func TestGroupProfileEntries(t *testing.T) {
+	entry := func(user string, millis int32, plan string) bson.M {
+		return bson.M{
+			"op": "query", "ns": "shop.orders", "millis": millis, "planSummary": plan,
+			"docsExamined": int32(100), "keysExamined": int32(0), "nreturned": int32(1),
+			"command": bson.M{"find": "orders", "filter": bson.M{"user": user}},
+		}
+	}
+
+	var entries []bson.M
+	for i := int32(1); i <= 20; i++ {
+		entries = append(entries, entry("u1", i, "COLLSCAN"))
+	}
+	entries = append(entries, entry("u2", 500, "IXSCAN { user: 1 }"))
+	entries = append(entries, bson.M{"op": "insert", "ns": "shop.orders", "millis": int32(2), "command": bson.M{"insert": "orders"}})
+
+	groups := groupProfileEntries(entries)
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(groups))
+	}
+
+	find := groups[0]
+	if find.Op != "query" || find.Count != 21 || find.Shape != `{"user":?}` {
+		t.Errorf("Unexpected find group %+v", find)
+	}
+	if find.TotalMillis != 710 || find.MaxMillis != 500 {
+		t.Errorf("Expected total 710 and max 500, got %d and %d", find.TotalMillis, find.MaxMillis)
+	}
+	if find.P50Millis != 11 || find.P95Millis != 20 {
+		t.Errorf("Expected p50 11 and p95 20, got %v and %v", find.P50Millis, find.P95Millis)
+	}
+	if find.PlanSummary != "COLLSCAN" || find.DocsExamined != 2100 || find.NReturned != 21 {
+		t.Errorf("Unexpected plan %q, docsExamined %d or nReturned %d", find.PlanSummary, find.DocsExamined, find.NReturned)
+	}
+	if filter := find.Example["filter"].(bson.M); filter["user"] != "u2" {
+		t.Errorf("Expected the slowest command as example, got %v", find.Example)
+	}
+
+	if groups[1].Op != "insert" || groups[1].Count != 1 {
+		t.Errorf("Unexpected insert group %+v", groups[1])
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	if p := percentile(values, 0.5); p != 5 {
+		t.Errorf("Expected p50 5, got %v", p)
+	}
+	if p := percentile(values, 0.95); p != 10 {
+		t.Errorf("Expected p95 10, got %v", p)
+	}
+	if p := percentile(nil, 0.5); p != 0 {
+		t.Errorf("Expected 0 for no values, got %v", p)
+	}
+}
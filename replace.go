@@ -0,0 +1,185 @@
+package xk6_mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var errReplacementsEmpty = errors.New("replacements array cannot be empty")
+
+// UpdateResult reports the outcome of a replace operation.
+type UpdateResult struct {
+	MatchedCount  int64         `js:"matchedCount"`
+	ModifiedCount int64         `js:"modifiedCount"`
+	UpsertedCount int64         `js:"upsertedCount"`
+	UpsertedID    any           `js:"upsertedId"`
+	UpsertedIDs   map[int64]any `js:"upsertedIds"`
+}
+
+// ReplaceOneModel is a single entry of a ReplaceMany call.
+type ReplaceOneModel struct {
+	Filter      any `json:"filter"`
+	Replacement any `json:"replacement"`
+}
+
+// ReplaceOne replaces the whole document matching filter. Options: upsert,
+// hint, collation, let, comment and bypassDocumentValidation.
+func (c *Client) ReplaceOne(database string, collection string, filter any, replacement any, opts map[string]any) (*UpdateResult, error) {
+	if filter == nil {
+		return nil, errFilterNil
+	}
+	if err := validateReplacementDocument(replacement); err != nil {
+		return nil, err
+	}
+
+	parsed, err := parseCommandOptions(opts, optBypassDocumentValidation, optCollation, optComment,
+		optHint, optLet, optUpsert)
+	if err != nil {
+		return nil, err
+	}
+
+	col, err := c.getCollection(database, collection)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	var result *mongo.UpdateResult
+	err = c.do("replaceOne", updateKind(filter, replacement), func(ctx context.Context) error {
+		var err error
+		result, err = col.ReplaceOne(ctx, filter, replacement, parsed.replace())
+		return err
+	})
+	if err != nil {
+		log.Printf(errReplacingDocument, err)
+		return nil, err
+	}
+
+	return &UpdateResult{
+		MatchedCount:  result.MatchedCount,
+		ModifiedCount: result.ModifiedCount,
+		UpsertedCount: result.UpsertedCount,
+		UpsertedID:    result.UpsertedID,
+	}, nil
+}
+
+// ReplaceMany replaces one document per {filter, replacement} entry in a
+// single bulk write. It accepts the ReplaceOne options plus ordered.
+func (c *Client) ReplaceMany(database string, collection string, replacements []any, opts map[string]any) (*UpdateResult, error) {
+	if len(replacements) == 0 {
+		return nil, errReplacementsEmpty
+	}
+
+	parsed, err := parseCommandOptions(opts, optBypassDocumentValidation, optCollation, optComment,
+		optHint, optLet, optOrdered, optUpsert)
+	if err != nil {
+		return nil, err
+	}
+
+	models := make([]mongo.WriteModel, 0, len(replacements))
+	for i, entry := range replacements {
+		model, err := parsed.replaceOneModel(entry)
+		if err != nil {
+			return nil, fmt.Errorf("replacement %d: %w", i, err)
+		}
+		models = append(models, model)
+	}
+
+	col, err := c.getCollection(database, collection)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	var result *mongo.BulkWriteResult
+	err = c.do("replaceMany", replaceManyKind(models), func(ctx context.Context) error {
+		var err error
+		result, err = col.BulkWrite(ctx, models, parsed.bulkWrite())
+		return err
+	})
+	if err != nil {
+		log.Printf(errReplacingDocuments, err)
+		return nil, err
+	}
+
+	return &UpdateResult{
+		MatchedCount:  result.MatchedCount,
+		ModifiedCount: result.ModifiedCount,
+		UpsertedCount: result.UpsertedCount,
+		UpsertedIDs:   result.UpsertedIDs,
+	}, nil
+}
+
+// replaceOneModel converts a {filter, replacement} JS object to a bulk model
+// carrying the per-document options.
+func (o *commandOptions) replaceOneModel(entry any) (*mongo.ReplaceOneModel, error) {
+	raw, ok := entry.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a {filter, replacement} object, got %T", entry)
+	}
+	filter := raw["filter"]
+	if filter == nil {
+		return nil, errFilterNil
+	}
+	replacement := raw["replacement"]
+	if err := validateReplacementDocument(replacement); err != nil {
+		return nil, err
+	}
+
+	model := mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(replacement)
+	if o.collation != nil {
+		model.SetCollation(o.collation)
+	}
+	if o.hint != nil {
+		model.SetHint(o.hint)
+	}
+	if o.upsert != nil {
+		model.SetUpsert(*o.upsert)
+	}
+	return model, nil
+}
+
+func (o *commandOptions) replace() *options.ReplaceOptions {
+	opts := options.Replace()
+	if o.bypassDocumentValidation != nil {
+		opts.SetBypassDocumentValidation(*o.bypassDocumentValidation)
+	}
+	if o.collation != nil {
+		opts.SetCollation(o.collation)
+	}
+	if o.comment != nil {
+		opts.SetComment(o.comment)
+	}
+	if o.hint != nil {
+		opts.SetHint(o.hint)
+	}
+	if o.let != nil {
+		opts.SetLet(o.let)
+	}
+	if o.upsert != nil {
+		opts.SetUpsert(*o.upsert)
+	}
+	return opts
+}
+
+func (o *commandOptions) bulkWrite() *options.BulkWriteOptions {
+	opts := options.BulkWrite()
+	if o.bypassDocumentValidation != nil {
+		opts.SetBypassDocumentValidation(*o.bypassDocumentValidation)
+	}
+	if o.comment != nil {
+		opts.SetComment(o.comment)
+	}
+	if o.let != nil {
+		opts.SetLet(o.let)
+	}
+	if o.ordered != nil {
+		opts.SetOrdered(*o.ordered)
+	}
+	return opts
+}
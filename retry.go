@@ -0,0 +1,420 @@
+package xk6_mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+// opKind classifies operations for the retry policy.
+type opKind int
+
+const (
+	// opRead operations are retried when retryReads is enabled.
+	opRead opKind = iota
+	// opIdempotentWrite operations leave the same state when applied twice
+	// and are retried when retryWrites is enabled.
+	opIdempotentWrite
+	// opWrite operations may apply twice if retried after an error that
+	// leaves their outcome unknown, so they are only retried after errors
+	// that guarantee nothing was written, unless retryNonIdempotent is set.
+	opWrite
+)
+
+// Jitter modes of the retry backoff.
+const (
+	jitterFull  = "full"
+	jitterEqual = "equal"
+	jitterNone  = "none"
+)
+
+// Reasons reported by mongo_retry_giveups.
+const (
+	giveUpMaxAttempts   = "max_attempts"
+	giveUpNonIdempotent = "non_idempotent"
+	giveUpDisabled      = "retry_disabled"
+)
+
+var (
+	// defaultRetryableCodes adds the network error codes and
+	// ExceededTimeLimit to the codes returned while there is no primary.
+	defaultRetryableCodes = append(append([]int{}, notPrimaryErrorCodes...),
+		6,    // HostUnreachable
+		7,    // HostNotFound
+		89,   // NetworkTimeout
+		9001, // SocketException
+		262,  // ExceededTimeLimit
+	)
+	defaultRetryableLabels = []string{"RetryableWriteError", "NetworkError", "SystemOverloadedError",
+		"RetryableError", "UnknownTransactionCommitResult"}
+
+	// notWrittenCodes are rejections that guarantee a write was not applied.
+	notWrittenCodes = []int{
+		10107, // NotWritablePrimary
+		13435, // NotPrimaryNoSecondaryOk
+		13436, // NotPrimaryOrSecondary
+		91,    // ShutdownInProgress
+	}
+
+	// idempotentUpdateOperators give the same document when applied twice.
+	idempotentUpdateOperators = map[string]bool{
+		"$set": true, "$unset": true, "$setOnInsert": true,
+		"$min": true, "$max": true, "$addToSet": true,
+	}
+)
+
+// retryPolicy is the application-level retry policy applied on top of the
+// driver's own single retry.
+type retryPolicy struct {
+	maxAttempts        int
+	initialBackoff     time.Duration
+	maxBackoff         time.Duration
+	multiplier         float64
+	jitter             string
+	retryableCodes     []int
+	retryableLabels    []string
+	retryNonIdempotent bool
+}
+
+// defaultRetryPolicy makes a single attempt, leaving retries to the driver.
+func defaultRetryPolicy() *retryPolicy {
+	return &retryPolicy{
+		maxAttempts:     1,
+		initialBackoff:  100 * time.Millisecond,
+		maxBackoff:      5 * time.Second,
+		multiplier:      2,
+		jitter:          jitterFull,
+		retryableCodes:  defaultRetryableCodes,
+		retryableLabels: defaultRetryableLabels,
+	}
+}
+
+// SetRetryPolicy configures how the client's operations are retried after
+// transient errors. Options: maxAttempts (default 3), initialBackoff and
+// maxBackoff in ms (default 100 and 5000), multiplier (default 2), jitter
+// ("full", "equal" or "none", default "full"), retryableCodes and
+// retryableLabels (replacing the defaults) and retryNonIdempotent. Reads
+// are only retried when retryReads is enabled and writes when retryWrites
+// is enabled.
+func (c *Client) SetRetryPolicy(opts map[string]any) error {
+	p, err := parseRetryPolicy(opts)
+	if err != nil {
+		return err
+	}
+	c.retry.Store(p)
+	return nil
+}
+
+func parseRetryPolicy(opts map[string]any) (*retryPolicy, error) {
+	if err := checkAllowedOptions(opts, "maxAttempts", "initialBackoff", "maxBackoff", "multiplier",
+		"jitter", "retryableCodes", "retryableLabels", "retryNonIdempotent"); err != nil {
+		return nil, err
+	}
+
+	p := defaultRetryPolicy()
+	p.maxAttempts = 3
+
+	maxAttempts, ok, err := intOption(opts, "maxAttempts")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if maxAttempts < 1 {
+			return nil, fmt.Errorf("option maxAttempts must be at least 1, got %d", maxAttempts)
+		}
+		p.maxAttempts = int(maxAttempts)
+	}
+	if d, ok, err := durationOption(opts, "initialBackoff"); err != nil {
+		return nil, err
+	} else if ok {
+		p.initialBackoff = d
+	}
+	if d, ok, err := durationOption(opts, "maxBackoff"); err != nil {
+		return nil, err
+	} else if ok {
+		p.maxBackoff = d
+	}
+	multiplier, ok, err := floatOption(opts, "multiplier")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if multiplier < 1 {
+			return nil, fmt.Errorf("option multiplier must be at least 1, got %v", multiplier)
+		}
+		p.multiplier = multiplier
+	}
+	jitter, ok, err := stringOption(opts, "jitter")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if jitter != jitterFull && jitter != jitterEqual && jitter != jitterNone {
+			return nil, fmt.Errorf("option jitter must be \"full\", \"equal\" or \"none\", got %q", jitter)
+		}
+		p.jitter = jitter
+	}
+	if codes, ok := lookupOption(opts, "retryableCodes"); ok && codes != nil {
+		items, isArray := documentArray(codes)
+		if !isArray {
+			return nil, fmt.Errorf("option retryableCodes must be an array of integers, got %T", codes)
+		}
+		p.retryableCodes = make([]int, 0, len(items))
+		for _, item := range items {
+			n, ok := toInt64(item)
+			if !ok {
+				return nil, fmt.Errorf("option retryableCodes must be an array of integers, got %v", item)
+			}
+			p.retryableCodes = append(p.retryableCodes, int(n))
+		}
+	}
+	labels, ok, err := stringsOption(opts, "retryableLabels")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		p.retryableLabels = labels
+	}
+	if p.retryNonIdempotent, _, err = boolOption(opts, "retryNonIdempotent"); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// retryReason returns why err is worth retrying, used as the reason tag of
+// mongo_retries: the error label or code that matched, or
+// server_selection.
+func (p *retryPolicy) retryReason(err error) (string, bool) {
+	if errors.Is(err, topology.ErrServerSelectionTimeout) {
+		return "server_selection", true
+	}
+	var se mongo.ServerError
+	if !errors.As(err, &se) {
+		return "", false
+	}
+	for _, label := range p.retryableLabels {
+		if se.HasErrorLabel(label) {
+			return label, true
+		}
+	}
+	for _, code := range p.retryableCodes {
+		if se.HasErrorCode(code) {
+			return strconv.Itoa(code), true
+		}
+	}
+	return "", false
+}
+
+// backoff returns the wait before retry number attempt (1 for the first
+// retry).
+func (p *retryPolicy) backoff(attempt int) time.Duration {
+	base := float64(p.initialBackoff) * math.Pow(p.multiplier, float64(attempt-1))
+	if limit := float64(p.maxBackoff); p.maxBackoff > 0 && base > limit {
+		base = limit
+	}
+	switch p.jitter {
+	case jitterFull:
+		base *= rand.Float64()
+	case jitterEqual:
+		base = base/2 + base/2*rand.Float64()
+	}
+	return time.Duration(base)
+}
+
+// notWritten reports whether err guarantees that a write was not applied,
+// so that retrying it cannot apply it twice.
+func notWritten(err error) bool {
+	if errors.Is(err, topology.ErrServerSelectionTimeout) {
+		return true
+	}
+	var se mongo.ServerError
+	if !errors.As(err, &se) {
+		return false
+	}
+	if se.HasErrorLabel("NoWritesPerformed") {
+		return true
+	}
+	for _, code := range notWrittenCodes {
+		if se.HasErrorCode(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// do runs fn with a fresh operation timeout per attempt, retrying it as the
+// retry policy allows. op names the operation in the retry metrics. Writes
+// are reported to the failover monitors.
+func (c *Client) do(op string, kind opKind, fn func(ctx context.Context) error) error {
+	p := c.retry.Load()
+	if p == nil {
+		p = defaultRetryPolicy()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := c.attempt(kind, fn)
+		if err == nil {
+			return nil
+		}
+
+		reason, ok := p.retryReason(err)
+		if !ok || p.maxAttempts == 1 {
+			return err
+		}
+		switch {
+		case (kind == opRead && !c.retryReads) || (kind != opRead && !c.retryWrites):
+			c.recordGiveUp(op, giveUpDisabled)
+			return err
+		case kind == opWrite && !p.retryNonIdempotent && !notWritten(err):
+			c.recordGiveUp(op, giveUpNonIdempotent)
+			return err
+		case attempt >= p.maxAttempts:
+			c.recordGiveUp(op, giveUpMaxAttempts)
+			return err
+		}
+
+		if c.metrics != nil {
+			c.emit(c.metrics.retries, 1, map[string]string{"op": op, "reason": reason})
+		}
+		if !c.sleep(p.backoff(attempt)) {
+			return err
+		}
+	}
+}
+
+// attempt runs fn once with a fresh operation timeout. Unacknowledged
+// writes succeed once sent.
+func (c *Client) attempt(kind opKind, fn func(ctx context.Context) error) error {
+	ctx, cancel := c.getContext()
+	defer cancel()
+
+	start := time.Now()
+	err := ignoreUnacknowledged(fn(ctx))
+	if kind != opRead {
+		c.observeWrite(start, err)
+	}
+	return err
+}
+
+func (c *Client) recordGiveUp(op, reason string) {
+	if c.metrics != nil {
+		c.emit(c.metrics.retryGiveUps, 1, map[string]string{"op": op, "reason": reason})
+	}
+}
+
+// sleep waits for d, returning false if the VU is interrupted first.
+func (c *Client) sleep(d time.Duration) bool {
+	done := context.Background().Done()
+	if c.vu != nil && c.vu.Context() != nil {
+		done = c.vu.Context().Done()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// insertKind classifies inserts: documents with an _id cannot be inserted
+// twice, but the driver generates a new _id on every attempt for documents
+// without one.
+func insertKind(docs ...any) opKind {
+	for _, doc := range docs {
+		if raw, ok := doc.(bson.Raw); ok {
+			if _, err := raw.LookupErr("_id"); err != nil {
+				return opWrite
+			}
+			continue
+		}
+		m, ok := stageDocument(doc)
+		if !ok {
+			return opWrite
+		}
+		if _, ok := m["_id"]; !ok {
+			return opWrite
+		}
+	}
+	return opIdempotentWrite
+}
+
+// updateKind classifies updates of a single document: they are idempotent
+// when the filter selects the document by _id and every update operator
+// gives the same result when applied twice. Replacements only need the _id
+// filter.
+func updateKind(filter, update any) opKind {
+	if !filtersByID(filter) {
+		return opWrite
+	}
+	return updateManyKind(update)
+}
+
+// updateManyKind classifies updates of every matching document.
+func updateManyKind(update any) opKind {
+	if isPipelineUpdate(update) {
+		return opWrite
+	}
+	doc, ok := stageDocument(update)
+	if !ok {
+		return opWrite
+	}
+	for key := range doc {
+		if strings.HasPrefix(key, "$") && !idempotentUpdateOperators[key] {
+			return opWrite
+		}
+	}
+	return opIdempotentWrite
+}
+
+// deleteOneKind classifies single deletes: deleting "the first match" again
+// would delete another document unless the filter selects it by _id.
+func deleteOneKind(filter any) opKind {
+	if filtersByID(filter) {
+		return opIdempotentWrite
+	}
+	return opWrite
+}
+
+// filtersByID reports whether filter selects a single document by an _id
+// value.
+func filtersByID(filter any) bool {
+	doc, ok := stageDocument(filter)
+	if !ok {
+		return false
+	}
+	id, ok := doc["_id"]
+	if !ok {
+		return false
+	}
+	if sub, isDoc := stageDocument(id); isDoc {
+		for key := range sub {
+			if strings.HasPrefix(key, "$") && key != "$eq" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// replaceManyKind classifies bulk replacements, which are idempotent when
+// every replacement selects its document by _id.
+func replaceManyKind(models []mongo.WriteModel) opKind {
+	for _, model := range models {
+		replace, ok := model.(*mongo.ReplaceOneModel)
+		if !ok || !filtersByID(replace.Filter) {
+			return opWrite
+		}
+	}
+	return opIdempotentWrite
+}
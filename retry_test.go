@@ -0,0 +1,230 @@
+package xk6_mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestParseRetryPolicy(t *testing.T) {
+	p, err := parseRetryPolicy(nil)
+	if err != nil {
+		t.Fatalf("parseRetryPolicy failed: %v", err)
+	}
+	if p.maxAttempts != 3 || p.initialBackoff != 100*time.Millisecond || p.jitter != jitterFull {
+		t.Errorf("Unexpected defaults %+v", p)
+	}
+
+	p, err = parseRetryPolicy(map[string]any{
+		"maxAttempts":     int64(5),
+		"initialBackoff":  int64(20),
+		"maxBackoff":      int64(200),
+		"multiplier":      3.0,
+		"jitter":          "none",
+		"retryableCodes":  []any{int64(112)},
+		"retryableLabels": []any{"TransientTransactionError"},
+	})
+	if err != nil {
+		t.Fatalf("parseRetryPolicy failed: %v", err)
+	}
+	if p.maxAttempts != 5 || p.multiplier != 3 || len(p.retryableCodes) != 1 || p.retryableLabels[0] != "TransientTransactionError" {
+		t.Errorf("Unexpected policy %+v", p)
+	}
+
+	invalid := []map[string]any{
+		{"maxAttempts": int64(0)},
+		{"multiplier": 0.5},
+		{"jitter": "random"},
+		{"retryableCodes": "91"},
+		{"retryableCodes": []any{"91"}},
+		{"backoff": int64(10)},
+	}
+	for _, opts := range invalid {
+		if _, err := parseRetryPolicy(opts); err == nil {
+			t.Errorf("Expected error for %v", opts)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := defaultRetryPolicy()
+	p.jitter = jitterNone
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 10: 5 * time.Second} {
+		if got := p.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+
+	p.jitter = jitterEqual
+	for i := 0; i < 100; i++ {
+		if got := p.backoff(2); got < 100*time.Millisecond || got > 200*time.Millisecond {
+			t.Fatalf("equal jitter backoff %v out of [100ms, 200ms]", got)
+		}
+	}
+	p.jitter = jitterFull
+	for i := 0; i < 100; i++ {
+		if got := p.backoff(2); got < 0 || got > 200*time.Millisecond {
+			t.Fatalf("full jitter backoff %v out of [0, 200ms]", got)
+		}
+	}
+}
+
+func TestRetryReason(t *testing.T) {
+	p := defaultRetryPolicy()
+	tests := []struct {
+		err    error
+		reason string
+		ok     bool
+	}{
+		{mongo.CommandError{Code: 10107}, "10107", true},
+		{mongo.CommandError{Code: 1, Labels: []string{"NetworkError"}}, "NetworkError", true},
+		{mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}, "", false},
+		{errors.New("boom"), "", false},
+	}
+	for _, tt := range tests {
+		reason, ok := p.retryReason(tt.err)
+		if reason != tt.reason || ok != tt.ok {
+			t.Errorf("retryReason(%v) = %q, %v, want %q, %v", tt.err, reason, ok, tt.reason, tt.ok)
+		}
+	}
+}
+
+func TestOperationKinds(t *testing.T) {
+	byID := bson.M{"_id": 1}
+	tests := []struct {
+		name string
+		got  opKind
+		want opKind
+	}{
+		{"insert with _id", insertKind(bson.M{"_id": 1}, bson.D{{Key: "_id", Value: 2}}), opIdempotentWrite},
+		{"insert without _id", insertKind(bson.M{"_id": 1}, bson.M{"name": "x"}), opWrite},
+		{"raw insert with _id", insertKind(mustMarshal(t, bson.D{{Key: "_id", Value: 1}})), opIdempotentWrite},
+		{"raw insert without _id", insertKind(mustMarshal(t, bson.D{{Key: "name", Value: "x"}})), opWrite},
+		{"$set by _id", updateKind(byID, bson.M{"$set": bson.M{"a": 1}}), opIdempotentWrite},
+		{"$inc by _id", updateKind(byID, bson.M{"$inc": bson.M{"a": 1}}), opWrite},
+		{"$set by other field", updateKind(bson.M{"status": "new"}, bson.M{"$set": bson.M{"status": "taken"}}), opWrite},
+		{"$set by _id $in", updateKind(bson.M{"_id": bson.M{"$in": bson.A{1, 2}}}, bson.M{"$set": bson.M{"a": 1}}), opWrite},
+		{"replacement by _id", updateKind(byID, bson.M{"a": 1}), opIdempotentWrite},
+		{"pipeline update", updateManyKind(bson.A{bson.M{"$set": bson.M{"a": 1}}}), opWrite},
+		{"$max many", updateManyKind(map[string]any{"$max": bson.M{"a": 1}}), opIdempotentWrite},
+		{"delete by _id", deleteOneKind(byID), opIdempotentWrite},
+		{"delete first match", deleteOneKind(bson.M{"status": "done"}), opWrite},
+		{"replace many by _id", replaceManyKind([]mongo.WriteModel{mongo.NewReplaceOneModel().SetFilter(byID)}), opIdempotentWrite},
+		{"replace many by field", replaceManyKind([]mongo.WriteModel{mongo.NewReplaceOneModel().SetFilter(bson.M{"a": 1})}), opWrite},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: kind = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+// retryTestClient returns a client with retries enabled, a policy without
+// backoff and the samples it emits.
+func retryTestClient(t *testing.T, opts map[string]any) (*Client, func() map[string]int) {
+	t.Helper()
+	client, samples := newMetricsTestClient(t)
+	client.defaultTimeout = time.Second
+	client.retryReads, client.retryWrites = true, true
+
+	policy := map[string]any{"initialBackoff": int64(0)}
+	for key, value := range opts {
+		policy[key] = value
+	}
+	if err := client.SetRetryPolicy(policy); err != nil {
+		t.Fatalf("SetRetryPolicy failed: %v", err)
+	}
+
+	return client, func() map[string]int {
+		counts := map[string]int{}
+		for _, s := range collectSamples(samples) {
+			counts[s.Metric.Name+"/"+s.Tags.Map()["reason"]]++
+		}
+		return counts
+	}
+}
+
+// failing returns an operation failing with errs in turn, then succeeding.
+func failing(calls *int, errs ...error) func(context.Context) error {
+	return func(context.Context) error {
+		*calls++
+		if *calls <= len(errs) {
+			return errs[*calls-1]
+		}
+		return nil
+	}
+}
+
+func TestDoRetriesUntilSuccess(t *testing.T) {
+	client, counts := retryTestClient(t, nil)
+	network := mongo.CommandError{Labels: []string{"NetworkError"}}
+
+	var calls int
+	if err := client.do("find", opRead, failing(&calls, network, network)); err != nil {
+		t.Fatalf("Expected success after retries, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+	if got := counts()["mongo_retries/NetworkError"]; got != 2 {
+		t.Errorf("Expected 2 retries, got %d", got)
+	}
+}
+
+func TestDoGivesUp(t *testing.T) {
+	network := mongo.CommandError{Labels: []string{"NetworkError"}}
+	notPrimary := mongo.CommandError{Code: 10107}
+
+	tests := []struct {
+		name      string
+		kind      opKind
+		errs      []error
+		opts      map[string]any
+		setup     func(*Client)
+		wantCalls int
+		giveUp    string
+	}{
+		{"max attempts", opRead, []error{network, network, network}, nil, nil, 3, giveUpMaxAttempts},
+		{"non idempotent", opWrite, []error{network}, nil, nil, 1, giveUpNonIdempotent},
+		{"retry disabled", opRead, []error{network}, nil, func(c *Client) { c.retryReads = false }, 1, giveUpDisabled},
+		{"not retryable", opRead, []error{errors.New("boom")}, nil, nil, 1, ""},
+		{"custom codes", opRead, []error{network}, map[string]any{"retryableLabels": []any{}, "retryableCodes": []any{int64(91)}}, nil, 1, ""},
+	}
+	for _, tt := range tests {
+		client, counts := retryTestClient(t, tt.opts)
+		if tt.setup != nil {
+			tt.setup(client)
+		}
+		var calls int
+		if err := client.do("op", tt.kind, failing(&calls, tt.errs...)); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+		if calls != tt.wantCalls {
+			t.Errorf("%s: expected %d attempts, got %d", tt.name, tt.wantCalls, calls)
+		}
+		if tt.giveUp != "" && counts()["mongo_retry_giveups/"+tt.giveUp] != 1 {
+			t.Errorf("%s: expected a %s give-up", tt.name, tt.giveUp)
+		}
+	}
+
+	// Writes rejected before being applied are retried even when not
+	// idempotent.
+	client, _ := retryTestClient(t, nil)
+	var calls int
+	if err := client.do("insert", opWrite, failing(&calls, notPrimary)); err != nil || calls != 2 {
+		t.Errorf("Expected a retried insert, got %v after %d attempts", err, calls)
+	}
+}
+
+func TestDoDefaultPolicySingleAttempt(t *testing.T) {
+	client := &Client{defaultTimeout: time.Second, retryReads: true, retryWrites: true}
+	var calls int
+	err := client.do("find", opRead, failing(&calls, mongo.CommandError{Labels: []string{"NetworkError"}}))
+	if err == nil || calls != 1 {
+		t.Errorf("Expected a single failed attempt, got %v after %d attempts", err, calls)
+	}
+}
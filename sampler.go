@@ -0,0 +1,101 @@
+package xk6_mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.k6.io/k6/metrics"
+)
+
+const defaultSamplerInterval = 5 * time.Second
+
+var errSamplerStopped = errors.New("sampler is stopped")
+
+// sampleLoop calls a sample function on a background goroutine, once right
+// away and then every interval, until it is stopped or the test is over for
+// the VU that started it, then releases the sampler's resources. Samples are
+// pushed with the tags of that VU at start, so that a loop started from
+// setup() keeps recording after setup() returns.
+type sampleLoop struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+	samples chan<- metrics.SampleContainer
+	tags    metrics.TagsAndMeta
+}
+
+func newSampleLoop(c *Client) *sampleLoop {
+	parent := testContext
+	l := &sampleLoop{done: make(chan struct{})}
+	if c.vu != nil {
+		if state := c.vu.State(); state != nil {
+			l.samples = state.Samples
+			l.tags = state.Tags.GetCurrentValues()
+			// setup() and teardown() run in a VU with ID 0 whose context
+			// ends with them.
+			if state.VUID != 0 && c.vu.Context() != nil {
+				parent = c.vu.Context()
+			}
+		}
+	}
+	l.ctx, l.cancel = context.WithCancel(parent)
+	return l
+}
+
+// start runs the loop, then calls release once it is over.
+func (l *sampleLoop) start(interval time.Duration, sample func(), release func()) {
+	go func() {
+		defer close(l.done)
+		defer release()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			sample()
+			select {
+			case <-l.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stop stops the loop and waits for a sample in progress to finish and for
+// the resources to be released.
+func (l *sampleLoop) stop() {
+	l.cancel()
+	<-l.done
+}
+
+// emit pushes a sample tagged like client.emit, for as long as the loop
+// runs.
+func (l *sampleLoop) emit(metric *metrics.Metric, value float64, extra map[string]string) {
+	if l.samples == nil || metric == nil {
+		return
+	}
+	tags := l.tags.Tags
+	if len(extra) > 0 {
+		tags = tags.WithTagsFromMap(extra)
+	}
+	metrics.PushIfNotDone(l.ctx, l.samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{Metric: metric, Tags: tags},
+		Time:       time.Now(),
+		Value:      value,
+		Metadata:   l.tags.Metadata,
+	})
+}
+
+// samplerInterval reads the interval option of the samplers, in ms.
+func samplerInterval(opts map[string]any) (time.Duration, error) {
+	interval, ok, err := durationOption(opts, "interval")
+	if err != nil {
+		return 0, err
+	}
+	if !ok || interval <= 0 {
+		return defaultSamplerInterval, nil
+	}
+	return interval, nil
+}
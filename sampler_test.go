@@ -0,0 +1,97 @@
+package xk6_mongo
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.k6.io/k6/js/modulestest"
+)
+
+// newSampleLoopTestClient returns a metrics test client whose VU has the
+// given ID and a context canceled by the returned function.
+func newSampleLoopTestClient(t *testing.T, vuID uint64) (*Client, context.CancelFunc) {
+	t.Helper()
+	client, _ := newMetricsTestClient(t)
+	vu := client.vu.(*modulestest.VU)
+	ctx, cancel := context.WithCancel(context.Background())
+	vu.CtxField = ctx
+	vu.StateField.VUID = vuID
+	return client, cancel
+}
+
+func TestSampleLoopStopsWithVU(t *testing.T) {
+	client, cancel := newSampleLoopTestClient(t, 1)
+	defer cancel()
+
+	released := make(chan struct{})
+	loop := newSampleLoop(client)
+	loop.start(time.Millisecond, func() {}, func() { close(released) })
+
+	cancel()
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the loop to stop when the VU context is done")
+	}
+	loop.stop()
+}
+
+func TestSampleLoopOutlivesSetup(t *testing.T) {
+	client, cancel := newSampleLoopTestClient(t, 0)
+	defer cancel()
+
+	var samples, releases atomic.Int32
+	loop := newSampleLoop(client)
+	loop.start(time.Millisecond, func() { samples.Add(1) }, func() { releases.Add(1) })
+
+	// setup() returning cancels the context of its VU.
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	before := samples.Load()
+	time.Sleep(20 * time.Millisecond)
+	if samples.Load() <= before || releases.Load() != 0 {
+		t.Errorf("Expected a loop started from setup() to keep sampling, got %d samples and %d releases",
+			samples.Load(), releases.Load())
+	}
+
+	loop.stop()
+	loop.stop()
+	if releases.Load() != 1 {
+		t.Errorf("Expected one release, got %d", releases.Load())
+	}
+}
+
+func TestSampleLoopEmit(t *testing.T) {
+	client, samples := newMetricsTestClient(t)
+	loop := newSampleLoop(client)
+	defer loop.cancel()
+	loop.emit(client.metrics.activeOperations, 3, map[string]string{"op": "query"})
+
+	got := collectSamples(samples)
+	if len(got) != 1 || got[0].Value != 3 {
+		t.Fatalf("Expected one sample, got %v", got)
+	}
+	if op, _ := got[0].Tags.Get("op"); op != "query" {
+		t.Errorf("Expected the op tag, got %q", op)
+	}
+
+	(&sampleLoop{}).emit(client.metrics.activeOperations, 1, nil)
+}
+
+func TestCurrentOpSamplerStopsWithVU(t *testing.T) {
+	client, cancel := newSampleLoopTestClient(t, 1)
+	defer cancel()
+
+	s := &CurrentOpSampler{client: client, previous: map[activeOpKey]int64{}, loop: newSampleLoop(client)}
+	s.loop.start(time.Hour, func() {}, s.release)
+	cancel()
+	<-s.loop.done
+	if err := s.Sample(); err != errSamplerStopped {
+		t.Errorf("Expected errSamplerStopped once the VU is done, got %v", err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Errorf("Stop failed: %v", err)
+	}
+}
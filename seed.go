@@ -0,0 +1,231 @@
+package xk6_mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.k6.io/k6/metrics"
+)
+
+const (
+	errSeeding = "Error while seeding %s.%s: %v"
+
+	defaultSeedBatchSize = 1000
+	defaultSeedWorkers   = 4
+	defaultSeedProgress  = 5 * time.Second
+)
+
+var (
+	errSeedGenerator = errors.New("seed requires a generator")
+	errSeedCount     = errors.New("seed requires a positive count")
+)
+
+// SeedResult reports the outcome of a Seed call.
+type SeedResult struct {
+	// Inserted is the number of documents inserted, or sent when the
+	// inserts are unacknowledged.
+	Inserted int64 `js:"inserted"`
+	// Duplicates is the number of documents skipped because of duplicate
+	// keys, when ignoreDuplicates is set.
+	Duplicates     int64   `js:"duplicates"`
+	WriteErrors    int64   `js:"writeErrors"`
+	Batches        int64   `js:"batches"`
+	DurationMillis float64 `js:"durationMillis"`
+	DocsPerSecond  float64 `js:"docsPerSecond"`
+}
+
+// batchProgress holds the counters shared by the batch insert workers.
+type batchProgress struct {
+	// processed counts the documents of the finished batches, whatever
+	// their outcome.
+	processed   atomic.Int64
+	inserted    atomic.Int64
+	duplicates  atomic.Int64
+	writeErrors atomic.Int64
+	batches     atomic.Int64
+
+	firstWriteError sync.Once
+	mu              sync.Mutex
+	err             error
+}
+
+func (p *batchProgress) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// Seed inserts count documents from a generator with parallel workers, to
+// prepare large datasets from setup(). Options: generator and count
+// (required), batchSize (default 1000), workers (default 4),
+// progressInterval (ms between progress messages in the k6 log, default
+// 5000, 0 to disable) and the insertMany options ordered (default false),
+// bypassDocumentValidation, comment, ignoreDuplicates and unacknowledged.
+// The first failing batch stops the workers.
+func (c *Client) Seed(database string, collection string, opts map[string]any) (*SeedResult, error) {
+	parsed, err := parseCommandOptions(opts, "generator", "count", optBatchSize, "workers", "progressInterval",
+		optBypassDocumentValidation, optComment, optIgnoreDuplicates, optOrdered, optUnacknowledged)
+	if err != nil {
+		return nil, err
+	}
+	raw, _ := lookupOption(opts, "generator")
+	g, ok := raw.(*Generator)
+	if !ok {
+		return nil, errSeedGenerator
+	}
+	count, _, err := intOption(opts, "count")
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		return nil, errSeedCount
+	}
+	batchSize := int64(defaultSeedBatchSize)
+	if parsed.batchSize != nil {
+		batchSize = int64(*parsed.batchSize)
+	}
+	workers, ok, err := intOption(opts, "workers")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		workers = defaultSeedWorkers
+	}
+	if workers < 1 {
+		return nil, fmt.Errorf("option workers must be at least 1, got %d", workers)
+	}
+	interval, ok, err := durationOption(opts, "progressInterval")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		interval = defaultSeedProgress
+	}
+	if parsed.ordered == nil {
+		unordered := false
+		parsed.ordered = &unordered
+	}
+
+	col, unacknowledged, err := c.writeCollection(database, collection, parsed.unacknowledged)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	parent := context.Background()
+	if c.vu != nil && c.vu.Context() != nil {
+		parent = c.vu.Context()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	start := time.Now()
+	progress := &batchProgress{}
+	var claimed atomic.Int64
+	var wg sync.WaitGroup
+	for range min(workers, (count+batchSize-1)/batchSize) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := g.newRand()
+			docs := make([]any, 0, batchSize)
+			for ctx.Err() == nil {
+				end := claimed.Add(batchSize)
+				first := end - batchSize
+				if first >= count {
+					return
+				}
+				docs = docs[:0]
+				for range min(end, count) - first {
+					docs = append(docs, g.generate(r))
+				}
+
+				result, err := c.insertMany("seed", col, docs, parsed)
+				if err != nil {
+					progress.fail(err)
+					cancel()
+					return
+				}
+				progress.processed.Add(int64(len(docs)))
+				progress.inserted.Add(result.InsertedCount)
+				progress.duplicates.Add(result.DuplicateCount)
+				progress.batches.Add(1)
+				if len(result.WriteErrors) > 0 {
+					progress.writeErrors.Add(int64(len(result.WriteErrors)))
+					progress.firstWriteError.Do(func() {
+						c.logf("Seeding %s.%s: write error: %s", database, collection, result.WriteErrors[0].Message)
+					})
+				}
+				c.recordWrite("seed", database, collection, unacknowledged, int(result.InsertedCount))
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	if interval > 0 {
+		go c.reportSeedProgress(done, interval, database, collection, count, start, progress)
+	}
+	wg.Wait()
+	close(done)
+
+	elapsed := time.Since(start)
+	result := &SeedResult{
+		Inserted:       progress.inserted.Load(),
+		Duplicates:     progress.duplicates.Load(),
+		WriteErrors:    progress.writeErrors.Load(),
+		Batches:        progress.batches.Load(),
+		DurationMillis: metrics.D(elapsed),
+	}
+	if elapsed > 0 {
+		result.DocsPerSecond = float64(result.Inserted) / elapsed.Seconds()
+	}
+
+	if progress.err == nil && ctx.Err() != nil {
+		progress.err = ctx.Err()
+	}
+	if progress.err != nil {
+		log.Printf(errSeeding, database, collection, progress.err)
+		return nil, fmt.Errorf("seed stopped after %d documents: %w", result.Inserted, progress.err)
+	}
+	c.logf("Seeded %s.%s: %d documents in %s (%.0f docs/s)", database, collection,
+		result.Inserted, elapsed.Round(time.Millisecond), result.DocsPerSecond)
+	return result, nil
+}
+
+func (c *Client) reportSeedProgress(done <-chan struct{}, interval time.Duration, database, collection string,
+	count int64, start time.Time, progress *batchProgress,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			processed := progress.processed.Load()
+			c.logf("Seeding %s.%s: %d/%d documents (%.1f%%), %d inserted, %.0f docs/s", database, collection,
+				processed, count, float64(processed)*100/float64(count), progress.inserted.Load(),
+				float64(processed)/time.Since(start).Seconds())
+		}
+	}
+}
+
+// logf logs through the k6 logger while a VU is running, so that the
+// message shows up in the k6 output, and through the standard logger
+// otherwise.
+func (c *Client) logf(format string, args ...any) {
+	if c.vu != nil {
+		if state := c.vu.State(); state != nil && state.Logger != nil {
+			state.Logger.Infof(format, args...)
+			return
+		}
+	}
+	log.Printf(format, args...)
+}
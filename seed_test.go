@@ -0,0 +1,89 @@
+package xk6_mongo
+
+import (
+	"os"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSeedValidation(t *testing.T) {
+	client := &Client{}
+	g := mustGenerator(t, `{n: {$gen: "int"}}`, nil)
+
+	tests := map[string]map[string]any{
+		"no generator":      {"count": int64(10)},
+		"not a generator":   {"generator": map[string]any{"n": 1}, "count": int64(10)},
+		"no count":          {"generator": g},
+		"zero count":        {"generator": g, "count": int64(0)},
+		"zero workers":      {"generator": g, "count": int64(10), "workers": int64(0)},
+		"zero batch size":   {"generator": g, "count": int64(10), "batchSize": int64(0)},
+		"negative progress": {"generator": g, "count": int64(10), "progressInterval": int64(-1)},
+		"unknown option":    {"generator": g, "count": int64(10), "threads": int64(2)},
+	}
+	for name, opts := range tests {
+		if _, err := client.Seed("db", "col", opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := client.Seed("", "col", map[string]any{"generator": g, "count": int64(1)}); err == nil {
+		t.Error("Expected an error for an empty database name")
+	}
+}
+
+func TestSeed(t *testing.T) {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("MONGODB_URI not set")
+	}
+
+	client := new(Mongo).NewClient(uri)
+	if client == nil {
+		t.Fatal("Failed to create client")
+	}
+	defer func() {
+		_ = client.Disconnect()
+	}()
+
+	db, col := "seedtest", "docs"
+	_ = client.DropCollection(db, col)
+	defer func() {
+		_ = client.DropCollection(db, col)
+	}()
+
+	template := `{_id: {$gen: "sequence"}, name: {$gen: "name"}, note: {$gen: "lorem", bytes: 64}}`
+	result, err := client.Seed(db, col, map[string]any{
+		"generator": mustGenerator(t, template, nil), "count": int64(2500), "batchSize": int64(300), "workers": int64(3),
+	})
+	if err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+	if result.Inserted != 2500 || result.Batches != 9 || result.DocsPerSecond <= 0 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	count, err := client.CountDocuments(db, col, bson.M{}, nil)
+	if err != nil || count != 2500 {
+		t.Errorf("Expected 2500 documents, got %d (%v)", count, err)
+	}
+
+	// A new generator restarts the sequence, so every _id already exists.
+	result, err = client.Seed(db, col, map[string]any{
+		"generator": mustGenerator(t, template, nil), "count": int64(500), "ignoreDuplicates": true,
+	})
+	if err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+	if result.Inserted != 0 || result.Duplicates != 500 || result.WriteErrors != 0 {
+		t.Errorf("Expected every document to be a duplicate, got %+v", result)
+	}
+
+	result, err = client.Seed(db, col, map[string]any{
+		"generator": mustGenerator(t, template, nil), "count": int64(3000), "ignoreDuplicates": true,
+	})
+	if err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+	if result.Inserted != 500 || result.Duplicates != 2500 || result.WriteErrors != 0 {
+		t.Errorf("Expected 500 new documents and 2500 duplicates, got %+v", result)
+	}
+}
@@ -0,0 +1,271 @@
+package xk6_mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.k6.io/k6/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	errSamplingServerStatus = "Error while sampling serverStatus on %s: %v"
+	errSamplingReplication  = "Error while sampling replication status, replication lag disabled: %v"
+)
+
+// opcounterNames are the serverStatus opcounters reported as deltas.
+var opcounterNames = []string{"insert", "query", "update", "delete", "getmore", "command"}
+
+// ServerStatusSampler periodically runs serverStatus on every member of the
+// deployment and emits the results as k6 gauges tagged by host.
+type ServerStatusSampler struct {
+	client *Client
+	hosts  []string
+
+	mu         sync.Mutex
+	conns      map[string]*mongo.Client
+	opcounters map[string]map[string]int64
+	replLag    bool
+	stopped    bool
+	releaseErr error
+
+	loop *sampleLoop
+}
+
+// StartServerStatusSampler starts sampling in the background. Options:
+// interval in ms (default 5000) and hosts, the members to sample (defaults
+// to every member reported by the deployment). A sampler started from
+// setup() runs until the end of the test; one started by a VU runs until
+// the VU is done. Either stops earlier with Stop.
+func (c *Client) StartServerStatusSampler(opts map[string]any) (*ServerStatusSampler, error) {
+	if err := checkAllowedOptions(opts, "interval", "hosts"); err != nil {
+		return nil, err
+	}
+	interval, err := samplerInterval(opts)
+	if err != nil {
+		return nil, err
+	}
+	hosts, _, err := stringsOption(opts, "hosts")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ServerStatusSampler{
+		client:     c,
+		hosts:      hosts,
+		conns:      map[string]*mongo.Client{},
+		opcounters: map[string]map[string]int64{},
+		replLag:    true,
+	}
+	s.loop = newSampleLoop(c)
+	s.loop.start(interval, func() { _ = s.Sample() }, s.release)
+	return s, nil
+}
+
+// Sample samples every host immediately and returns the errors met along the
+// way. Hosts that fail are skipped until the next sample.
+func (s *ServerStatusSampler) Sample() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return errSamplerStopped
+	}
+
+	ctx, cancel := s.client.getContext()
+	defer cancel()
+
+	hosts := s.hosts
+	if len(hosts) == 0 {
+		var err error
+		if hosts, err = s.client.knownHosts(ctx); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	for _, host := range hosts {
+		status, err := s.serverStatus(ctx, host)
+		if err != nil {
+			log.Printf(errSamplingServerStatus, host, err)
+			errs = append(errs, fmt.Errorf("%s: %w", host, err))
+			continue
+		}
+		s.recordServerStatus(host, status)
+	}
+
+	if s.replLag {
+		if err := s.recordReplicationLag(ctx); err != nil {
+			// Standalone servers and mongos routers have no replication
+			// status; stop asking instead of failing every sample.
+			log.Printf(errSamplingReplication, err)
+			s.replLag = false
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stop stops sampling and closes the connections opened to each host.
+func (s *ServerStatusSampler) Stop() error {
+	s.loop.stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.releaseErr
+}
+
+// release closes the connections opened to each host once the loop is over.
+func (s *ServerStatusSampler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+
+	ctx, cancel := s.client.getContext()
+	defer cancel()
+
+	var errs []error
+	for host, conn := range s.conns {
+		if err := conn.Disconnect(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", host, err))
+		}
+	}
+	s.conns = nil
+	s.releaseErr = errors.Join(errs...)
+}
+
+func (s *ServerStatusSampler) serverStatus(ctx context.Context, host string) (bson.M, error) {
+	conn, ok := s.conns[host]
+	if !ok {
+		var err error
+		if conn, err = s.client.connectToHost(ctx, host); err != nil {
+			return nil, err
+		}
+		s.conns[host] = conn
+	}
+
+	var status bson.M
+	err := conn.Database("admin").RunCommand(ctx, bson.D{{Key: "serverStatus", Value: 1}}).Decode(&status)
+	return status, err
+}
+
+// recordServerStatus emits the gauges derived from one serverStatus reply.
+func (s *ServerStatusSampler) recordServerStatus(host string, status bson.M) {
+	c := s.client
+	if c.metrics == nil {
+		return
+	}
+	tags := map[string]string{"host": host}
+
+	current := map[string]int64{}
+	for _, op := range opcounterNames {
+		if n, ok := nestedNumber(status, "opcounters", op); ok {
+			current[op] = int64(n)
+		}
+	}
+	if previous, ok := s.opcounters[host]; ok {
+		for op, n := range current {
+			// Counters restart from zero when the server restarts.
+			if delta := n - previous[op]; delta >= 0 {
+				s.loop.emit(c.metrics.serverOpcounters, float64(delta), map[string]string{"host": host, "op": op})
+			}
+		}
+	}
+	s.opcounters[host] = current
+
+	emitNested := func(metric *metrics.Metric, tags map[string]string, path ...string) {
+		if n, ok := nestedNumber(status, path...); ok {
+			s.loop.emit(metric, n, tags)
+		}
+	}
+	emitNested(c.metrics.serverConnectionsCurrent, tags, "connections", "current")
+	emitNested(c.metrics.serverConnectionsAvailable, tags, "connections", "available")
+	emitNested(c.metrics.serverCacheDirtyBytes, tags, "wiredTiger", "cache", "tracked dirty bytes in the cache")
+	emitNested(c.metrics.serverCacheUsedBytes, tags, "wiredTiger", "cache", "bytes currently in the cache")
+
+	// MongoDB 7.0 moved execution tickets from wiredTiger.concurrentTransactions
+	// to queues.execution.
+	tickets := []string{"queues", "execution"}
+	if _, ok := nestedNumber(status, "queues", "execution", "read", "available"); !ok {
+		tickets = []string{"wiredTiger", "concurrentTransactions"}
+	}
+	for _, kind := range []string{"read", "write"} {
+		emitNested(c.metrics.serverTicketsAvailable, map[string]string{"host": host, "type": kind},
+			append(tickets, kind, "available")...)
+	}
+
+	for _, kind := range []string{"readers", "writers"} {
+		emitNested(c.metrics.serverGlobalLockQueue, map[string]string{"host": host, "type": kind},
+			"globalLock", "currentQueue", kind)
+	}
+}
+
+// recordReplicationLag emits how far each secondary's last applied operation
+// trails the primary's.
+func (s *ServerStatusSampler) recordReplicationLag(ctx context.Context) error {
+	c := s.client
+
+	var status struct {
+		Members []struct {
+			Name       string    `bson:"name"`
+			StateStr   string    `bson:"stateStr"`
+			OptimeDate time.Time `bson:"optimeDate"`
+		} `bson:"members"`
+	}
+	err := c.client.Database("admin").RunCommand(ctx, bson.D{{Key: "replSetGetStatus", Value: 1}}).Decode(&status)
+	if err != nil {
+		return err
+	}
+	if c.metrics == nil {
+		return nil
+	}
+
+	var primary time.Time
+	for _, member := range status.Members {
+		if member.StateStr == "PRIMARY" {
+			primary = member.OptimeDate
+		}
+	}
+	if primary.IsZero() {
+		return nil
+	}
+	for _, member := range status.Members {
+		if member.StateStr != "SECONDARY" {
+			continue
+		}
+		lag := primary.Sub(member.OptimeDate)
+		if lag < 0 {
+			lag = 0
+		}
+		s.loop.emit(c.metrics.serverReplicationLag, metrics.D(lag), map[string]string{"host": member.Name})
+	}
+	return nil
+}
+
+// nestedNumber reads the number stored under path in a command reply.
+func nestedNumber(doc bson.M, path ...string) (float64, bool) {
+	var value any = doc
+	for _, key := range path {
+		m, ok := stageDocument(value)
+		if !ok {
+			return 0, false
+		}
+		if value, ok = m[key]; !ok {
+			return 0, false
+		}
+	}
+	switch v := value.(type) {
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
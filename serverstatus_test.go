@@ -0,0 +1,112 @@
+package xk6_mongo
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestNestedNumber(t *testing.T) {
+	doc := bson.M{"connections": bson.M{"current": int32(12), "available": int64(800)}, "uptime": 3.5}
+
+	if n, ok := nestedNumber(doc, "connections", "current"); !ok || n != 12 {
+		t.Errorf("Expected 12, got %v (%v)", n, ok)
+	}
+	if n, ok := nestedNumber(doc, "uptime"); !ok || n != 3.5 {
+		t.Errorf("Expected 3.5, got %v (%v)", n, ok)
+	}
+	if _, ok := nestedNumber(doc, "connections", "missing"); ok {
+		t.Error("Expected missing path to fail")
+	}
+	if _, ok := nestedNumber(doc, "uptime", "seconds"); ok {
+		t.Error("Expected path through a number to fail")
+	}
+}
+
+func TestStartServerStatusSamplerValidation(t *testing.T) {
+	client := &Client{}
+
+	invalid := map[string]map[string]any{
+		"unknown option":   {"period": 1000},
+		"negative":         {"interval": int64(-1)},
+		"hosts not string": {"hosts": []any{1}},
+	}
+	for name, raw := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := client.StartServerStatusSampler(raw); err == nil {
+				t.Error("Expected error for invalid options")
+			}
+		})
+	}
+}
+
+func TestRecordServerStatusMetrics(t *testing.T) {
+	client, samples := newMetricsTestClient(t)
+	sampler := &ServerStatusSampler{client: client, opcounters: map[string]map[string]int64{}, loop: newSampleLoop(client)}
+	defer sampler.loop.cancel()
+
+	status := func(inserts int64, tickets bson.M) bson.M {
+		return bson.M{
+			"opcounters":  bson.M{"insert": inserts, "query": int64(0)},
+			"connections": bson.M{"current": int32(10), "available": int32(90)},
+			"wiredTiger": bson.M{
+				"cache": bson.M{
+					"tracked dirty bytes in the cache": int64(1024),
+					"bytes currently in the cache":     int64(4096),
+				},
+				"concurrentTransactions": bson.M{
+					"read":  bson.M{"available": int32(128)},
+					"write": bson.M{"available": int32(127)},
+				},
+			},
+			"queues":     tickets,
+			"globalLock": bson.M{"currentQueue": bson.M{"readers": int32(1), "writers": int32(2)}},
+		}
+	}
+
+	// The first sample only sets the opcounter baseline.
+	sampler.recordServerStatus("db1:27017", status(100, nil))
+	for _, sample := range collectSamples(samples) {
+		if sample.Metric.Name == "mongo_server_opcounters_delta" {
+			t.Error("Expected no opcounter delta on the first sample")
+		}
+	}
+
+	sampler.recordServerStatus("db1:27017", status(150, bson.M{"execution": bson.M{
+		"read":  bson.M{"available": int32(8)},
+		"write": bson.M{"available": int32(7)},
+	}}))
+
+	got := map[string]float64{}
+	for _, sample := range collectSamples(samples) {
+		if host, _ := sample.Tags.Get("host"); host != "db1:27017" {
+			t.Errorf("Expected host tag db1:27017, got %q", host)
+		}
+		key := sample.Metric.Name
+		if op, ok := sample.Tags.Get("op"); ok {
+			key += "." + op
+		}
+		if kind, ok := sample.Tags.Get("type"); ok {
+			key += "." + kind
+		}
+		got[key] = sample.Value
+	}
+
+	want := map[string]float64{
+		"mongo_server_opcounters_delta.insert":  50,
+		"mongo_server_opcounters_delta.query":   0,
+		"mongo_server_connections_current":      10,
+		"mongo_server_connections_available":    90,
+		"mongo_server_wt_cache_dirty_bytes":     1024,
+		"mongo_server_wt_cache_used_bytes":      4096,
+		"mongo_server_tickets_available.read":   8,
+		"mongo_server_tickets_available.write":  7,
+		"mongo_server_globallock_queue.readers": 1,
+		"mongo_server_globallock_queue.writers": 2,
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("Expected %s = %v, got %v", key, value, got[key])
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package xk6_mongo
+
+import (
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const errCollectingStats = "Error while collecting stats: %v"
+
+var errSnapshotNil = errors.New("stats snapshot cannot be nil")
+
+// Fields kept from dbStats and from the storageStats of $collStats.
+var (
+	dbStatsFields         = []string{"collections", "objects", "dataSize", "storageSize", "indexSize"}
+	collectionStatsFields = []string{"count", "size", "storageSize", "totalIndexSize"}
+)
+
+// StatsSnapshot captures dbStats, $collStats and $indexStats for a database.
+// collections limits the snapshot to the named collections; by default every
+// collection returned by ListCollections is included, except views and
+// system collections.
+//
+// The snapshot is a plain document so it can be returned from setup() and
+// diffed against a later snapshot in handleSummary() with DiffStats:
+//
+//	{database, takenAt, db: {collections, objects, dataSize, storageSize, indexSize},
+//	 collections: {<name>: {count, size, storageSize, avgObjSize, totalIndexSize,
+//	                        indexes: {<name>: {key, ops}}}}}
+func (c *Client) StatsSnapshot(database string, collections []string) (bson.M, error) {
+	if database == "" {
+		return nil, errDatabaseEmpty
+	}
+
+	ctx, cancel := c.getContext()
+	defer cancel()
+
+	var dbStats bson.M
+	if err := c.client.Database(database).RunCommand(ctx, bson.D{{Key: "dbStats", Value: 1}}).Decode(&dbStats); err != nil {
+		log.Printf(errCollectingStats, err)
+		return nil, err
+	}
+
+	if len(collections) == 0 {
+		listed, err := c.ListCollections(database)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range listed {
+			name, _ := info["name"].(string)
+			if kind, _ := info["type"].(string); kind == "view" || strings.HasPrefix(name, "system.") {
+				continue
+			}
+			collections = append(collections, name)
+		}
+	}
+
+	perCollection := bson.M{}
+	for _, collection := range collections {
+		stats, err := c.collectionStats(database, collection)
+		if err != nil {
+			return nil, err
+		}
+		perCollection[collection] = stats
+	}
+
+	return bson.M{
+		"database":    database,
+		"takenAt":     time.Now().UTC().Format(time.RFC3339Nano),
+		"db":          pickNumbers(dbStats, dbStatsFields),
+		"collections": perCollection,
+	}, nil
+}
+
+// collectionStats combines $collStats with the index definitions and the
+// $indexStats access counters of one collection.
+func (c *Client) collectionStats(database, collection string) (bson.M, error) {
+	collStats, err := c.Aggregate(database, collection, bson.A{
+		bson.M{"$collStats": bson.M{"storageStats": bson.M{}}},
+	}, nil)
+	if err != nil {
+		log.Printf(errCollectingStats, err)
+		return nil, err
+	}
+	stats := mergeCollStats(collStats)
+
+	definitions, err := c.ListIndexes(database, collection)
+	if err != nil {
+		return nil, err
+	}
+	indexStats, err := c.Aggregate(database, collection, bson.A{bson.M{"$indexStats": bson.M{}}}, nil)
+	if err != nil {
+		log.Printf(errCollectingStats, err)
+		return nil, err
+	}
+	stats["indexes"] = mergeIndexStats(definitions, indexStats)
+	return stats, nil
+}
+
+// mergeCollStats sums the storage statistics of $collStats, which returns
+// one document per shard on sharded collections.
+func mergeCollStats(docs []bson.M) bson.M {
+	totals := bson.M{}
+	for _, field := range collectionStatsFields {
+		var sum float64
+		for _, doc := range docs {
+			n, _ := nestedNumber(doc, "storageStats", field)
+			sum += n
+		}
+		totals[field] = statsValue(sum)
+	}
+
+	var avgObjSize float64
+	if count := statsNumber(totals["count"]); count > 0 {
+		avgObjSize = statsNumber(totals["size"]) / count
+	}
+	totals["avgObjSize"] = statsValue(avgObjSize)
+	return totals
+}
+
+// mergeIndexStats lists every index defined on a collection together with
+// the number of operations that used it, summed across shards.
+func mergeIndexStats(definitions []bson.M, stats []bson.M) bson.M {
+	indexes := bson.M{}
+	for _, def := range definitions {
+		name, _ := def["name"].(string)
+		indexes[name] = bson.M{"key": def["key"], "ops": int64(0)}
+	}
+	for _, doc := range stats {
+		name, _ := doc["name"].(string)
+		ops, _ := nestedNumber(doc, "accesses", "ops")
+		index, ok := indexes[name].(bson.M)
+		if !ok {
+			index = bson.M{"key": doc["key"], "ops": int64(0)}
+			indexes[name] = index
+		}
+		index["ops"] = statsValue(statsNumber(index["ops"]) + ops)
+	}
+	return indexes
+}
+
+// DiffStats compares two snapshots taken by StatsSnapshot and reports how
+// the database changed in between:
+//
+//	{seconds, db: {collections, objects, dataSize, storageSize, indexSize},
+//	 collections: {<name>: {count, size, storageSize, totalIndexSize,
+//	                        indexes: {<name>: {ops}}, usedIndexes, unusedIndexes}},
+//	 createdCollections, droppedCollections}
+//
+// Numbers are after minus before. Collections and indexes missing from
+// before count from zero.
+func (c *Client) DiffStats(before map[string]any, after map[string]any) (bson.M, error) {
+	if before == nil || after == nil {
+		return nil, errSnapshotNil
+	}
+
+	diff := bson.M{
+		"db": diffNumbers(documentField(before, "db"), documentField(after, "db"), dbStatsFields),
+	}
+	if seconds, ok := snapshotInterval(before, after); ok {
+		diff["seconds"] = seconds
+	}
+
+	collections := bson.M{}
+	created := []string{}
+	dropped := []string{}
+	beforeCollections := documentField(before, "collections")
+	afterCollections := documentField(after, "collections")
+	for _, name := range sortedKeys(afterCollections) {
+		old, existed := stageDocument(beforeCollections[name])
+		if !existed {
+			created = append(created, name)
+		}
+		current, _ := stageDocument(afterCollections[name])
+		collections[name] = diffCollectionStats(old, current)
+	}
+	for _, name := range sortedKeys(beforeCollections) {
+		if _, ok := afterCollections[name]; !ok {
+			dropped = append(dropped, name)
+		}
+	}
+
+	diff["collections"] = collections
+	diff["createdCollections"] = created
+	diff["droppedCollections"] = dropped
+	return diff, nil
+}
+
+func diffCollectionStats(before, after map[string]any) bson.M {
+	diff := diffNumbers(before, after, collectionStatsFields)
+
+	indexes := bson.M{}
+	used := []string{}
+	unused := []string{}
+	beforeIndexes := documentField(before, "indexes")
+	afterIndexes := documentField(after, "indexes")
+	for _, name := range sortedKeys(afterIndexes) {
+		ops := diffNumbers(documentField(beforeIndexes, name), documentField(afterIndexes, name), []string{"ops"})
+		indexes[name] = ops
+		if statsNumber(ops["ops"]) > 0 {
+			used = append(used, name)
+		} else {
+			unused = append(unused, name)
+		}
+	}
+	diff["indexes"] = indexes
+	diff["usedIndexes"] = used
+	diff["unusedIndexes"] = unused
+	return diff
+}
+
+// snapshotInterval returns the seconds elapsed between two snapshots.
+func snapshotInterval(before, after map[string]any) (float64, bool) {
+	from, ok := snapshotTime(before["takenAt"])
+	if !ok {
+		return 0, false
+	}
+	to, ok := snapshotTime(after["takenAt"])
+	if !ok {
+		return 0, false
+	}
+	return to.Sub(from).Seconds(), true
+}
+
+// snapshotTime reads a point in time given as a Date, an ISO-8601 string or
+// milliseconds since the epoch.
+func snapshotTime(value any) (time.Time, bool) {
+	if dt, ok := value.(primitive.DateTime); ok {
+		return dt.Time(), true
+	}
+	t, err := timeValue(value)
+	return t, err == nil
+}
+
+func diffNumbers(before, after map[string]any, fields []string) bson.M {
+	out := bson.M{}
+	for _, field := range fields {
+		out[field] = statsValue(statsNumber(after[field]) - statsNumber(before[field]))
+	}
+	return out
+}
+
+func pickNumbers(doc bson.M, fields []string) bson.M {
+	out := bson.M{}
+	for _, field := range fields {
+		n, _ := nestedNumber(doc, field)
+		out[field] = statsValue(n)
+	}
+	return out
+}
+
+// documentField returns the sub-document stored under key, or nil.
+func documentField(doc map[string]any, key string) map[string]any {
+	m, _ := stageDocument(doc[key])
+	return m
+}
+
+func sortedKeys(doc map[string]any) []string {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// statsNumber reads a number that may have been through a JSON round trip,
+// e.g. when a snapshot is returned from setup().
+func statsNumber(value any) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	}
+	n, _ := toInt64(value)
+	return float64(n)
+}
+
+// statsValue keeps whole numbers integral so that counts and byte sizes do
+// not render as floats.
+func statsValue(n float64) any {
+	if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+		return int64(n)
+	}
+	return n
+}
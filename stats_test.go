@@ -0,0 +1,142 @@
+package xk6_mongo
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMergeCollStats(t *testing.T) {
+	got := mergeCollStats([]bson.M{
+		{"shard": "a", "storageStats": bson.M{"count": int32(10), "size": int32(1000), "storageSize": int32(4096), "totalIndexSize": int32(2048)}},
+		{"shard": "b", "storageStats": bson.M{"count": int64(30), "size": int64(2000), "storageSize": int64(8192), "totalIndexSize": int64(2048)}},
+	})
+	want := bson.M{"count": int64(40), "size": int64(3000), "storageSize": int64(12288), "totalIndexSize": int64(4096), "avgObjSize": int64(75)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeCollStats = %v, want %v", got, want)
+	}
+
+	if empty := mergeCollStats(nil); empty["avgObjSize"] != int64(0) {
+		t.Errorf("Expected avgObjSize 0 for an empty collection, got %v", empty["avgObjSize"])
+	}
+}
+
+func TestMergeIndexStats(t *testing.T) {
+	got := mergeIndexStats(
+		[]bson.M{
+			{"name": "_id_", "key": bson.M{"_id": int32(1)}},
+			{"name": "by_user", "key": bson.M{"user": int32(1)}},
+		},
+		[]bson.M{
+			{"name": "by_user", "accesses": bson.M{"ops": int64(5)}},
+			{"name": "by_user", "accesses": bson.M{"ops": int64(7)}},
+		},
+	)
+	if ops := got["by_user"].(bson.M)["ops"]; ops != int64(12) {
+		t.Errorf("Expected by_user ops 12, got %v", ops)
+	}
+	if ops := got["_id_"].(bson.M)["ops"]; ops != int64(0) {
+		t.Errorf("Expected _id_ ops 0, got %v", ops)
+	}
+}
+
+func TestSnapshotTime(t *testing.T) {
+	want := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	valid := map[string]any{
+		"iso string":   "2026-01-01T00:00:00Z",
+		"date":         want,
+		"bson date":    primitive.NewDateTimeFromTime(want),
+		"milliseconds": want.UnixMilli(),
+		"float ms":     float64(want.UnixMilli()),
+	}
+	for name, value := range valid {
+		if got, ok := snapshotTime(value); !ok || !got.Equal(want) {
+			t.Errorf("%s: expected %v, got %v (%v)", name, want, got, ok)
+		}
+	}
+	for name, value := range map[string]any{"bad string": "yesterday", "bool": true, "nil": nil} {
+		if _, ok := snapshotTime(value); ok {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDiffStats(t *testing.T) {
+	before := bson.M{
+		"takenAt": "2026-01-01T00:00:00Z",
+		"db":      bson.M{"collections": int64(2), "objects": int64(100), "dataSize": int64(5000), "storageSize": int64(8192), "indexSize": int64(4096)},
+		"collections": bson.M{
+			"orders": bson.M{"count": int64(100), "size": int64(5000), "storageSize": int64(8192), "totalIndexSize": int64(4096),
+				"indexes": bson.M{"_id_": bson.M{"ops": int64(3)}, "by_user": bson.M{"ops": int64(0)}}},
+			"legacy": bson.M{"count": int64(0)},
+		},
+	}
+	after := bson.M{
+		"takenAt": "2026-01-01T00:01:30Z",
+		"db":      bson.M{"collections": int64(2), "objects": int64(250), "dataSize": int64(12500), "storageSize": int64(16384), "indexSize": int64(8192)},
+		"collections": bson.M{
+			"orders": bson.M{"count": int64(200), "size": int64(10000), "storageSize": int64(12288), "totalIndexSize": int64(6144),
+				"indexes": bson.M{"_id_": bson.M{"ops": int64(3)}, "by_user": bson.M{"ops": int64(42)}, "by_total": bson.M{"ops": int64(1)}}},
+			"events": bson.M{"count": int64(50), "size": int64(2500), "storageSize": int64(4096), "totalIndexSize": int64(2048),
+				"indexes": bson.M{"_id_": bson.M{"ops": int64(0)}}},
+		},
+	}
+
+	// Snapshots returned from setup() reach handleSummary() as JSON.
+	var roundTripped map[string]any
+	raw, err := json.Marshal(before)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	if err := json.Unmarshal(raw, &roundTripped); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+
+	diff, err := (&Client{}).DiffStats(roundTripped, after)
+	if err != nil {
+		t.Fatalf("DiffStats failed: %v", err)
+	}
+
+	if diff["seconds"] != 90.0 {
+		t.Errorf("Expected 90 seconds, got %v", diff["seconds"])
+	}
+	if objects := diff["db"].(bson.M)["objects"]; objects != int64(150) {
+		t.Errorf("Expected 150 new objects, got %v", objects)
+	}
+	if !reflect.DeepEqual(diff["createdCollections"], []string{"events"}) {
+		t.Errorf("Unexpected created collections %v", diff["createdCollections"])
+	}
+	if !reflect.DeepEqual(diff["droppedCollections"], []string{"legacy"}) {
+		t.Errorf("Unexpected dropped collections %v", diff["droppedCollections"])
+	}
+
+	orders := diff["collections"].(bson.M)["orders"].(bson.M)
+	if orders["count"] != int64(100) || orders["storageSize"] != int64(4096) {
+		t.Errorf("Unexpected orders diff %v", orders)
+	}
+	if !reflect.DeepEqual(orders["usedIndexes"], []string{"by_total", "by_user"}) {
+		t.Errorf("Unexpected used indexes %v", orders["usedIndexes"])
+	}
+	if !reflect.DeepEqual(orders["unusedIndexes"], []string{"_id_"}) {
+		t.Errorf("Unexpected unused indexes %v", orders["unusedIndexes"])
+	}
+
+	events := diff["collections"].(bson.M)["events"].(bson.M)
+	if events["count"] != int64(50) {
+		t.Errorf("Expected a new collection to count from zero, got %v", events["count"])
+	}
+
+	if _, err := (&Client{}).DiffStats(nil, after); err != errSnapshotNil {
+		t.Errorf("Expected errSnapshotNil, got %v", err)
+	}
+}
+
+func TestStatsSnapshotValidation(t *testing.T) {
+	if _, err := (&Client{}).StatsSnapshot("", nil); err != errDatabaseEmpty {
+		t.Errorf("Expected errDatabaseEmpty, got %v", err)
+	}
+}
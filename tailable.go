@@ -0,0 +1,259 @@
+package xk6_mongo
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"go.k6.io/k6/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	errCreatingCollection    = "Error while creating collection: %v"
+	errOpeningTailableCursor = "Error while opening tailable cursor: %v"
+
+	// tailablePollInterval spaces out getMore calls of cursors opened without
+	// awaitData, which return immediately when no document is available.
+	tailablePollInterval = 50 * time.Millisecond
+)
+
+var (
+	errCappedSize           = errors.New("capped collection size must be positive")
+	errTailableCursorClosed = errors.New("tailable cursor is closed")
+)
+
+// TailableCursor is a handle to a tailable cursor on a capped collection.
+type TailableCursor struct {
+	cursor       *mongo.Cursor
+	client       *Client
+	namespace    string
+	latencyField string
+	awaitData    bool
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// CreateCappedCollection creates a capped collection of sizeBytes, optionally
+// limited to maxDocuments (0 means no document limit).
+func (c *Client) CreateCappedCollection(database string, collection string, sizeBytes int64, maxDocuments int64) error {
+	if sizeBytes <= 0 {
+		return errCappedSize
+	}
+	if err := validateDatabaseAndCollection(database, collection); err != nil {
+		log.Printf(errValidatingCollection, err)
+		return err
+	}
+
+	opts := options.CreateCollection().SetCapped(true).SetSizeInBytes(sizeBytes)
+	if maxDocuments > 0 {
+		opts.SetMaxDocuments(maxDocuments)
+	}
+
+	ctx, cancel := c.getContext()
+	defer cancel()
+
+	if err := c.client.Database(database).CreateCollection(ctx, collection, opts); err != nil {
+		log.Printf(errCreatingCollection, err)
+		return err
+	}
+	log.Printf("Capped collection created successfully: %s.%s", database, collection)
+	return nil
+}
+
+// OpenTailableCursor opens a tailable cursor on a capped collection. Options:
+// awaitData (default true), maxAwaitTime in ms, batchSize and latencyField,
+// the Date field in which the producer stores when it sent the document,
+// used to compute delivery latency. Without it, only documents are counted.
+func (c *Client) OpenTailableCursor(database string, collection string, filter any, opts map[string]any) (*TailableCursor, error) {
+	findOpts, err := tailableFindOptionsFromMap(opts)
+	if err != nil {
+		return nil, err
+	}
+	latencyField, _, err := stringOption(opts, "latencyField")
+	if err != nil {
+		return nil, err
+	}
+
+	col, err := c.getCollection(database, collection)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	if filter == nil {
+		filter = bson.D{}
+	}
+
+	ctx, cancel := c.getContext()
+	defer cancel()
+
+	cur, err := col.Find(ctx, filter, findOpts)
+	if err != nil {
+		log.Printf(errOpeningTailableCursor, err)
+		return nil, err
+	}
+
+	return &TailableCursor{
+		cursor:       cur,
+		client:       c,
+		namespace:    database + "." + collection,
+		latencyField: latencyField,
+		awaitData:    *findOpts.CursorType == options.TailableAwait,
+	}, nil
+}
+
+// TryNext returns the next document if one is available, otherwise null.
+func (t *TailableCursor) TryNext() (bson.M, error) {
+	ctx, cancel := t.client.getContext()
+	defer cancel()
+	return t.tryNext(ctx)
+}
+
+func (t *TailableCursor) tryNext(ctx context.Context) (bson.M, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil, errTailableCursorClosed
+	}
+
+	if !t.cursor.TryNext(ctx) {
+		return nil, t.cursor.Err()
+	}
+	receivedAt := time.Now()
+
+	var doc bson.M
+	if err := t.cursor.Decode(&doc); err != nil {
+		log.Printf(errDecodingDocuments, err)
+		return nil, err
+	}
+	t.client.recordTailableDocument(doc, t.latencyField, t.namespace, receivedAt)
+	return doc, nil
+}
+
+// Next waits for the next document for up to the client's operation timeout
+// and returns null if none arrived in that window, the cursor died or the
+// VU was interrupted. Each poll gets its own timeout: a deadline that expires
+// inside the driver would leave the cursor unusable.
+func (t *TailableCursor) Next() (bson.M, error) {
+	var interrupted <-chan struct{}
+	if t.client.vu != nil && t.client.vu.Context() != nil {
+		interrupted = t.client.vu.Context().Done()
+	}
+	deadline := time.Now().Add(t.client.defaultTimeout)
+
+	for {
+		doc, err := t.TryNext()
+		if err != nil || doc != nil || !t.IsAlive() {
+			return doc, err
+		}
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return nil, nil
+		}
+		if t.awaitData {
+			// The server already waited for data before replying.
+			wait = 0
+		}
+		select {
+		case <-interrupted:
+			return nil, nil
+		case <-time.After(min(wait, tailablePollInterval)):
+		}
+	}
+}
+
+// IsAlive reports whether the server still holds the cursor open. A tailable
+// cursor dies when its position in the capped collection is overwritten.
+func (t *TailableCursor) IsAlive() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.closed && t.cursor.ID() != 0
+}
+
+// Close releases the server-side cursor.
+func (t *TailableCursor) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+
+	ctx, cancel := t.client.getContext()
+	defer cancel()
+	return t.cursor.Close(ctx)
+}
+
+// recordTailableDocument emits the delivery latency of a tailed document,
+// measured from the Date stored in latencyField. Documents without a Date
+// there are only counted.
+func (c *Client) recordTailableDocument(doc bson.M, latencyField string, namespace string, receivedAt time.Time) {
+	if c.metrics == nil {
+		return
+	}
+
+	tags := map[string]string{"namespace": namespace}
+	c.emit(c.metrics.tailableDocuments, 1, tags)
+
+	if latencyField == "" {
+		return
+	}
+	if at, ok := documentTime(doc[latencyField]); ok {
+		c.emit(c.metrics.tailableLatency, metrics.D(receivedAt.Sub(at)), tags)
+	}
+}
+
+// documentTime extracts a point in time from a Date. Other types, such as
+// ObjectIds with their one-second precision or numbers that may not be
+// timestamps, are rejected.
+func documentTime(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case primitive.DateTime:
+		return v.Time(), true
+	case time.Time:
+		return v, true
+	}
+	return time.Time{}, false
+}
+
+func tailableFindOptionsFromMap(raw map[string]any) (*options.FindOptions, error) {
+	opts := options.Find().SetCursorType(options.TailableAwait)
+
+	awaitData, ok, err := boolOption(raw, "awaitData")
+	if err != nil {
+		return nil, err
+	}
+	if ok && !awaitData {
+		opts.SetCursorType(options.Tailable)
+	}
+
+	maxAwaitTime, ok, err := durationOption(raw, "maxAwaitTime")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if *opts.CursorType != options.TailableAwait {
+			return nil, errors.New("option maxAwaitTime requires awaitData")
+		}
+		opts.SetMaxAwaitTime(maxAwaitTime)
+	}
+
+	batchSize, ok, err := batchSizeOption(raw)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		opts.SetBatchSize(batchSize)
+	}
+
+	return opts, nil
+}
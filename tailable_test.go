@@ -0,0 +1,176 @@
+package xk6_mongo
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestTailableFindOptionsFromMap(t *testing.T) {
+	t.Run("defaults to tailable await", func(t *testing.T) {
+		opts, err := tailableFindOptionsFromMap(nil)
+		if err != nil {
+			t.Fatalf("tailableFindOptionsFromMap failed: %v", err)
+		}
+		if *opts.CursorType != options.TailableAwait {
+			t.Errorf("Expected TailableAwait, got %v", *opts.CursorType)
+		}
+	})
+
+	t.Run("awaitData disabled", func(t *testing.T) {
+		opts, err := tailableFindOptionsFromMap(map[string]any{"awaitData": false, "batch_size": int64(10)})
+		if err != nil {
+			t.Fatalf("tailableFindOptionsFromMap failed: %v", err)
+		}
+		if *opts.CursorType != options.Tailable {
+			t.Errorf("Expected Tailable, got %v", *opts.CursorType)
+		}
+		if *opts.BatchSize != 10 {
+			t.Errorf("Expected batchSize 10, got %d", *opts.BatchSize)
+		}
+	})
+
+	t.Run("maxAwaitTime without awaitData", func(t *testing.T) {
+		_, err := tailableFindOptionsFromMap(map[string]any{"awaitData": false, "maxAwaitTime": int64(100)})
+		if err == nil {
+			t.Error("Expected error for maxAwaitTime without awaitData")
+		}
+	})
+}
+
+func TestDocumentTime(t *testing.T) {
+	at := time.UnixMilli(1700000000123)
+	oid := primitive.NewObjectIDFromTimestamp(time.Unix(1700000000, 0))
+
+	tests := []struct {
+		name  string
+		input any
+		want  time.Time
+	}{
+		{"date", primitive.NewDateTimeFromTime(at), at},
+		{"time", at, at},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := documentTime(tt.input)
+			if !ok || !got.Equal(tt.want) {
+				t.Errorf("documentTime(%v) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	for _, value := range []any{"now", oid, int64(1700000000123), int32(42), nil} {
+		if _, ok := documentTime(value); ok {
+			t.Errorf("Expected %T to be rejected", value)
+		}
+	}
+}
+
+func TestRecordTailableDocument(t *testing.T) {
+	client, samples := newMetricsTestClient(t)
+	now := time.Now()
+	sentAt := primitive.NewDateTimeFromTime(now.Add(-250 * time.Millisecond))
+
+	client.recordTailableDocument(bson.M{"_id": int64(7), "sentAt": sentAt}, "", "db.events", now)
+	client.recordTailableDocument(bson.M{"_id": int64(8), "sentAt": int64(123)}, "sentAt", "db.events", now)
+	client.recordTailableDocument(bson.M{"_id": int64(9), "sentAt": sentAt}, "sentAt", "db.events", now)
+
+	var documents, latencies []float64
+	for _, sample := range collectSamples(samples) {
+		switch sample.Metric.Name {
+		case "mongo_tailable_documents":
+			documents = append(documents, sample.Value)
+		case "mongo_tailable_delivery_latency":
+			latencies = append(latencies, sample.Value)
+		}
+	}
+	if len(documents) != 3 {
+		t.Errorf("Expected every document to be counted, got %v", documents)
+	}
+	if len(latencies) != 1 || latencies[0] < 249 || latencies[0] > 251 {
+		t.Errorf("Expected a single 250ms latency from the Date field, got %v", latencies)
+	}
+}
+
+func TestTailableValidation(t *testing.T) {
+	client := &Client{}
+
+	t.Run("non-positive capped size", func(t *testing.T) {
+		err := client.CreateCappedCollection("db", "col", 0, 0)
+		if err != errCappedSize {
+			t.Errorf("Expected errCappedSize, got %v", err)
+		}
+	})
+
+	t.Run("empty collection for capped collection", func(t *testing.T) {
+		err := client.CreateCappedCollection("db", "", 1024, 0)
+		if err == nil {
+			t.Error("Expected error for empty collection")
+		}
+	})
+
+	t.Run("empty database for tailable cursor", func(t *testing.T) {
+		_, err := client.OpenTailableCursor("", "col", bson.M{}, nil)
+		if err == nil {
+			t.Error("Expected error for empty database")
+		}
+	})
+}
+
+func TestTailableCursorNextAfterTimeout(t *testing.T) {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("MONGODB_URI not set")
+	}
+
+	client := new(Mongo).NewClient(uri)
+	if client == nil {
+		t.Fatal("Failed to create client")
+	}
+	defer func() {
+		_ = client.Disconnect()
+	}()
+	client.defaultTimeout = 2 * time.Second
+
+	db, col := "tailabletest", "events"
+	_ = client.DropCollection(db, col)
+	defer func() {
+		_ = client.DropCollection(db, col)
+	}()
+
+	if err := client.CreateCappedCollection(db, col, 1<<20, 0); err != nil {
+		t.Fatalf("CreateCappedCollection failed: %v", err)
+	}
+	if err := client.Insert(db, col, bson.M{"_id": 1}, nil); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	cursor, err := client.OpenTailableCursor(db, col, bson.M{}, map[string]any{"maxAwaitTime": int64(500)})
+	if err != nil {
+		t.Fatalf("OpenTailableCursor failed: %v", err)
+	}
+	defer func() {
+		_ = cursor.Close()
+	}()
+
+	if doc, err := cursor.Next(); err != nil || doc["_id"] != int32(1) {
+		t.Fatalf("Expected the first document, got %v (%v)", doc, err)
+	}
+	if doc, err := cursor.Next(); err != nil || doc != nil {
+		t.Fatalf("Expected no document on a quiet collection, got %v (%v)", doc, err)
+	}
+
+	// The timed out next() must leave the cursor usable.
+	if err := client.Insert(db, col, bson.M{"_id": 2}, nil); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if doc, err := cursor.Next(); err != nil || doc["_id"] != int32(2) {
+		t.Errorf("Expected the document inserted after the timeout, got %v (%v)", doc, err)
+	}
+	if doc, err := cursor.TryNext(); err != nil || doc != nil {
+		t.Errorf("Expected tryNext to find nothing without failing, got %v (%v)", doc, err)
+	}
+}
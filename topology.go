@@ -0,0 +1,217 @@
+package xk6_mongo
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.k6.io/k6/metrics"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/description"
+)
+
+// TopologySnapshot describes the deployment as currently seen by the driver.
+type TopologySnapshot struct {
+	// Kind is the driver's topology type, e.g. ReplicaSetWithPrimary,
+	// ReplicaSetNoPrimary, Sharded or Single.
+	Kind    string           `js:"kind"`
+	SetName string           `js:"setName"`
+	Servers []ServerSnapshot `js:"servers"`
+	// Changes is the number of topology changes seen since the client
+	// connected.
+	Changes int64 `js:"changes"`
+}
+
+// ServerSnapshot describes one server of the deployment.
+type ServerSnapshot struct {
+	Host string `js:"host"`
+	// Role is primary, secondary, arbiter, other, ghost, mongos,
+	// standalone, loadBalancer or unknown.
+	Role       string            `js:"role"`
+	RTTMillis  float64           `js:"rttMillis"`
+	SetName    string            `js:"setName"`
+	SetVersion int64             `js:"setVersion"`
+	ElectionID string            `js:"electionId"`
+	Tags       map[string]string `js:"tags"`
+	LastError  string            `js:"lastError"`
+	LastUpdate string            `js:"lastUpdate"`
+}
+
+// topologyWatcher receives the driver's SDAM events for a client, emits them
+// as metrics and keeps the latest topology description for Topology.
+type topologyWatcher struct {
+	client *Client
+
+	mu       sync.Mutex
+	topology description.Topology
+	rtt      map[string]time.Duration
+	changes  int64
+}
+
+func newTopologyWatcher(c *Client) *topologyWatcher {
+	return &topologyWatcher{client: c, rtt: map[string]time.Duration{}}
+}
+
+// serverMonitor returns the monitor to attach to the client options. The
+// callbacks of a monitor set by the caller keep being called.
+func (w *topologyWatcher) serverMonitor(user *event.ServerMonitor) *event.ServerMonitor {
+	m := &event.ServerMonitor{}
+	if user != nil {
+		*m = *user
+	}
+	m.TopologyDescriptionChanged = chainEvent(m.TopologyDescriptionChanged, w.topologyChanged)
+	m.ServerDescriptionChanged = chainEvent(m.ServerDescriptionChanged, w.serverChanged)
+	m.ServerHeartbeatSucceeded = chainEvent(m.ServerHeartbeatSucceeded, w.heartbeatSucceeded)
+	m.ServerHeartbeatFailed = chainEvent(m.ServerHeartbeatFailed, w.heartbeatFailed)
+	return m
+}
+
+func chainEvent[E any](first, second func(*E)) func(*E) {
+	if first == nil {
+		return second
+	}
+	return func(e *E) {
+		first(e)
+		second(e)
+	}
+}
+
+func (w *topologyWatcher) topologyChanged(e *event.TopologyDescriptionChangedEvent) {
+	w.mu.Lock()
+	w.topology = e.NewDescription
+	w.changes++
+	w.mu.Unlock()
+
+	c := w.client
+	if c.metrics != nil {
+		c.emit(c.metrics.topologyChanges, 1, map[string]string{
+			"from": e.PreviousDescription.Kind.String(),
+			"to":   e.NewDescription.Kind.String(),
+		})
+	}
+}
+
+func (w *topologyWatcher) serverChanged(e *event.ServerDescriptionChangedEvent) {
+	from, to := serverRole(e.PreviousDescription.Kind), serverRole(e.NewDescription.Kind)
+	c := w.client
+	if from == to || c.metrics == nil {
+		return
+	}
+	c.emit(c.metrics.serverKindChanges, 1, map[string]string{
+		"host": e.Address.String(),
+		"from": from,
+		"to":   to,
+	})
+}
+
+// heartbeatSucceeded records the round trip time of the heartbeat. Awaited
+// heartbeats of the streaming protocol (MongoDB 4.4+) wait for a topology
+// change or for heartbeatFrequencyMS instead of measuring the round trip,
+// so they are left out.
+func (w *topologyWatcher) heartbeatSucceeded(e *event.ServerHeartbeatSucceededEvent) {
+	if e.Awaited {
+		return
+	}
+	host := heartbeatHost(e.ConnectionID)
+
+	w.mu.Lock()
+	w.rtt[host] = e.Duration
+	w.mu.Unlock()
+
+	c := w.client
+	if c.metrics != nil {
+		c.emit(c.metrics.heartbeatRTT, metrics.D(e.Duration), map[string]string{"host": host})
+	}
+}
+
+func (w *topologyWatcher) heartbeatFailed(e *event.ServerHeartbeatFailedEvent) {
+	c := w.client
+	if c.metrics != nil {
+		c.emit(c.metrics.heartbeatFailures, 1, map[string]string{"host": heartbeatHost(e.ConnectionID)})
+	}
+}
+
+// snapshot converts the latest topology description.
+func (w *topologyWatcher) snapshot() *TopologySnapshot {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	s := &TopologySnapshot{
+		Kind:    w.topology.Kind.String(),
+		SetName: w.topology.SetName,
+		Servers: make([]ServerSnapshot, 0, len(w.topology.Servers)),
+		Changes: w.changes,
+	}
+	for _, server := range w.topology.Servers {
+		host := server.Addr.String()
+		rtt, ok := w.rtt[host]
+		if !ok {
+			rtt = server.AverageRTT
+		}
+		snap := ServerSnapshot{
+			Host:       host,
+			Role:       serverRole(server.Kind),
+			RTTMillis:  metrics.D(rtt),
+			SetName:    server.SetName,
+			SetVersion: int64(server.SetVersion),
+			Tags:       map[string]string{},
+		}
+		if !server.ElectionID.IsZero() {
+			snap.ElectionID = server.ElectionID.Hex()
+		}
+		for _, t := range server.Tags {
+			snap.Tags[t.Name] = t.Value
+		}
+		if server.LastError != nil {
+			snap.LastError = server.LastError.Error()
+		}
+		if !server.LastUpdateTime.IsZero() {
+			snap.LastUpdate = server.LastUpdateTime.UTC().Format(time.RFC3339Nano)
+		}
+		s.Servers = append(s.Servers, snap)
+	}
+	sort.Slice(s.Servers, func(i, j int) bool { return s.Servers[i].Host < s.Servers[j].Host })
+	return s
+}
+
+// Topology returns the deployment as currently seen by the driver's server
+// discovery and monitoring: its kind, replica set name and, for each server,
+// its role, round trip time, election id and tags.
+func (c *Client) Topology() *TopologySnapshot {
+	if c.topology == nil {
+		return &TopologySnapshot{Kind: "Unknown", Servers: []ServerSnapshot{}}
+	}
+	return c.topology.snapshot()
+}
+
+func serverRole(kind description.ServerKind) string {
+	switch kind {
+	case description.RSPrimary:
+		return "primary"
+	case description.RSSecondary:
+		return "secondary"
+	case description.RSArbiter:
+		return "arbiter"
+	case description.RSMember:
+		return "other"
+	case description.RSGhost:
+		return "ghost"
+	case description.Mongos:
+		return "mongos"
+	case description.Standalone:
+		return "standalone"
+	case description.LoadBalancer:
+		return "loadBalancer"
+	}
+	return "unknown"
+}
+
+// heartbeatHost strips the connection number from a heartbeat connection
+// id such as "localhost:27017[-3]".
+func heartbeatHost(connectionID string) string {
+	if i := strings.Index(connectionID, "[-"); i >= 0 {
+		return connectionID[:i]
+	}
+	return connectionID
+}
@@ -0,0 +1,129 @@
+package xk6_mongo
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/address"
+	"go.mongodb.org/mongo-driver/mongo/description"
+	"go.mongodb.org/mongo-driver/tag"
+)
+
+func TestHeartbeatHost(t *testing.T) {
+	if got := heartbeatHost("rs1:27017[-12]"); got != "rs1:27017" {
+		t.Errorf("heartbeatHost = %q, want rs1:27017", got)
+	}
+	if got := heartbeatHost("rs1:27017"); got != "rs1:27017" {
+		t.Errorf("heartbeatHost = %q, want rs1:27017", got)
+	}
+}
+
+func TestTopologyWithoutWatcher(t *testing.T) {
+	snapshot := (&Client{}).Topology()
+	if snapshot.Kind != "Unknown" || len(snapshot.Servers) != 0 {
+		t.Errorf("Unexpected snapshot %+v", snapshot)
+	}
+}
+
+func TestTopologyWatcherMetrics(t *testing.T) {
+	client, samples := newMetricsTestClient(t)
+	w := newTopologyWatcher(client)
+
+	var userCalls int
+	monitor := w.serverMonitor(&event.ServerMonitor{
+		TopologyDescriptionChanged: func(*event.TopologyDescriptionChangedEvent) { userCalls++ },
+	})
+
+	monitor.TopologyDescriptionChanged(&event.TopologyDescriptionChangedEvent{
+		PreviousDescription: description.Topology{Kind: description.ReplicaSetWithPrimary},
+		NewDescription:      description.Topology{Kind: description.ReplicaSetNoPrimary},
+	})
+	monitor.ServerDescriptionChanged(&event.ServerDescriptionChangedEvent{
+		Address:             address.Address("rs1:27017"),
+		PreviousDescription: description.Server{Kind: description.RSPrimary},
+		NewDescription:      description.Server{Kind: description.RSSecondary},
+	})
+	monitor.ServerDescriptionChanged(&event.ServerDescriptionChangedEvent{
+		Address:             address.Address("rs2:27017"),
+		PreviousDescription: description.Server{Kind: description.RSSecondary},
+		NewDescription:      description.Server{Kind: description.RSSecondary},
+	})
+	monitor.ServerHeartbeatSucceeded(&event.ServerHeartbeatSucceededEvent{ConnectionID: "rs1:27017[-1]", Duration: 3 * time.Millisecond})
+	monitor.ServerHeartbeatSucceeded(&event.ServerHeartbeatSucceededEvent{ConnectionID: "rs1:27017[-2]", Duration: 10 * time.Second, Awaited: true})
+	monitor.ServerHeartbeatFailed(&event.ServerHeartbeatFailedEvent{ConnectionID: "rs3:27017[-4]"})
+
+	if userCalls != 1 {
+		t.Errorf("Expected the user monitor to be called once, got %d", userCalls)
+	}
+
+	counts := map[string]int{}
+	for _, s := range collectSamples(samples) {
+		counts[s.Metric.Name]++
+		tags := s.Tags.Map()
+		switch s.Metric.Name {
+		case "mongo_topology_changes":
+			if tags["from"] != "ReplicaSetWithPrimary" || tags["to"] != "ReplicaSetNoPrimary" {
+				t.Errorf("Unexpected topology change tags %v", tags)
+			}
+		case "mongo_server_kind_changes":
+			if tags["host"] != "rs1:27017" || tags["from"] != "primary" || tags["to"] != "secondary" {
+				t.Errorf("Unexpected server kind change tags %v", tags)
+			}
+		case "mongo_heartbeat_rtt":
+			if tags["host"] != "rs1:27017" || s.Value != 3 {
+				t.Errorf("Unexpected heartbeat RTT sample %v %v", tags, s.Value)
+			}
+		case "mongo_heartbeat_failures":
+			if tags["host"] != "rs3:27017" {
+				t.Errorf("Unexpected heartbeat failure tags %v", tags)
+			}
+		}
+	}
+	want := map[string]int{
+		"mongo_topology_changes":    1,
+		"mongo_server_kind_changes": 1,
+		"mongo_heartbeat_rtt":       1,
+		"mongo_heartbeat_failures":  1,
+	}
+	for name, n := range want {
+		if counts[name] != n {
+			t.Errorf("Expected %d %s samples, got %d", n, name, counts[name])
+		}
+	}
+}
+
+func TestTopologySnapshot(t *testing.T) {
+	w := newTopologyWatcher(&Client{})
+	electionID := primitive.NewObjectID()
+
+	w.topologyChanged(&event.TopologyDescriptionChangedEvent{
+		NewDescription: description.Topology{
+			Kind:    description.ReplicaSetWithPrimary,
+			SetName: "rs0",
+			Servers: []description.Server{
+				{Addr: "rs2:27017", Kind: description.RSSecondary, SetName: "rs0", AverageRTT: 2 * time.Millisecond,
+					Tags: tag.Set{{Name: "dc", Value: "east"}}},
+				{Addr: "rs1:27017", Kind: description.RSPrimary, SetName: "rs0", SetVersion: 3, ElectionID: electionID,
+					AverageRTT: time.Millisecond},
+			},
+		},
+	})
+	w.heartbeatSucceeded(&event.ServerHeartbeatSucceededEvent{ConnectionID: "rs1:27017[-1]", Duration: 5 * time.Millisecond})
+
+	s := w.snapshot()
+	if s.Kind != "ReplicaSetWithPrimary" || s.SetName != "rs0" || s.Changes != 1 || len(s.Servers) != 2 {
+		t.Fatalf("Unexpected snapshot %+v", s)
+	}
+	primary, secondary := s.Servers[0], s.Servers[1]
+	if primary.Host != "rs1:27017" || primary.Role != "primary" || primary.ElectionID != electionID.Hex() || primary.SetVersion != 3 {
+		t.Errorf("Unexpected primary %+v", primary)
+	}
+	if primary.RTTMillis != 5 {
+		t.Errorf("Expected the latest heartbeat RTT, got %v", primary.RTTMillis)
+	}
+	if secondary.Role != "secondary" || secondary.RTTMillis != 2 || secondary.Tags["dc"] != "east" || secondary.ElectionID != "" {
+		t.Errorf("Unexpected secondary %+v", secondary)
+	}
+}
@@ -0,0 +1,210 @@
+package xk6_mongo
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+const errReconcilingWrites = "Error while reconciling writes: %v"
+
+const reconcilePoll = 100 * time.Millisecond
+
+// sentDocuments counts the documents inserted through the extension, keyed
+// by deployment and namespace, so that a client created by teardown() can
+// reconcile the inserts of every VU.
+var sentDocuments = struct {
+	sync.Mutex
+	byNamespace map[string]*namespaceWrites
+}{byNamespace: map[string]*namespaceWrites{}}
+
+type namespaceWrites struct {
+	acknowledged   int64
+	unacknowledged int64
+}
+
+// WriteReconciliation compares the documents inserted into a collection
+// with the documents that actually landed there.
+type WriteReconciliation struct {
+	// Sent is the number of documents inserted through the extension since
+	// the collection was last dropped, of which Unacknowledged were sent
+	// with w:0.
+	Sent           int64 `js:"sent"`
+	Unacknowledged int64 `js:"unacknowledged"`
+	// Expected is the expected option, or Sent.
+	Expected int64 `js:"expected"`
+	Landed   int64 `js:"landed"`
+	// Missing is Expected minus Landed, never negative, and LossRate
+	// Missing over Expected.
+	Missing  int64   `js:"missing"`
+	LossRate float64 `js:"lossRate"`
+}
+
+// unacknowledged reports whether a write runs with w:0: the unacknowledged
+// option when set, otherwise the client's write concern.
+func (c *Client) unacknowledged(option *bool) bool {
+	if option != nil {
+		return *option
+	}
+	return c.clientOptions != nil && c.clientOptions.WriteConcern != nil &&
+		!c.clientOptions.WriteConcern.Acknowledged()
+}
+
+// writeCollection returns the collection to write to with the write concern
+// selected by the unacknowledged option, and whether writes to it are
+// unacknowledged. Without the option the client's write concern applies.
+func (c *Client) writeCollection(database, collection string, option *bool) (*mongo.Collection, bool, error) {
+	unacknowledged := c.unacknowledged(option)
+	if option == nil {
+		col, err := c.getCollection(database, collection)
+		return col, unacknowledged, err
+	}
+
+	wc := writeconcern.W1()
+	if unacknowledged {
+		wc = writeconcern.Unacknowledged()
+	}
+	col, err := c.getCollection(database, collection, options.Collection().SetWriteConcern(wc))
+	return col, unacknowledged, err
+}
+
+// ignoreUnacknowledged drops the error the driver returns together with the
+// result of every w:0 write: the write was sent, and nothing more is known.
+func ignoreUnacknowledged(err error) error {
+	if errors.Is(err, mongo.ErrUnacknowledgedWrite) {
+		return nil
+	}
+	return err
+}
+
+// recordWrite emits mongo_writes for a write sent successfully and, for
+// inserts, mongo_documents_sent and the counts used by ReconcileWrites.
+func (c *Client) recordWrite(op, database, collection string, unacknowledged bool, inserted int) {
+	if inserted > 0 {
+		sentDocuments.Lock()
+		key := c.namespaceKey(database, collection)
+		writes, ok := sentDocuments.byNamespace[key]
+		if !ok {
+			writes = &namespaceWrites{}
+			sentDocuments.byNamespace[key] = writes
+		}
+		if unacknowledged {
+			writes.unacknowledged += int64(inserted)
+		} else {
+			writes.acknowledged += int64(inserted)
+		}
+		sentDocuments.Unlock()
+	}
+
+	if c.metrics == nil {
+		return
+	}
+	tags := map[string]string{"op": op, "acknowledged": strconv.FormatBool(!unacknowledged)}
+	c.emit(c.metrics.writes, 1, tags)
+	if inserted > 0 {
+		c.emit(c.metrics.documentsSent, float64(inserted), tags)
+	}
+}
+
+func (c *Client) namespaceKey(database, collection string) string {
+	return c.deploymentKey() + "/" + database + "." + collection
+}
+
+// forgetWrites resets the inserted document counts of a dropped collection,
+// or of every collection of a dropped database when collection is empty.
+func (c *Client) forgetWrites(database, collection string) {
+	sentDocuments.Lock()
+	defer sentDocuments.Unlock()
+
+	if collection != "" {
+		delete(sentDocuments.byNamespace, c.namespaceKey(database, collection))
+		return
+	}
+	prefix := c.namespaceKey(database, "")
+	for key := range sentDocuments.byNamespace {
+		if strings.HasPrefix(key, prefix) {
+			delete(sentDocuments.byNamespace, key)
+		}
+	}
+}
+
+func (c *Client) sentWrites(database, collection string) namespaceWrites {
+	sentDocuments.Lock()
+	defer sentDocuments.Unlock()
+
+	if writes, ok := sentDocuments.byNamespace[c.namespaceKey(database, collection)]; ok {
+		return *writes
+	}
+	return namespaceWrites{}
+}
+
+// ReconcileWrites counts the documents that landed in a collection after a
+// run and compares them with the documents inserted into it through the
+// extension, by every VU, since it was last dropped. Options: filter (the
+// documents to count, default all), expected (overriding the inserted
+// count) and wait (ms to keep counting while documents are missing, since
+// w:0 writes may still be applied after they were sent; default 0).
+func (c *Client) ReconcileWrites(database string, collection string, opts map[string]any) (*WriteReconciliation, error) {
+	if err := checkAllowedOptions(opts, "filter", "expected", "wait"); err != nil {
+		return nil, err
+	}
+	filter := any(bson.D{})
+	if value, ok := lookupOption(opts, "filter"); ok && value != nil {
+		filter = value
+	}
+	expected, err := optionalNonNegativeInt(opts, "expected")
+	if err != nil {
+		return nil, err
+	}
+	wait, _, err := durationOption(opts, "wait")
+	if err != nil {
+		return nil, err
+	}
+
+	col, err := c.getCollection(database, collection)
+	if err != nil {
+		log.Printf(errValidatingCollection, err)
+		return nil, err
+	}
+
+	sent := c.sentWrites(database, collection)
+	r := &WriteReconciliation{
+		Sent:           sent.acknowledged + sent.unacknowledged,
+		Unacknowledged: sent.unacknowledged,
+	}
+	r.Expected = r.Sent
+	if expected != nil {
+		r.Expected = *expected
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		err = c.do("reconcileWrites", opRead, func(ctx context.Context) error {
+			var err error
+			r.Landed, err = col.CountDocuments(ctx, filter)
+			return err
+		})
+		if err != nil {
+			log.Printf(errReconcilingWrites, err)
+			return nil, err
+		}
+		if r.Landed >= r.Expected || !time.Now().Before(deadline) || !c.sleep(reconcilePoll) {
+			break
+		}
+	}
+
+	r.Missing = max(r.Expected-r.Landed, 0)
+	if r.Expected > 0 {
+		r.LossRate = float64(r.Missing) / float64(r.Expected)
+	}
+	return r, nil
+}
@@ -0,0 +1,171 @@
+package xk6_mongo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+func TestUnacknowledgedDefault(t *testing.T) {
+	enabled, disabled := true, false
+	acknowledged := &Client{clientOptions: options.Client().SetHosts([]string{"localhost:27017"})}
+	fireAndForget := &Client{clientOptions: options.Client().SetWriteConcern(writeconcern.Unacknowledged())}
+
+	tests := []struct {
+		name   string
+		client *Client
+		option *bool
+		want   bool
+	}{
+		{"client default", acknowledged, nil, false},
+		{"client w:0", fireAndForget, nil, true},
+		{"per call", acknowledged, &enabled, true},
+		{"per call override", fireAndForget, &disabled, false},
+		{"no options", &Client{}, nil, false},
+	}
+	for _, tt := range tests {
+		if got := tt.client.unacknowledged(tt.option); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestIgnoreUnacknowledged(t *testing.T) {
+	if err := ignoreUnacknowledged(mongo.ErrUnacknowledgedWrite); err != nil {
+		t.Errorf("Expected unacknowledged writes to succeed, got %v", err)
+	}
+	if err := ignoreUnacknowledged(fmt.Errorf("wrapped: %w", mongo.ErrUnacknowledgedWrite)); err != nil {
+		t.Errorf("Expected wrapped unacknowledged writes to succeed, got %v", err)
+	}
+	if err := ignoreUnacknowledged(errDocumentNil); err != errDocumentNil {
+		t.Errorf("Expected other errors to be kept, got %v", err)
+	}
+
+	client := &Client{defaultTimeout: time.Second}
+	err := client.do("insert", opWrite, func(context.Context) error { return mongo.ErrUnacknowledgedWrite })
+	if err != nil {
+		t.Errorf("Expected do to succeed, got %v", err)
+	}
+}
+
+func TestRecordWrite(t *testing.T) {
+	client, samples := newMetricsTestClient(t)
+	client.clientOptions = options.Client().SetHosts([]string{"record-write:27017"})
+
+	client.recordWrite("insertMany", "db", "col", true, 3)
+	client.recordWrite("insert", "db", "col", false, 1)
+	client.recordWrite("updateOne", "db", "col", true, 0)
+
+	counts := map[string]float64{}
+	for _, s := range collectSamples(samples) {
+		tags := s.Tags.Map()
+		counts[s.Metric.Name+"/"+tags["op"]+"/"+tags["acknowledged"]] += s.Value
+	}
+	want := map[string]float64{
+		"mongo_writes/insertMany/false":         1,
+		"mongo_documents_sent/insertMany/false": 3,
+		"mongo_writes/insert/true":              1,
+		"mongo_documents_sent/insert/true":      1,
+		"mongo_writes/updateOne/false":          1,
+	}
+	for key, value := range want {
+		if counts[key] != value {
+			t.Errorf("Expected %s to be %v, got %v", key, value, counts[key])
+		}
+	}
+	if len(counts) != len(want) {
+		t.Errorf("Expected %d series, got %v", len(want), counts)
+	}
+
+	sent := client.sentWrites("db", "col")
+	if sent.acknowledged != 1 || sent.unacknowledged != 3 {
+		t.Errorf("Expected 1 acknowledged and 3 unacknowledged documents, got %+v", sent)
+	}
+
+	// Clients connected to the same deployment share the counts.
+	other := &Client{clientOptions: options.Client().SetHosts([]string{"record-write:27017"})}
+	if got := other.sentWrites("db", "col"); got != sent {
+		t.Errorf("Expected counts shared across clients, got %+v", got)
+	}
+
+	client.recordWrite("insert", "db", "other", true, 2)
+	client.forgetWrites("db", "col")
+	if got := client.sentWrites("db", "col"); got != (namespaceWrites{}) {
+		t.Errorf("Expected counts reset by a collection drop, got %+v", got)
+	}
+	if got := client.sentWrites("db", "other"); got.unacknowledged != 2 {
+		t.Errorf("Expected other collections kept, got %+v", got)
+	}
+	client.forgetWrites("db", "")
+	if got := client.sentWrites("db", "other"); got != (namespaceWrites{}) {
+		t.Errorf("Expected counts reset by a database drop, got %+v", got)
+	}
+}
+
+func TestReconcileWritesValidation(t *testing.T) {
+	client := &Client{}
+	if _, err := client.ReconcileWrites("db", "col", map[string]any{"expect": int64(1)}); err == nil {
+		t.Error("Expected an error for an unknown option")
+	}
+	if _, err := client.ReconcileWrites("db", "col", map[string]any{"expected": int64(-1)}); err == nil {
+		t.Error("Expected an error for a negative expected count")
+	}
+	if _, err := client.ReconcileWrites("", "col", nil); err == nil {
+		t.Error("Expected an error for an empty database name")
+	}
+}
+
+func TestUnacknowledgedWrites(t *testing.T) {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("MONGODB_URI not set")
+	}
+
+	client := new(Mongo).NewClient(uri)
+	if client == nil {
+		t.Fatal("Failed to create client")
+	}
+	defer func() {
+		_ = client.Disconnect()
+	}()
+
+	db, col := "unacktest", "writes"
+	_ = client.DropCollection(db, col)
+	defer func() {
+		_ = client.DropCollection(db, col)
+	}()
+
+	fireAndForget := map[string]any{"unacknowledged": true}
+	if err := client.Insert(db, col, bson.M{"_id": 1}, fireAndForget); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if _, err := client.InsertMany(db, col, []any{bson.M{"_id": 2}, bson.M{"_id": 3}}, fireAndForget); err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+	if err := client.UpdateOne(db, col, bson.M{"_id": 1}, bson.M{"$set": bson.M{"seen": true}}, fireAndForget); err != nil {
+		t.Fatalf("UpdateOne failed: %v", err)
+	}
+	models := []mongo.WriteModel{mongo.NewInsertOneModel().SetDocument(bson.M{"_id": 4})}
+	if _, _, err := client.BulkWrite(db, col, models, fireAndForget); err != nil {
+		t.Fatalf("BulkWrite failed: %v", err)
+	}
+	// Duplicate keys go unnoticed with w:0.
+	if err := client.Insert(db, col, bson.M{"_id": 1}, fireAndForget); err != nil {
+		t.Fatalf("Duplicate insert failed: %v", err)
+	}
+
+	r, err := client.ReconcileWrites(db, col, map[string]any{"wait": int64(500)})
+	if err != nil {
+		t.Fatalf("ReconcileWrites failed: %v", err)
+	}
+	if r.Sent != 5 || r.Unacknowledged != 5 || r.Landed != 4 || r.Missing != 1 {
+		t.Errorf("Unexpected reconciliation %+v", r)
+	}
+}
@@ -1,10 +1,13 @@
 package xk6_mongo
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func TestValidateDatabaseAndCollection(t *testing.T) {
@@ -88,21 +91,21 @@ func TestInsertValidation(t *testing.T) {
 	client := &Client{} // Mock client without real connection
 
 	t.Run("nil document", func(t *testing.T) {
-		err := client.Insert("db", "col", nil)
+		err := client.Insert("db", "col", nil, nil)
 		if err != errDocumentNil {
 			t.Errorf("Expected errDocumentNil, got %v", err)
 		}
 	})
 
 	t.Run("empty database", func(t *testing.T) {
-		err := client.Insert("", "col", map[string]any{"key": "value"})
+		err := client.Insert("", "col", map[string]any{"key": "value"}, nil)
 		if err == nil {
 			t.Error("Expected error for empty database")
 		}
 	})
 
 	t.Run("empty collection", func(t *testing.T) {
-		err := client.Insert("db", "", map[string]any{"key": "value"})
+		err := client.Insert("db", "", map[string]any{"key": "value"}, nil)
 		if err == nil {
 			t.Error("Expected error for empty collection")
 		}
@@ -113,7 +116,7 @@ func TestInsertManyValidation(t *testing.T) {
 	client := &Client{}
 
 	t.Run("empty documents array", func(t *testing.T) {
-		err := client.InsertMany("db", "col", []any{})
+		_, err := client.InsertMany("db", "col", []any{}, nil)
 		if err != errDocsEmpty {
 			t.Errorf("Expected errDocsEmpty, got %v", err)
 		}
@@ -135,40 +138,270 @@ func TestUpdateValidation(t *testing.T) {
 	client := &Client{}
 
 	t.Run("nil filter for UpdateOne", func(t *testing.T) {
-		err := client.UpdateOne("db", "col", nil, map[string]any{"key": "value"})
+		err := client.UpdateOne("db", "col", nil, map[string]any{"key": "value"}, nil)
 		if err != errFilterNil {
 			t.Errorf("Expected errFilterNil, got %v", err)
 		}
 	})
 
 	t.Run("nil filter for UpdateMany", func(t *testing.T) {
-		err := client.UpdateMany("db", "col", nil, map[string]any{"key": "value"})
+		err := client.UpdateMany("db", "col", nil, map[string]any{"key": "value"}, nil)
 		if err != errFilterNil {
 			t.Errorf("Expected errFilterNil, got %v", err)
 		}
 	})
+
+	t.Run("unsupported option for UpdateOne", func(t *testing.T) {
+		err := client.UpdateOne("db", "col", map[string]any{}, map[string]any{"key": "value"}, map[string]any{"sort": bson.M{"_id": 1}})
+		if err == nil {
+			t.Error("Expected error for unsupported option")
+		}
+	})
+
+	t.Run("nil filter for Session.UpdateOne", func(t *testing.T) {
+		session := &Session{client: client}
+		err := session.UpdateOne("db", "col", nil, map[string]any{"key": "value"}, nil)
+		if err != errFilterNil {
+			t.Errorf("Expected errFilterNil, got %v", err)
+		}
+	})
+}
+
+func TestUpdateOptions(t *testing.T) {
+	parsed, err := parseUpdateOptions(map[string]any{
+		"arrayFilters":             []any{bson.M{"elem.grade": bson.M{"$gte": 85}}},
+		"hint":                     "grades_1",
+		"let":                      bson.M{"target": 100},
+		"comment":                  "k6",
+		"bypassDocumentValidation": true,
+	})
+	if err != nil {
+		t.Fatalf("parseUpdateOptions failed: %v", err)
+	}
+
+	opts := parsed.update()
+	if opts.ArrayFilters == nil || len(opts.ArrayFilters.Filters) != 1 {
+		t.Error("Expected arrayFilters to be set")
+	}
+	if opts.Hint != "grades_1" {
+		t.Errorf("Expected hint grades_1, got %v", opts.Hint)
+	}
+	if opts.Let == nil || opts.Comment != "k6" {
+		t.Error("Expected let and comment to be set")
+	}
+	if opts.BypassDocumentValidation == nil || !*opts.BypassDocumentValidation {
+		t.Error("Expected bypassDocumentValidation to be set")
+	}
+	if opts.Upsert != nil {
+		t.Error("Expected upsert to be unset")
+	}
 }
 
 func TestUpsertValidation(t *testing.T) {
 	client := &Client{}
 
 	t.Run("nil filter", func(t *testing.T) {
-		err := client.Upsert("db", "col", nil, map[string]any{"key": "value"})
+		err := client.Upsert("db", "col", nil, map[string]any{"key": "value"}, nil)
 		if err != errFilterNil {
 			t.Errorf("Expected errFilterNil, got %v", err)
 		}
 	})
 }
 
+func TestDeleteValidation(t *testing.T) {
+	client := &Client{}
+
+	t.Run("unsupported option for DeleteOne", func(t *testing.T) {
+		err := client.DeleteOne("db", "col", map[string]any{}, map[string]any{"sort": bson.M{"_id": 1}})
+		if err == nil {
+			t.Error("Expected error for unsupported option")
+		}
+	})
+
+	t.Run("invalid collation for DeleteMany", func(t *testing.T) {
+		err := client.DeleteMany("db", "col", map[string]any{}, map[string]any{"collation": "en"})
+		if err == nil {
+			t.Error("Expected error for invalid collation")
+		}
+	})
+
+	t.Run("empty database for Session.DeleteOne", func(t *testing.T) {
+		session := &Session{client: client}
+		err := session.DeleteOne("", "col", map[string]any{}, nil)
+		if err == nil {
+			t.Error("Expected error for empty database")
+		}
+	})
+}
+
+func TestCountValidation(t *testing.T) {
+	client := &Client{}
+
+	t.Run("negative skip", func(t *testing.T) {
+		_, err := client.CountDocuments("db", "col", map[string]any{}, map[string]any{"skip": int64(-1)})
+		if err == nil {
+			t.Error("Expected error for negative skip")
+		}
+	})
+
+	t.Run("hint not supported by estimate", func(t *testing.T) {
+		_, err := client.EstimatedDocumentCount("db", "col", map[string]any{"hint": "_id_"})
+		if err == nil {
+			t.Error("Expected error for unsupported option")
+		}
+	})
+
+	t.Run("empty collection for estimate", func(t *testing.T) {
+		_, err := client.EstimatedDocumentCount("db", "", nil)
+		if err == nil {
+			t.Error("Expected error for empty collection")
+		}
+	})
+}
+
+func TestCountOptions(t *testing.T) {
+	parsed, err := parseCommandOptions(map[string]any{
+		"skip": int64(5), "limit": int64(10), "hint": bson.M{"status": 1}, "maxTime": int64(200), "comment": "dash",
+	}, optComment, optHint, optLimit, optMaxTime, optSkip)
+	if err != nil {
+		t.Fatalf("parseCommandOptions failed: %v", err)
+	}
+
+	opts := parsed.count()
+	if *opts.Skip != 5 || *opts.Limit != 10 {
+		t.Errorf("Expected skip 5 and limit 10, got %d and %d", *opts.Skip, *opts.Limit)
+	}
+	if *opts.MaxTime != 200*time.Millisecond {
+		t.Errorf("Expected maxTime 200ms, got %v", *opts.MaxTime)
+	}
+	if opts.Hint == nil || *opts.Comment != "dash" {
+		t.Error("Expected hint and comment to be set")
+	}
+
+	parsed, err = parseCommandOptions(map[string]any{
+		"comment": map[string]any{"tag": "dash", "run": int64(3)},
+	}, optComment)
+	if err != nil {
+		t.Fatalf("parseCommandOptions failed: %v", err)
+	}
+	if comment := *parsed.count().Comment; comment != `{"run":3,"tag":"dash"}` {
+		t.Errorf("Expected an object comment sent as JSON, got %s", comment)
+	}
+}
+
 func TestAggregateValidation(t *testing.T) {
 	client := &Client{}
 
 	t.Run("nil pipeline", func(t *testing.T) {
-		_, err := client.Aggregate("db", "col", nil)
+		_, err := client.Aggregate("db", "col", nil, nil)
 		if err != errPipelineNil {
 			t.Errorf("Expected errPipelineNil, got %v", err)
 		}
 	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := client.Aggregate("db", "col", []any{}, map[string]any{"allowDiskUse": "yes"})
+		if err == nil {
+			t.Error("Expected error for invalid options")
+		}
+	})
+
+	t.Run("empty database for AggregateDatabase", func(t *testing.T) {
+		_, err := client.AggregateDatabase("", []any{}, nil)
+		if err != errDatabaseEmpty {
+			t.Errorf("Expected errDatabaseEmpty, got %v", err)
+		}
+	})
+
+	t.Run("AggregateWrite without write stage", func(t *testing.T) {
+		_, err := client.AggregateWrite("db", "col", []any{bson.M{"$match": bson.M{}}}, nil)
+		if err != errNoWriteStage {
+			t.Errorf("Expected errNoWriteStage, got %v", err)
+		}
+	})
+}
+
+func TestParseAggregateOptions(t *testing.T) {
+	parsed, err := parseAggregateOptions(map[string]any{
+		"allowDiskUse": true,
+		"batchSize":    int64(100),
+		"maxTime":      int64(1500),
+		"comment":      "report",
+		"readConcern":  map[string]any{"level": "majority"},
+	})
+	if err != nil {
+		t.Fatalf("parseAggregateOptions failed: %v", err)
+	}
+	if parsed.readConcern == nil || parsed.readConcern.Level != "majority" {
+		t.Errorf("Expected majority read concern, got %v", parsed.readConcern)
+	}
+
+	opts := parsed.aggregate()
+	if !*opts.AllowDiskUse || *opts.BatchSize != 100 {
+		t.Error("Expected allowDiskUse and batchSize to be set")
+	}
+	if *opts.MaxTime != 1500*time.Millisecond || *opts.Comment != "report" {
+		t.Errorf("Unexpected maxTime %v or comment %v", *opts.MaxTime, *opts.Comment)
+	}
+
+	parsed, err = parseAggregateOptions(map[string]any{"comment": map[string]any{"report": "daily"}})
+	if err != nil {
+		t.Fatalf("parseAggregateOptions failed: %v", err)
+	}
+	if comment := *parsed.aggregate().Comment; comment != `{"report":"daily"}` {
+		t.Errorf("Expected an object comment sent as JSON, got %s", comment)
+	}
+
+	invalid := map[string]map[string]any{
+		"unknown option":      {"sort": bson.M{"a": 1}},
+		"unknown read level":  {"readConcern": "strong"},
+		"numeric read level":  {"readConcern": 1},
+		"zero batchSize":      {"batchSize": int64(0)},
+		"read concern extras": {"readConcern": map[string]any{"level": "local", "afterClusterTime": 1}},
+	}
+	for name, raw := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseAggregateOptions(raw); err == nil {
+				t.Error("Expected error for invalid option")
+			}
+		})
+	}
+}
+
+func TestAggregateWriteTarget(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline any
+		want     AggregateWriteResult
+	}{
+		{"$out string", []any{map[string]any{"$out": "report"}},
+			AggregateWriteResult{Stage: "$out", Database: "db", Collection: "report"}},
+		{"$out object", []bson.M{{"$out": bson.M{"db": "archive", "coll": "report"}}},
+			AggregateWriteResult{Stage: "$out", Database: "archive", Collection: "report"}},
+		{"$merge string", mongo.Pipeline{{{Key: "$merge", Value: "totals"}}},
+			AggregateWriteResult{Stage: "$merge", Database: "db", Collection: "totals"}},
+		{"$merge into object", []any{bson.M{"$match": bson.M{}}, bson.M{"$merge": bson.M{
+			"into": bson.M{"db": "archive", "coll": "totals"}, "whenMatched": "replace"}}},
+			AggregateWriteResult{Stage: "$merge", Database: "archive", Collection: "totals"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := aggregateWriteTarget(tt.pipeline, "db")
+			if err != nil {
+				t.Fatalf("aggregateWriteTarget failed: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("aggregateWriteTarget = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+
+	if _, err := aggregateWriteTarget([]any{bson.M{"$out": bson.M{"db": "archive"}}}, "db"); err == nil {
+		t.Error("Expected error for $out without a collection")
+	}
+	if _, err := aggregateWriteTarget([]any{}, "db"); err != errNoWriteStage {
+		t.Errorf("Expected errNoWriteStage for empty pipeline, got %v", err)
+	}
 }
 
 func TestDistinctValidation(t *testing.T) {
@@ -186,18 +419,118 @@ func TestFindOneAndUpdateValidation(t *testing.T) {
 	client := &Client{}
 
 	t.Run("nil filter", func(t *testing.T) {
-		_, err := client.FindOneAndUpdate("db", "col", nil, map[string]any{"key": "value"})
+		_, err := client.FindOneAndUpdate("db", "col", nil, map[string]any{"key": "value"}, nil)
 		if err != errFilterNil {
 			t.Errorf("Expected errFilterNil, got %v", err)
 		}
 	})
+
+	t.Run("unsupported option", func(t *testing.T) {
+		_, err := client.FindOneAndUpdate("db", "col", map[string]any{}, map[string]any{"key": "value"}, map[string]any{"returnDoc": "before"})
+		if err == nil {
+			t.Error("Expected error for unsupported option")
+		}
+	})
+
+	t.Run("invalid returnDocument", func(t *testing.T) {
+		_, err := client.FindOneAndUpdate("db", "col", map[string]any{}, map[string]any{"key": "value"}, map[string]any{"returnDocument": "later"})
+		if err == nil {
+			t.Error("Expected error for invalid returnDocument")
+		}
+	})
+}
+
+func TestFindOneAndReplaceValidation(t *testing.T) {
+	client := &Client{}
+
+	t.Run("nil filter", func(t *testing.T) {
+		_, err := client.FindOneAndReplace("db", "col", nil, map[string]any{"key": "value"}, nil)
+		if err != errFilterNil {
+			t.Errorf("Expected errFilterNil, got %v", err)
+		}
+	})
+
+	t.Run("replacement with operators", func(t *testing.T) {
+		_, err := client.FindOneAndReplace("db", "col", map[string]any{}, map[string]any{"$set": map[string]any{"key": "value"}}, nil)
+		if err != errReplacementOp {
+			t.Errorf("Expected errReplacementOp, got %v", err)
+		}
+	})
+
+	t.Run("arrayFilters not supported", func(t *testing.T) {
+		_, err := client.FindOneAndReplace("db", "col", map[string]any{}, map[string]any{"key": "value"}, map[string]any{"arrayFilters": []any{}})
+		if err == nil {
+			t.Error("Expected error for arrayFilters on replace")
+		}
+	})
+}
+
+func TestFindOneAndDeleteValidation(t *testing.T) {
+	client := &Client{}
+
+	t.Run("nil filter", func(t *testing.T) {
+		_, err := client.FindOneAndDelete("db", "col", nil, nil)
+		if err != errFilterNil {
+			t.Errorf("Expected errFilterNil, got %v", err)
+		}
+	})
+
+	t.Run("upsert not supported", func(t *testing.T) {
+		_, err := client.FindOneAndDelete("db", "col", map[string]any{}, map[string]any{"upsert": true})
+		if err == nil {
+			t.Error("Expected error for upsert on delete")
+		}
+	})
+}
+
+func TestParseCommandOptions(t *testing.T) {
+	opts, err := parseCommandOptions(map[string]any{
+		"sort":            bson.M{"priority": -1},
+		"return_document": "before",
+		"upsert":          true,
+		"maxTime":         int64(1500),
+		"arrayFilters":    []any{bson.M{"elem.qty": bson.M{"$gt": 1}}},
+		"collation":       map[string]any{"locale": "en", "strength": int64(2)},
+	}, optSort, optReturnDocument, optUpsert, optMaxTime, optArrayFilters, optCollation)
+	if err != nil {
+		t.Fatalf("parseCommandOptions failed: %v", err)
+	}
+	if opts.sort == nil || opts.upsert == nil || !*opts.upsert {
+		t.Error("Expected sort and upsert to be set")
+	}
+	if *opts.returnDocument != options.Before {
+		t.Errorf("Expected returnDocument before, got %v", *opts.returnDocument)
+	}
+	if *opts.maxTime != 1500*time.Millisecond {
+		t.Errorf("Expected maxTime 1.5s, got %v", *opts.maxTime)
+	}
+	if len(opts.arrayFilters.Filters) != 1 {
+		t.Errorf("Expected 1 array filter, got %d", len(opts.arrayFilters.Filters))
+	}
+	if opts.collation.Locale != "en" || opts.collation.Strength != 2 {
+		t.Errorf("Unexpected collation %+v", opts.collation)
+	}
+
+	invalid := map[string]map[string]any{
+		"collation without locale": {"collation": map[string]any{"strength": int64(2)}},
+		"collation unknown field":  {"collation": map[string]any{"locale": "en", "accent": true}},
+		"arrayFilters not array":   {"arrayFilters": bson.M{}},
+		"upsert not bool":          {"upsert": "yes"},
+	}
+	for name, raw := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseCommandOptions(raw, optCollation, optArrayFilters, optUpsert); err == nil {
+				t.Error("Expected error for invalid option")
+			}
+		})
+	}
 }
 
 func TestBulkWriteValidation(t *testing.T) {
 	client := &Client{}
 
 	t.Run("empty operations array", func(t *testing.T) {
-		_, _, err := client.BulkWrite("db", "col", []mongo.WriteModel{})
+		_, _, err := client.BulkWrite("db", "col", []mongo.WriteModel{}, nil)
 		if err == nil {
 			t.Error("Expected error for empty operations array")
 		}
@@ -435,3 +768,63 @@ func TestPrepareClientOptions(t *testing.T) {
 		}
 	})
 }
+
+func TestReplaceOneValidation(t *testing.T) {
+	client := &Client{}
+
+	t.Run("nil filter", func(t *testing.T) {
+		_, err := client.ReplaceOne("db", "col", nil, map[string]any{"key": "value"}, nil)
+		if err != errFilterNil {
+			t.Errorf("Expected errFilterNil, got %v", err)
+		}
+	})
+
+	t.Run("nil replacement", func(t *testing.T) {
+		_, err := client.ReplaceOne("db", "col", map[string]any{}, nil, nil)
+		if err != errDocumentNil {
+			t.Errorf("Expected errDocumentNil, got %v", err)
+		}
+	})
+
+	t.Run("replacement with operators", func(t *testing.T) {
+		_, err := client.ReplaceOne("db", "col", map[string]any{}, bson.D{{Key: "$inc", Value: bson.M{"n": 1}}}, nil)
+		if err != errReplacementOp {
+			t.Errorf("Expected errReplacementOp, got %v", err)
+		}
+	})
+
+	t.Run("pipeline replacement", func(t *testing.T) {
+		_, err := client.ReplaceOne("db", "col", map[string]any{}, []any{bson.M{"$set": bson.M{"n": 1}}}, nil)
+		if err != errReplacementOp {
+			t.Errorf("Expected errReplacementOp, got %v", err)
+		}
+	})
+}
+
+func TestReplaceManyValidation(t *testing.T) {
+	client := &Client{}
+
+	t.Run("empty replacements", func(t *testing.T) {
+		_, err := client.ReplaceMany("db", "col", []any{}, nil)
+		if err != errReplacementsEmpty {
+			t.Errorf("Expected errReplacementsEmpty, got %v", err)
+		}
+	})
+
+	t.Run("entry without filter", func(t *testing.T) {
+		_, err := client.ReplaceMany("db", "col", []any{map[string]any{"replacement": map[string]any{"n": 1}}}, nil)
+		if !errors.Is(err, errFilterNil) {
+			t.Errorf("Expected errFilterNil, got %v", err)
+		}
+	})
+
+	t.Run("entry with operators", func(t *testing.T) {
+		_, err := client.ReplaceMany("db", "col", []any{map[string]any{
+			"filter":      map[string]any{"_id": 1},
+			"replacement": map[string]any{"$set": map[string]any{"n": 1}},
+		}}, nil)
+		if !errors.Is(err, errReplacementOp) {
+			t.Errorf("Expected errReplacementOp, got %v", err)
+		}
+	})
+}